@@ -9,6 +9,8 @@ import (
 	"go-template/internal/shared"
 	"go-template/internal/shared/infrastructure/database"
 	"go-template/internal/shared/infrastructure/logger"
+	"go-template/internal/shared/infrastructure/outbox"
+	"go-template/internal/shared/infrastructure/storage"
 	"go-template/internal/shared/interfaces/http"
 	"go-template/internal/shared/middleware"
 	"go-template/internal/user"
@@ -16,6 +18,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	_ "go-template/docs"
 	"go-template/internal/config"
@@ -44,15 +47,24 @@ func main() {
 	db := initDatabase(cloudWatchModule)
 	defer db.Close()
 
-	s3Module := s3.NewModule(logger, cloudWatchModule)
-	snsModule := sns.NewModule(logger)
+	objectStorage := initStorage(logger, cloudWatchModule)
+	notificationOutbox := outbox.NewPostgresStore(db)
+	dispatcher := outbox.NewDispatcher(
+		notificationOutbox,
+		initNotificationTransport(logger),
+		logger,
+		time.Duration(config.App.Notification.DispatchInterval)*time.Second,
+		config.App.Notification.MaxAttempts,
+	)
+	dispatcher.Start()
 
-	authModule := auth.NewModule(db, logger, snsModule)
-	userModule := user.NewModule(db, logger, authModule.GetBasicService(), s3Module)
+	authModule := auth.NewModule(db, logger, notificationOutbox, cloudWatchModule)
+	userModule := user.NewModule(db, logger, authModule.GetAuthenticator(), objectStorage)
 
 	server := http.NewServer()
 
 	server.AddMiddlewares(
+		middleware.RequestInfo(),
 		middleware.NewRequestLoggerMiddleware(logger, cloudWatchModule).Handler(),
 		middleware.RemovePayloadForMethodNotAllowed(),
 		gin.Recovery(),
@@ -64,7 +76,7 @@ func main() {
 		userModule,
 	)
 
-	setupGracefulShutdown(cloudWatchModule, db)
+	setupGracefulShutdown(cloudWatchModule, userModule, dispatcher, db)
 
 	serveAndListen(server)
 }
@@ -111,6 +123,64 @@ func initDatabase(cloudWatchModule cloudwatch.CloudWatchModule) database.BaseDat
 	return postgres
 }
 
+// defaultLocalStoragePath is used by the local storage driver when storage.local_path is not configured
+const defaultLocalStoragePath = "./storage"
+
+// initStorage selects the storage driver, only the S3 one needs AWS credentials
+func initStorage(logger logger.Logger, cloudWatchModule cloudwatch.CloudWatchModule) storage.ObjectStorage {
+	switch config.App.Storage.Driver {
+	case "", storage.DriverS3:
+		return s3.NewModule(logger, cloudWatchModule)
+	case storage.DriverLocal:
+		localPath := config.App.Storage.LocalPath
+		if localPath == "" {
+			localPath = defaultLocalStoragePath
+		}
+		log.Println("Storing the objects in " + localPath)
+		return storage.NewLocalStorage(localPath)
+	case storage.DriverMemory:
+		log.Println("Storing the objects in memory, they are lost on restart")
+		return storage.NewMemoryStorage()
+	default:
+		log.Fatalf("Unknown storage driver: %s", config.App.Storage.Driver)
+		return nil
+	}
+}
+
+// defaultSMTPAddress and defaultSMTPFrom are used by the smtp transport when notification.smtp_address and notification.smtp_from are not configured
+const (
+	defaultSMTPAddress = "localhost:1025"
+	defaultSMTPFrom    = "no-reply@localhost"
+)
+
+// initNotificationTransport selects how the outbox is published, only the SNS transport needs AWS credentials
+func initNotificationTransport(logger logger.Logger) sns.SNSModule {
+	switch config.App.Notification.Transport {
+	case "", sns.TransportSNS:
+		return sns.NewModule(logger)
+	case sns.TransportLog:
+		log.Println("Writing the notifications to the log")
+		return sns.NewLogTransport(logger)
+	case sns.TransportSMTP:
+		address := config.App.Notification.SMTPAddress
+		if address == "" {
+			address = defaultSMTPAddress
+		}
+		from := config.App.Notification.SMTPFrom
+		if from == "" {
+			from = defaultSMTPFrom
+		}
+		log.Println("Mailing the notifications to " + address)
+		return sns.NewSMTPTransport(address, from)
+	case sns.TransportMemory:
+		log.Println("Keeping the notifications in memory, they are not delivered")
+		return sns.NewMemoryTransport()
+	default:
+		log.Fatalf("Unknown notification transport: %s", config.App.Notification.Transport)
+		return nil
+	}
+}
+
 func serveAndListen(server *http.Server) {
 	log.Println("Starting server on :" + fmt.Sprint(config.App.Server.Port))
 	if err := server.Start("127.0.0.1:" + fmt.Sprint(config.App.Server.Port)); err != nil {
@@ -120,6 +190,8 @@ func serveAndListen(server *http.Server) {
 
 func setupGracefulShutdown(
 	cloudwatchModule cloudwatch.CloudWatchModule,
+	userModule *user.Module,
+	dispatcher *outbox.Dispatcher,
 	database database.BaseDatabase,
 ) {
 	sigChan := make(chan os.Signal, 1)
@@ -129,6 +201,8 @@ func setupGracefulShutdown(
 		<-sigChan
 		fmt.Printf("\n--------------------------------\n")
 		fmt.Println("Shutting down server...")
+		userModule.Shutdown()
+		dispatcher.Shutdown()
 		cloudwatchModule.Shutdown()
 		database.Close()
 		os.Exit(0)
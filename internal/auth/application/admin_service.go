@@ -0,0 +1,159 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/admin"
+	"go-template/internal/shared/infrastructure/logger"
+	"go-template/pkg/apperrors"
+	"time"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// UserPage is a page of the users matching a search, Page starts at 1
+type UserPage struct {
+	Users    []*domain.AuthUser
+	Total    int
+	Page     int
+	PageSize int
+}
+
+type AdminApplicationService interface {
+	ListUsers(ctx context.Context, search, status string, page, pageSize int) (*UserPage, *apperrors.Error)
+	GetUser(ctx context.Context, id string) (*domain.AuthUser, *apperrors.Error)
+	SuspendUser(ctx context.Context, actor *domain.AuthUser, id, reason string) (*domain.AuthUser, *apperrors.Error)
+	DeactivateUser(ctx context.Context, actor *domain.AuthUser, id, reason string) (*domain.AuthUser, *apperrors.Error)
+	ActivateUser(ctx context.Context, actor *domain.AuthUser, id string) (*domain.AuthUser, *apperrors.Error)
+	VerifyUser(ctx context.Context, actor *domain.AuthUser, id string) (*domain.AuthUser, *apperrors.Error)
+	DeleteUser(ctx context.Context, actor *domain.AuthUser, id string) (time.Time, *apperrors.Error)
+}
+
+type adminApplicationService struct {
+	adminService *admin.AdminService
+	logger       logger.Logger
+}
+
+func NewAdminApplicationService(adminService *admin.AdminService, logger logger.Logger) AdminApplicationService {
+	return &adminApplicationService{
+		adminService: adminService,
+		logger:       logger,
+	}
+}
+
+// ListUsers falls back to the first page and the default page size, the page size is capped
+func (s *adminApplicationService) ListUsers(ctx context.Context, search, status string, page, pageSize int) (*UserPage, *apperrors.Error) {
+	switch domain.AccountStatus(status) {
+	case "", domain.AccountStatusPending, domain.AccountStatusActive, domain.AccountStatusSuspended, domain.AccountStatusDeactivated:
+	default:
+		return nil, apperrors.NewUnprocessableEntity("unknown account status " + status)
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultUserPageSize
+	}
+	pageSize = min(pageSize, maxUserPageSize)
+
+	users, total, err := s.adminService.ListUsers(ctx, domain.UserQuery{
+		Search: search,
+		Status: domain.AccountStatus(status),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list users", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return &UserPage{Users: users, Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+func (s *adminApplicationService) GetUser(ctx context.Context, id string) (*domain.AuthUser, *apperrors.Error) {
+	user, err := s.adminService.GetUser(ctx, id)
+	if err != nil {
+		return nil, s.adminError("Failed to get user", err)
+	}
+
+	return user, nil
+}
+
+func (s *adminApplicationService) SuspendUser(ctx context.Context, actor *domain.AuthUser, id, reason string) (*domain.AuthUser, *apperrors.Error) {
+	user, err := s.adminService.Suspend(ctx, actor, id, reason)
+	if err != nil {
+		return nil, s.adminError("Failed to suspend user", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("User %s suspended by %s: %s", user.ID, actor.ID, reason))
+
+	return user, nil
+}
+
+func (s *adminApplicationService) DeactivateUser(ctx context.Context, actor *domain.AuthUser, id, reason string) (*domain.AuthUser, *apperrors.Error) {
+	user, err := s.adminService.Deactivate(ctx, actor, id, reason)
+	if err != nil {
+		return nil, s.adminError("Failed to deactivate user", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("User %s deactivated by %s: %s", user.ID, actor.ID, reason))
+
+	return user, nil
+}
+
+func (s *adminApplicationService) ActivateUser(ctx context.Context, actor *domain.AuthUser, id string) (*domain.AuthUser, *apperrors.Error) {
+	user, err := s.adminService.Activate(ctx, actor, id)
+	if err != nil {
+		return nil, s.adminError("Failed to activate user", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("User %s activated by %s", user.ID, actor.ID))
+
+	return user, nil
+}
+
+func (s *adminApplicationService) VerifyUser(ctx context.Context, actor *domain.AuthUser, id string) (*domain.AuthUser, *apperrors.Error) {
+	user, err := s.adminService.Verify(ctx, id)
+	if err != nil {
+		return nil, s.adminError("Failed to verify user", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("User %s verified by %s", user.ID, actor.ID))
+
+	return user, nil
+}
+
+func (s *adminApplicationService) DeleteUser(ctx context.Context, actor *domain.AuthUser, id string) (time.Time, *apperrors.Error) {
+	purgeAfter, err := s.adminService.Delete(ctx, actor, id)
+	if err != nil {
+		return time.Time{}, s.adminError("Failed to delete user", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("User %s deleted by %s", id, actor.ID))
+
+	return purgeAfter, nil
+}
+
+// adminError maps the expected errors of the admin service, the others are logged as internal errors
+func (s *adminApplicationService) adminError(message string, err error) *apperrors.Error {
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		s.logger.Debug(message, err)
+		return apperrors.NewNotFound("user not found")
+	case errors.Is(err, admin.ErrSelfAction), errors.Is(err, domain.ErrStatusReasonRequired):
+		s.logger.Debug(message, err)
+		return apperrors.NewUnprocessableEntity(err.Error())
+	case errors.Is(err, domain.ErrInvalidStatusTransition):
+		s.logger.Debug(message, err)
+		return apperrors.NewConflict(err.Error())
+	}
+
+	s.logger.Error(message, err)
+	return apperrors.NewInternal()
+}
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/apikey"
+	"go-template/internal/shared/infrastructure/logger"
+	"go-template/pkg/apperrors"
+	"time"
+)
+
+type ApiKeyApplicationService interface {
+	CreateApiKey(ctx context.Context, user *domain.AuthUser, name string, scopes []string, expiresAt *time.Time) (*domain.ApiKey, string, *apperrors.Error)
+	ListApiKeys(ctx context.Context, user *domain.AuthUser) ([]*domain.ApiKey, *apperrors.Error)
+	UpdateApiKey(ctx context.Context, user *domain.AuthUser, id, name string, scopes []string) (*domain.ApiKey, *apperrors.Error)
+	RevokeApiKey(ctx context.Context, user *domain.AuthUser, id string) *apperrors.Error
+}
+
+type apiKeyApplicationService struct {
+	apiKeyService *apikey.ApiKeyService
+	logger        logger.Logger
+}
+
+func NewApiKeyApplicationService(
+	apiKeyService *apikey.ApiKeyService,
+	logger logger.Logger,
+) ApiKeyApplicationService {
+	return &apiKeyApplicationService{
+		apiKeyService: apiKeyService,
+		logger:        logger,
+	}
+}
+
+func (s *apiKeyApplicationService) CreateApiKey(ctx context.Context, user *domain.AuthUser, name string, scopes []string, expiresAt *time.Time) (*domain.ApiKey, string, *apperrors.Error) {
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, "", apperrors.NewUnprocessableEntity("expiration time must be in the future")
+	}
+
+	apiKey, key, err := s.apiKeyService.Create(ctx, user, name, scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidScope) {
+			return nil, "", apperrors.NewUnprocessableEntity(err.Error())
+		}
+
+		s.logger.Error("Failed to create api key", err)
+		return nil, "", apperrors.NewInternal()
+	}
+
+	s.logger.Info(fmt.Sprintf("Api key %s created for user %s", apiKey.ID, user.ID))
+
+	return apiKey, key, nil
+}
+
+func (s *apiKeyApplicationService) ListApiKeys(ctx context.Context, user *domain.AuthUser) ([]*domain.ApiKey, *apperrors.Error) {
+	apiKeys, err := s.apiKeyService.List(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to list api keys", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return apiKeys, nil
+}
+
+func (s *apiKeyApplicationService) UpdateApiKey(ctx context.Context, user *domain.AuthUser, id, name string, scopes []string) (*domain.ApiKey, *apperrors.Error) {
+	apiKey, err := s.apiKeyService.Update(ctx, user, id, name, scopes)
+	if err != nil {
+		if err == domain.ErrApiKeyNotFound {
+			return nil, apperrors.NewNotFound("api key not found")
+		}
+
+		if errors.Is(err, domain.ErrInvalidScope) {
+			return nil, apperrors.NewUnprocessableEntity(err.Error())
+		}
+
+		s.logger.Error("Failed to update api key", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return apiKey, nil
+}
+
+func (s *apiKeyApplicationService) RevokeApiKey(ctx context.Context, user *domain.AuthUser, id string) *apperrors.Error {
+	err := s.apiKeyService.Revoke(ctx, user, id)
+	if err != nil {
+		if err == domain.ErrApiKeyNotFound {
+			return apperrors.NewNotFound("api key not found")
+		}
+
+		s.logger.Error("Failed to revoke api key", err)
+		return apperrors.NewInternal()
+	}
+
+	s.logger.Info(fmt.Sprintf("Api key %s revoked for user %s", id, user.ID))
+
+	return nil
+}
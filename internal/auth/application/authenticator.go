@@ -1,41 +1,143 @@
 package application
 
 import (
+	"context"
+	"errors"
 	"go-template/internal/auth/domain"
 	"go-template/internal/auth/domain/basic"
+	"go-template/internal/shared/infrastructure/database"
 	"go-template/internal/shared/infrastructure/logger"
 	"go-template/pkg/apperrors"
+	"strings"
 )
 
+// AuthStrategy authenticates the credentials of a single Authorization scheme,
+// e.g. "Basic <base64(email:password)>" or "Bearer <access token>"
+type AuthStrategy interface {
+	Scheme() string
+	Authenticate(credentials string) (*domain.AuthUser, error)
+}
+
+// ThrottledStrategy is implemented by the strategies checking a guessable secret such as a password,
+// their failures are counted per account and per client IP
+type ThrottledStrategy interface {
+	// Account returns the account the credentials claim, empty if they cannot be decoded
+	Account(credentials string) string
+}
+
+// EventRecorder appends the failed attempts of the throttled strategies to the audit trail
+type EventRecorder interface {
+	RecordEvent(ctx context.Context, event *domain.AuthEvent) error
+}
+
+// Authenticator selects the strategy matching the scheme of the Authorization header
 type Authenticator interface {
-	BasicAuthenticate(token string) (*domain.AuthUser, *apperrors.Error)
+	Authenticate(ctx context.Context, authorizationHeader, clientIP string) (*domain.AuthUser, *apperrors.Error)
+	Schemes() []string
 }
 
 type authenticatorService struct {
-	basicService *basic.BasicService
-	logger       logger.Logger
+	strategies map[string]AuthStrategy
+	schemes    []string
+	loginGuard *LoginGuard
+	recorder   EventRecorder
+	logger     logger.Logger
 }
 
+// NewAuthenticatorService creates the authenticator, the login guard and the event recorder are optional
 func NewAuthenticatorService(
-	basicService *basic.BasicService,
 	logger logger.Logger,
+	loginGuard *LoginGuard,
+	recorder EventRecorder,
+	strategies ...AuthStrategy,
 ) Authenticator {
-	return &authenticatorService{
-		basicService: basicService,
-		logger:       logger,
+	s := &authenticatorService{
+		strategies: make(map[string]AuthStrategy),
+		loginGuard: loginGuard,
+		recorder:   recorder,
+		logger:     logger,
+	}
+
+	for _, strategy := range strategies {
+		s.strategies[strings.ToLower(strategy.Scheme())] = strategy
+		s.schemes = append(s.schemes, strategy.Scheme())
 	}
+
+	return s
 }
 
-func (s *authenticatorService) BasicAuthenticate(token string) (*domain.AuthUser, *apperrors.Error) {
-	user, err := s.basicService.Authenticate(token)
+func (s *authenticatorService) Authenticate(ctx context.Context, authorizationHeader, clientIP string) (*domain.AuthUser, *apperrors.Error) {
+	if authorizationHeader == "" {
+		return &domain.AuthUser{}, apperrors.NewAuthorization("Authorization header is required")
+	}
+
+	// the scheme is case-insensitive (RFC 7235)
+	scheme, credentials, found := strings.Cut(authorizationHeader, " ")
+	strategy, supported := s.strategies[strings.ToLower(scheme)]
+	if !found || !supported || credentials == "" || strings.Contains(credentials, " ") {
+		return &domain.AuthUser{}, apperrors.NewAuthorization("Invalid token format")
+	}
+
+	// guessable credentials are throttled before they are checked, the check is what costs
+	throttledStrategy, guessable := strategy.(ThrottledStrategy)
+	throttled := guessable && s.loginGuard != nil
+
+	var account string
+	if guessable {
+		account = throttledStrategy.Account(credentials)
+	}
+
+	if throttled {
+		if err := s.loginGuard.Check(ctx, account, clientIP); err != nil {
+			s.recordFailure(ctx, account, loginFailedThrottled)
+			return &domain.AuthUser{}, err
+		}
+	}
+
+	user, err := strategy.Authenticate(credentials)
 	if err != nil {
-		if err == basic.ErrInvalidToken {
+		// the credentials were valid, it is not a failed guess
+		if domain.IsAuthenticationError(err) || errors.Is(err, basic.ErrSecondFactorRequired) {
 			s.logger.Debug("Failed to authenticate user", err)
-		} else {
+			if guessable {
+				s.recordFailure(ctx, account, err.Error())
+			}
+			return &domain.AuthUser{}, apperrors.NewForbidden(err.Error())
+		}
+
+		if errors.Is(err, database.ErrDatabaseError) {
 			s.logger.Error("Failed to authenticate user", err)
+		} else {
+			s.logger.Debug("Failed to authenticate user", err)
+			if throttled {
+				s.loginGuard.Failure(ctx, account, clientIP)
+			}
+			if guessable {
+				s.recordFailure(ctx, account, loginFailedInvalidCredentials)
+			}
 		}
-		return &domain.AuthUser{}, apperrors.NewAuthorization("invalid credentials")
+		return &domain.AuthUser{}, apperrors.NewAuthorization("Invalid credentials")
+	}
+
+	if throttled {
+		s.loginGuard.Success(ctx, account)
 	}
 
 	return user, nil
 }
+
+// recordFailure appends the failed attempt on the account to the audit trail, a failure to record is only logged
+func (s *authenticatorService) recordFailure(ctx context.Context, account, detail string) {
+	if s.recorder == nil {
+		return
+	}
+
+	if err := s.recorder.RecordEvent(ctx, domain.NewAuthEvent(domain.AuthEventLoginFailed, "", account, detail)); err != nil {
+		s.logger.Error("Failed to record auth event", err)
+	}
+}
+
+// Schemes returns the supported schemes in registration order, used for the WWW-Authenticate header
+func (s *authenticatorService) Schemes() []string {
+	return s.schemes
+}
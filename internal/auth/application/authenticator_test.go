@@ -0,0 +1,193 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/basic"
+	"go-template/internal/auth/domain/throttle"
+	"go-template/internal/auth/infrastructure"
+	"go-template/internal/shared/infrastructure/database"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLogger struct {
+	mock.Mock
+}
+
+func (m *MockLogger) Info(args ...interface{})  {}
+func (m *MockLogger) Error(args ...interface{}) { m.Called() }
+func (m *MockLogger) Debug(args ...interface{}) {}
+func (m *MockLogger) Warn(args ...interface{})  {}
+
+type fakeStrategy struct {
+	scheme string
+	user   *domain.AuthUser
+	err    error
+}
+
+func (s *fakeStrategy) Scheme() string { return s.scheme }
+func (s *fakeStrategy) Authenticate(credentials string) (*domain.AuthUser, error) {
+	if credentials != "valid" {
+		return nil, s.err
+	}
+	return s.user, nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	basicUser := &domain.AuthUser{ID: "basic-user"}
+	bearerUser := &domain.AuthUser{ID: "bearer-user"}
+
+	mockLogger := new(MockLogger)
+	authenticator := NewAuthenticatorService(
+		mockLogger,
+		nil,
+		nil,
+		&fakeStrategy{scheme: "Bearer", user: bearerUser, err: errors.New("invalid token")},
+		&fakeStrategy{scheme: "Basic", user: basicUser, err: database.ErrDatabaseError},
+	)
+
+	t.Run("Test strategy selection", func(t *testing.T) {
+		user, err := authenticator.Authenticate(context.Background(), "Bearer valid", "")
+		if err != nil || user.ID != bearerUser.ID {
+			t.Errorf("User should be %s, got %v (%v)", bearerUser.ID, user, err)
+		}
+
+		user, err = authenticator.Authenticate(context.Background(), "basic valid", "")
+		if err != nil || user.ID != basicUser.ID {
+			t.Errorf("User should be %s, got %v (%v)", basicUser.ID, user, err)
+		}
+	})
+
+	t.Run("Test invalid header", func(t *testing.T) {
+		for _, header := range []string{"", "Bearer", "Digest valid", "Bearer valid extra"} {
+			_, err := authenticator.Authenticate(context.Background(), header, "")
+			if err == nil || err.Status() != http.StatusUnauthorized {
+				t.Errorf("Header %q should be unauthorized, got %v", header, err)
+			}
+		}
+	})
+
+	t.Run("Test failed authentication", func(t *testing.T) {
+		_, err := authenticator.Authenticate(context.Background(), "Bearer invalid", "")
+		if err == nil || err.Message != "Invalid credentials" {
+			t.Errorf("Error should be invalid credentials, got %v", err)
+		}
+		mockLogger.AssertNotCalled(t, "Error")
+
+		// database failures are logged as errors
+		mockLogger.On("Error").Once()
+		_, err = authenticator.Authenticate(context.Background(), "Basic invalid", "")
+		if err == nil || err.Message != "Invalid credentials" {
+			t.Errorf("Error should be invalid credentials, got %v", err)
+		}
+		mockLogger.AssertExpectations(t)
+	})
+
+	t.Run("Test second factor required", func(t *testing.T) {
+		authenticator := NewAuthenticatorService(
+			new(MockLogger),
+			nil,
+			nil,
+			&fakeStrategy{scheme: "Basic", err: basic.ErrSecondFactorRequired},
+		)
+
+		_, err := authenticator.Authenticate(context.Background(), "Basic invalid", "")
+		if err == nil || err.Status() != http.StatusForbidden {
+			t.Errorf("Error should be forbidden, got %v", err)
+		}
+	})
+
+	t.Run("Test Schemes", func(t *testing.T) {
+		schemes := authenticator.Schemes()
+		if len(schemes) != 2 || schemes[0] != "Bearer" || schemes[1] != "Basic" {
+			t.Errorf("Schemes should be [Bearer Basic], got %v", schemes)
+		}
+	})
+}
+
+// fakeThrottledStrategy claims the credentials as the account, like basic credentials carry the email
+type fakeThrottledStrategy struct {
+	fakeStrategy
+}
+
+func (s *fakeThrottledStrategy) Account(credentials string) string { return "test@example.com" }
+
+type fakeCloudWatchModule struct {
+	metrics []string
+}
+
+func (m *fakeCloudWatchModule) PublishMetric(namespace, metricName string, value float64, unit types.StandardUnit) {
+	m.metrics = append(m.metrics, metricName)
+}
+func (m *fakeCloudWatchModule) Shutdown() {}
+
+type fakeRecorder struct {
+	events []*domain.AuthEvent
+}
+
+func (r *fakeRecorder) RecordEvent(ctx context.Context, event *domain.AuthEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestAuthenticatorThrottling(t *testing.T) {
+	policy := throttle.DefaultPolicy()
+	policy.FreeAttempts = 2
+	policy.MaxAccountFailures = 3
+
+	cloudWatchModule := &fakeCloudWatchModule{}
+	recorder := &fakeRecorder{}
+	loginGuard := NewLoginGuard(throttle.NewGuard(infrastructure.NewMemoryAttemptStore(), policy), new(MockLogger), cloudWatchModule)
+	authenticator := NewAuthenticatorService(
+		new(MockLogger),
+		loginGuard,
+		recorder,
+		&fakeThrottledStrategy{fakeStrategy{scheme: "Basic", user: &domain.AuthUser{ID: "basic-user"}, err: errors.New("invalid password")}},
+		&fakeStrategy{scheme: "Bearer", user: &domain.AuthUser{ID: "bearer-user"}, err: errors.New("invalid token")},
+	)
+
+	ctx := context.Background()
+
+	for i := 0; i < policy.FreeAttempts; i++ {
+		if _, err := authenticator.Authenticate(ctx, "Basic invalid", "10.0.0.1"); err.Status() != http.StatusUnauthorized {
+			t.Fatalf("Free attempt %d should be unauthorized, got %v", i, err)
+		}
+	}
+
+	// the third failure starts the backoff and locks the account out
+	authenticator.Authenticate(ctx, "Basic invalid", "10.0.0.1")
+
+	_, err := authenticator.Authenticate(ctx, "Basic valid", "10.0.0.2")
+	if err == nil || err.Status() != http.StatusTooManyRequests {
+		t.Fatalf("Error should be too many requests, got %v", err)
+	}
+
+	if err.RetryAfter <= 0 || err.RetryAfter > int(policy.LockoutDuration.Seconds()) {
+		t.Errorf("Retry after should be within the lockout duration, got %d", err.RetryAfter)
+	}
+
+	if len(cloudWatchModule.metrics) != 1 || cloudWatchModule.metrics[0] != "account_lockout_count" {
+		t.Errorf("Lockout should be published, got %v", cloudWatchModule.metrics)
+	}
+
+	// strategies without a guessable secret are not throttled
+	if _, err := authenticator.Authenticate(ctx, "Bearer valid", "10.0.0.1"); err != nil {
+		t.Errorf("Error should be nil, got %v", err)
+	}
+
+	// the failed and the refused attempts are recorded for the account, the bearer ones are not
+	authenticator.Authenticate(ctx, "Bearer invalid", "10.0.0.1")
+	if len(recorder.events) != policy.FreeAttempts+2 {
+		t.Fatalf("Failures should be recorded, got %d events", len(recorder.events))
+	}
+
+	last := recorder.events[len(recorder.events)-1]
+	if last.Type != domain.AuthEventLoginFailed || last.Email != "test@example.com" || last.Detail != loginFailedThrottled {
+		t.Errorf("Throttled attempt should be recorded, got %+v", last)
+	}
+}
@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-template/internal/auth/domain/throttle"
+	"go-template/internal/aws/cloudwatch"
+	appConfig "go-template/internal/config"
+	"go-template/internal/shared/infrastructure/logger"
+	"go-template/pkg/apperrors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+/*
+LoginGuard throttles the password and second factor checks per account and per client IP
+- A store failure is logged and the attempt is let through, the store must not take the login down
+- Lockouts are logged and published as CloudWatch metrics
+*/
+type LoginGuard struct {
+	guard            *throttle.Guard
+	logger           logger.Logger
+	cloudWatchModule cloudwatch.CloudWatchModule
+}
+
+func NewLoginGuard(guard *throttle.Guard, logger logger.Logger, cloudWatchModule cloudwatch.CloudWatchModule) *LoginGuard {
+	return &LoginGuard{
+		guard:            guard,
+		logger:           logger,
+		cloudWatchModule: cloudWatchModule,
+	}
+}
+
+// Check returns a 429 with the time to wait when the account or the IP is throttled
+func (g *LoginGuard) Check(ctx context.Context, email, clientIP string) *apperrors.Error {
+	err := g.guard.Check(ctx, subjects(email, clientIP)...)
+	if err == nil {
+		return nil
+	}
+
+	var throttled *throttle.ThrottledError
+	if errors.As(err, &throttled) {
+		g.logger.Debug("Login attempt throttled", err)
+		if throttled.Locked {
+			return apperrors.NewTooManyRequests("too many failed attempts, try again later", throttled.RetryAfter)
+		}
+		return apperrors.NewTooManyRequests("too many failed attempts, slow down", throttled.RetryAfter)
+	}
+
+	g.logger.Error("Failed to check login attempts", err)
+	return nil
+}
+
+func (g *LoginGuard) Failure(ctx context.Context, email, clientIP string) {
+	lockouts, err := g.guard.RecordFailure(ctx, subjects(email, clientIP)...)
+	if err != nil {
+		g.logger.Error("Failed to record login failure", err)
+	}
+
+	for _, lockout := range lockouts {
+		g.logger.Warn(fmt.Sprintf("Login locked out for %s %s after %d failures until %s",
+			lockout.Subject.Kind, lockout.Subject.Value, lockout.Failures, lockout.Until.Format(time.RFC3339)))
+
+		g.cloudWatchModule.PublishMetric(
+			appConfig.App.Name+"/Auth",
+			string(lockout.Subject.Kind)+"_lockout_count",
+			1,
+			types.StandardUnitCount,
+		)
+	}
+}
+
+// Success forgets the failures of the account, the IP keeps its count
+func (g *LoginGuard) Success(ctx context.Context, email string) {
+	if err := g.guard.RecordSuccess(ctx, throttle.Account(email)); err != nil {
+		g.logger.Error("Failed to reset login failures", err)
+	}
+}
+
+func subjects(email, clientIP string) []throttle.Subject {
+	subjects := make([]throttle.Subject, 0, 2)
+	if email != "" {
+		subjects = append(subjects, throttle.Account(email))
+	}
+	if clientIP != "" {
+		subjects = append(subjects, throttle.IP(clientIP))
+	}
+
+	return subjects
+}
@@ -0,0 +1,93 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/mfa"
+	"go-template/internal/shared/infrastructure/logger"
+	"go-template/pkg/apperrors"
+)
+
+type SecondFactorApplicationService interface {
+	EnrollTOTP(ctx context.Context, user *domain.AuthUser) (*mfa.Enrollment, *apperrors.Error)
+	ConfirmTOTP(ctx context.Context, user *domain.AuthUser, code string) ([]string, *apperrors.Error)
+	DisableTOTP(ctx context.Context, user *domain.AuthUser, code string) *apperrors.Error
+}
+
+type secondFactorApplicationService struct {
+	mfaService *mfa.MFAService
+	logger     logger.Logger
+}
+
+func NewSecondFactorApplicationService(
+	mfaService *mfa.MFAService,
+	logger logger.Logger,
+) SecondFactorApplicationService {
+	return &secondFactorApplicationService{
+		mfaService: mfaService,
+		logger:     logger,
+	}
+}
+
+func (s *secondFactorApplicationService) EnrollTOTP(ctx context.Context, user *domain.AuthUser) (*mfa.Enrollment, *apperrors.Error) {
+	enrollment, err := s.mfaService.Enroll(ctx, user)
+	if err != nil {
+		if err == mfa.ErrAlreadyEnabled {
+			s.logger.Debug("Failed to enroll second factor", err)
+			return nil, apperrors.NewConflict(err.Error())
+		}
+
+		s.logger.Error("Failed to enroll second factor", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return enrollment, nil
+}
+
+func (s *secondFactorApplicationService) ConfirmTOTP(ctx context.Context, user *domain.AuthUser, code string) ([]string, *apperrors.Error) {
+	recoveryCodes, err := s.mfaService.Confirm(ctx, user, code)
+	if err != nil {
+		if appErr := mapSecondFactorError(err); appErr != nil {
+			s.logger.Debug("Failed to confirm second factor", err)
+			return nil, appErr
+		}
+
+		s.logger.Error("Failed to confirm second factor", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	s.logger.Info(fmt.Sprintf("Second factor enabled for user %s", user.ID))
+
+	return recoveryCodes, nil
+}
+
+func (s *secondFactorApplicationService) DisableTOTP(ctx context.Context, user *domain.AuthUser, code string) *apperrors.Error {
+	if err := s.mfaService.Disable(ctx, user, code); err != nil {
+		if appErr := mapSecondFactorError(err); appErr != nil {
+			s.logger.Debug("Failed to disable second factor", err)
+			return appErr
+		}
+
+		s.logger.Error("Failed to disable second factor", err)
+		return apperrors.NewInternal()
+	}
+
+	s.logger.Info(fmt.Sprintf("Second factor disabled for user %s", user.ID))
+
+	return nil
+}
+
+// mapSecondFactorError returns nil for unexpected errors
+func mapSecondFactorError(err error) *apperrors.Error {
+	switch err {
+	case mfa.ErrNotEnrolled:
+		return apperrors.NewNotFound(err.Error())
+	case mfa.ErrAlreadyEnabled:
+		return apperrors.NewConflict(err.Error())
+	case mfa.ErrInvalidCode, mfa.ErrCodeAlreadyUsed:
+		return apperrors.NewUnprocessableEntity(err.Error())
+	}
+
+	return nil
+}
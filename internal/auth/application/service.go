@@ -2,30 +2,80 @@ package application
 
 import (
 	"context"
+	"errors"
 	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/basic"
+	"go-template/internal/auth/domain/bearer"
+	"go-template/internal/auth/domain/mfa"
 	"go-template/internal/shared/infrastructure/logger"
 	"go-template/pkg/apperrors"
+	"time"
 )
 
 type AuthApplicationService interface {
 	Register(ctx context.Context, email, firstName, lastName, password string) (*domain.AuthUser, *apperrors.Error)
-	UpdateUser(ctx context.Context, user *domain.AuthUser, firstName, lastName, password string) (*domain.AuthUser, *apperrors.Error)
+	Login(ctx context.Context, email, password, clientIP string) (*LoginResult, *apperrors.Error)
+	CompleteSecondFactor(ctx context.Context, challengeToken, code, clientIP string) (*bearer.Session, *domain.AuthUser, *apperrors.Error)
+	RefreshSession(ctx context.Context, refreshToken string) (*bearer.Session, *domain.AuthUser, *apperrors.Error)
+	Logout(ctx context.Context, refreshToken string) *apperrors.Error
+	LogoutAll(ctx context.Context, user *domain.AuthUser) *apperrors.Error
+	UpdateUser(ctx context.Context, user *domain.AuthUser, firstName, lastName, password, currentPassword string) (*domain.AuthUser, *apperrors.Error)
+	PatchUser(ctx context.Context, user *domain.AuthUser, firstName, lastName, password *string, currentPassword string) (*domain.AuthUser, *apperrors.Error)
 	VerifyAccount(ctx context.Context, token, userId string) *apperrors.Error
 	ResendVerification(ctx context.Context, user *domain.AuthUser) *apperrors.Error
+	RequestPasswordReset(ctx context.Context, email string) *apperrors.Error
+	ConfirmPasswordReset(ctx context.Context, token, newPassword string) *apperrors.Error
+	RequestEmailChange(ctx context.Context, user *domain.AuthUser, newEmail, currentPassword string) *apperrors.Error
+	ConfirmEmailChange(ctx context.Context, token string) *apperrors.Error
+	DeleteAccount(ctx context.Context, user *domain.AuthUser, currentPassword string) (time.Time, *apperrors.Error)
+	ListActivity(ctx context.Context, user *domain.AuthUser, page, pageSize int) (*ActivityPage, *apperrors.Error)
 }
 
+// LoginResult holds the new session, or the challenge to complete when the user has a second factor
+type LoginResult struct {
+	Session           *bearer.Session
+	User              *domain.AuthUser
+	SecondFactorToken string
+}
+
+// details of the auth events
+const (
+	loginFailedInvalidCredentials = "invalid credentials"
+	loginFailedThrottled          = "throttled"
+	loginSucceededSecondFactor    = "second factor"
+	passwordChangedReset          = "reset"
+	emailVerifiedChange           = "email change"
+)
+
+const (
+	defaultActivityPageSize = 20
+	maxActivityPageSize     = 100
+)
+
 type authApplicationService struct {
-	authService domain.AuthService
-	logger      logger.Logger
+	authService   domain.AuthService
+	basicService  *basic.BasicService
+	bearerService *bearer.BearerService
+	mfaService    *mfa.MFAService
+	loginGuard    *LoginGuard
+	logger        logger.Logger
 }
 
 func NewAuthApplicationService(
 	authService domain.AuthService,
+	basicService *basic.BasicService,
+	bearerService *bearer.BearerService,
+	mfaService *mfa.MFAService,
+	loginGuard *LoginGuard,
 	logger logger.Logger,
 ) AuthApplicationService {
 	return &authApplicationService{
-		authService: authService,
-		logger:      logger,
+		authService:   authService,
+		basicService:  basicService,
+		bearerService: bearerService,
+		mfaService:    mfaService,
+		loginGuard:    loginGuard,
+		logger:        logger,
 	}
 }
 
@@ -43,45 +93,320 @@ func (s *authApplicationService) Register(ctx context.Context, email, firstName,
 		return &domain.AuthUser{}, apperrors.NewBadRequest("user already exists")
 	}
 
-	// 2. create user
-	authUser, err := s.authService.CreateUser(ctx, email, firstName, lastName, password)
+	// 2. create user and queue the verification email, neither is stored when the other fails
+	authUser, err := s.authService.RegisterUser(ctx, email, firstName, lastName, password)
 	if err != nil {
-		s.logger.Error("Failed to create user", err)
+		if appErr := passwordPolicyError(err); appErr != nil {
+			s.logger.Debug("Password does not meet the policy", err)
+			return &domain.AuthUser{}, appErr
+		}
+
+		// the email was taken by a concurrent registration or email change
+		if err == domain.ErrDuplicateEntry {
+			s.logger.Debug("User already exists", err)
+			return &domain.AuthUser{}, apperrors.NewConflict("email already in use")
+		}
+
+		s.logger.Error("Failed to register user", err)
 		return &domain.AuthUser{}, apperrors.NewInternal()
 	}
 
-	// 3. send verification email
-	err = s.authService.SendVerificationEmail(authUser)
+	s.recordEvent(ctx, domain.AuthEventRegistered, authUser.ID, authUser.Email, "")
+
+	return authUser, nil
+}
+
+func (s *authApplicationService) Login(ctx context.Context, email, password, clientIP string) (*LoginResult, *apperrors.Error) {
+	// 1. refuse to check the password while the account or the IP is throttled
+	if err := s.loginGuard.Check(ctx, email, clientIP); err != nil {
+		s.recordEvent(ctx, domain.AuthEventLoginFailed, "", email, loginFailedThrottled)
+		return nil, err
+	}
+
+	// 2. check the email and password
+	user, err := s.basicService.AuthenticateCredentials(email, password)
 	if err != nil {
-		s.logger.Error("Failed to send verification email", err)
-		return &domain.AuthUser{}, apperrors.NewInternal()
+		if domain.IsAuthenticationError(err) {
+			s.logger.Debug("Failed to login user", err)
+			s.recordEvent(ctx, domain.AuthEventLoginFailed, "", email, err.Error())
+			return nil, apperrors.NewForbidden(err.Error())
+		}
+
+		if err == basic.ErrInvalidToken || err == domain.ErrUserNotFound {
+			s.logger.Debug("Failed to login user", err)
+			s.loginGuard.Failure(ctx, email, clientIP)
+			s.recordEvent(ctx, domain.AuthEventLoginFailed, "", email, loginFailedInvalidCredentials)
+		} else {
+			s.logger.Error("Failed to login user", err)
+		}
+		return nil, apperrors.NewAuthorization("invalid credentials")
 	}
 
-	return authUser, nil
+	// 3. users with a second factor get a challenge instead of a session,
+	// the failures are only forgotten once the second factor is passed as well
+	enabled, err := s.mfaService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to check second factor", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if enabled {
+		challengeToken, err := s.mfaService.StartChallenge(ctx, user)
+		if err != nil {
+			s.logger.Error("Failed to start second factor challenge", err)
+			return nil, apperrors.NewInternal()
+		}
+
+		return &LoginResult{User: user, SecondFactorToken: challengeToken}, nil
+	}
+
+	s.loginGuard.Success(ctx, email)
+
+	// 4. start a new session
+	session, err := s.bearerService.StartSession(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to start session", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	s.recordLogin(ctx, user, "")
+
+	return &LoginResult{Session: session, User: user}, nil
 }
 
-func (s *authApplicationService) UpdateUser(ctx context.Context, user *domain.AuthUser, firstName, lastName, password string) (*domain.AuthUser, *apperrors.Error) {
-	// 2. update user
-	err := user.Update(firstName, lastName, password)
+func (s *authApplicationService) CompleteSecondFactor(ctx context.Context, challengeToken, code, clientIP string) (*bearer.Session, *domain.AuthUser, *apperrors.Error) {
+	// 1. the challenge names the account, the codes are not checked while the account or the IP is throttled
+	challenged, err := s.mfaService.ChallengeUser(ctx, challengeToken)
+	if err != nil {
+		if err == mfa.ErrInvalidChallenge {
+			s.logger.Debug("Failed to complete second factor", err)
+			return nil, &domain.AuthUser{}, apperrors.NewAuthorization(err.Error())
+		}
+
+		s.logger.Error("Failed to complete second factor", err)
+		return nil, &domain.AuthUser{}, apperrors.NewInternal()
+	}
+
+	if err := s.loginGuard.Check(ctx, challenged.Email, clientIP); err != nil {
+		s.recordEvent(ctx, domain.AuthEventLoginFailed, challenged.ID, challenged.Email, loginFailedThrottled)
+		return nil, &domain.AuthUser{}, err
+	}
+
+	// 2. check the challenge and the code
+	user, err := s.mfaService.CompleteChallenge(ctx, challengeToken, code)
+	if err != nil {
+		switch err {
+		case mfa.ErrInvalidCode, mfa.ErrCodeAlreadyUsed:
+			s.logger.Debug("Failed to complete second factor", err)
+			s.loginGuard.Failure(ctx, user.Email, clientIP)
+			s.recordEvent(ctx, domain.AuthEventLoginFailed, user.ID, user.Email, err.Error())
+			return nil, &domain.AuthUser{}, apperrors.NewAuthorization(err.Error())
+		case mfa.ErrInvalidChallenge, mfa.ErrNotEnrolled:
+			s.logger.Debug("Failed to complete second factor", err)
+			return nil, &domain.AuthUser{}, apperrors.NewAuthorization(err.Error())
+		case domain.ErrAccountSuspended, domain.ErrAccountDeactivated:
+			s.logger.Debug("Failed to complete second factor", err)
+			s.recordEvent(ctx, domain.AuthEventLoginFailed, user.ID, user.Email, err.Error())
+			return nil, &domain.AuthUser{}, apperrors.NewForbidden(err.Error())
+		}
+
+		s.logger.Error("Failed to complete second factor", err)
+		return nil, &domain.AuthUser{}, apperrors.NewInternal()
+	}
+
+	s.loginGuard.Success(ctx, user.Email)
+
+	// 3. start a new session with the second factor
+	session, err := s.bearerService.StartSession(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to start session", err)
+		return nil, &domain.AuthUser{}, apperrors.NewInternal()
+	}
+
+	s.recordLogin(ctx, user, loginSucceededSecondFactor)
+
+	return session, user, nil
+}
+
+func (s *authApplicationService) RefreshSession(ctx context.Context, refreshToken string) (*bearer.Session, *domain.AuthUser, *apperrors.Error) {
+	session, user, err := s.bearerService.Refresh(ctx, refreshToken)
+	if err != nil {
+		switch err {
+		case bearer.ErrInvalidRefreshToken, bearer.ErrTokenExpired:
+			s.logger.Debug("Failed to refresh session", err)
+			return nil, &domain.AuthUser{}, apperrors.NewAuthorization(err.Error())
+		case bearer.ErrRefreshTokenReused:
+			s.logger.Warn("Refresh token reused, session revoked", err)
+			return nil, &domain.AuthUser{}, apperrors.NewAuthorization(err.Error())
+		case domain.ErrAccountSuspended, domain.ErrAccountDeactivated:
+			s.logger.Debug("Failed to refresh session", err)
+			return nil, &domain.AuthUser{}, apperrors.NewForbidden(err.Error())
+		}
+
+		s.logger.Error("Failed to refresh session", err)
+		return nil, &domain.AuthUser{}, apperrors.NewInternal()
+	}
+
+	return session, user, nil
+}
+
+func (s *authApplicationService) Logout(ctx context.Context, refreshToken string) *apperrors.Error {
+	err := s.bearerService.RevokeSession(ctx, refreshToken)
 	if err != nil {
+		if err == bearer.ErrInvalidRefreshToken {
+			s.logger.Debug("Failed to logout", err)
+			return apperrors.NewAuthorization(err.Error())
+		}
+
+		s.logger.Error("Failed to logout", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (s *authApplicationService) LogoutAll(ctx context.Context, user *domain.AuthUser) *apperrors.Error {
+	err := s.bearerService.RevokeAllSessions(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to logout all sessions", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+/*
+UpdateUser replaces the names
+- Without the current password, password has to be the current one and only confirms the update
+- With the current password, password is the new one and it is changed like with PatchUser
+- Wrong passwords are throttled like logins
+*/
+func (s *authApplicationService) UpdateUser(ctx context.Context, user *domain.AuthUser, firstName, lastName, password, currentPassword string) (*domain.AuthUser, *apperrors.Error) {
+	// 1. check or change the password
+	passwordChanged := false
+	if currentPassword == "" {
+		if err := s.loginGuard.Check(ctx, user.Email, ""); err != nil {
+			return &domain.AuthUser{}, err
+		}
+
+		if !domain.VerifyPassword(user, password) {
+			s.logger.Debug("Failed to update user", domain.ErrInvalidPassword)
+			s.loginGuard.Failure(ctx, user.Email, "")
+			return &domain.AuthUser{}, apperrors.NewForbidden("password is not the current password, current_password is required to change it")
+		}
+	} else {
+		changed, err := s.changePassword(ctx, user, currentPassword, password)
+		if err != nil {
+			return &domain.AuthUser{}, err
+		}
+		passwordChanged = changed
+	}
+
+	// 2. update user
+	user.Update(firstName, lastName)
+
+	if err := s.authService.UpdateUser(ctx, user); err != nil {
 		s.logger.Error("Failed to update user", err)
 		return &domain.AuthUser{}, apperrors.NewInternal()
 	}
 
-	err = s.authService.UpdateUser(ctx, user)
-	if err != nil {
+	// 3. notify the user
+	if passwordChanged {
+		s.passwordChanged(ctx, user)
+	}
+
+	return user, nil
+}
+
+/*
+PatchUser updates the fields that are set
+- Changing the password needs the current password, wrong guesses are throttled like logins
+- The user is only stored when a field is set, the password is only re-hashed when it changes
+*/
+func (s *authApplicationService) PatchUser(ctx context.Context, user *domain.AuthUser, firstName, lastName, password *string, currentPassword string) (*domain.AuthUser, *apperrors.Error) {
+	// 1. update the names
+	if err := user.Patch(firstName, lastName); err != nil {
+		s.logger.Debug("Failed to patch user", err)
+		return &domain.AuthUser{}, apperrors.NewUnprocessableEntity(err.Error())
+	}
+
+	// 2. change the password
+	passwordChanged := false
+	if password != nil {
+		if currentPassword == "" {
+			return &domain.AuthUser{}, apperrors.NewUnprocessableEntity("current password is required to change the password")
+		}
+
+		changed, err := s.changePassword(ctx, user, currentPassword, *password)
+		if err != nil {
+			return &domain.AuthUser{}, err
+		}
+		passwordChanged = changed
+	}
+
+	if firstName == nil && lastName == nil && !passwordChanged {
+		return user, nil
+	}
+
+	// 3. store the user
+	if err := s.authService.UpdateUser(ctx, user); err != nil {
 		s.logger.Error("Failed to update user", err)
 		return &domain.AuthUser{}, apperrors.NewInternal()
 	}
 
+	// 4. notify the user
+	if passwordChanged {
+		s.passwordChanged(ctx, user)
+	}
+
 	return user, nil
 }
 
+// changePassword checks the current password under the login guard and sets the new one on the user, the caller stores it
+func (s *authApplicationService) changePassword(ctx context.Context, user *domain.AuthUser, currentPassword, password string) (bool, *apperrors.Error) {
+	if err := s.loginGuard.Check(ctx, user.Email, ""); err != nil {
+		return false, err
+	}
+
+	changed, err := s.authService.ChangePassword(ctx, user, currentPassword, password)
+	if err != nil {
+		if err == domain.ErrInvalidPassword {
+			s.logger.Debug("Failed to change password", err)
+			s.loginGuard.Failure(ctx, user.Email, "")
+			return false, apperrors.NewForbidden(err.Error())
+		}
+
+		if appErr := passwordPolicyError(err); appErr != nil {
+			s.logger.Debug("Password does not meet the policy", err)
+			return false, appErr
+		}
+
+		s.logger.Error("Failed to change password", err)
+		return false, apperrors.NewInternal()
+	}
+
+	return changed, nil
+}
+
+// passwordChanged records the stored password change and notifies the user, the password is already changed so a failure is only logged
+func (s *authApplicationService) passwordChanged(ctx context.Context, user *domain.AuthUser) {
+	s.recordEvent(ctx, domain.AuthEventPasswordChanged, user.ID, user.Email, "")
+
+	if err := s.authService.SendPasswordChangedNotification(ctx, user); err != nil {
+		s.logger.Error("Failed to send password changed notification", err)
+	}
+}
+
 func (s *authApplicationService) VerifyAccount(ctx context.Context, token, userId string) *apperrors.Error {
 	// 1. verify account
-	err := s.authService.VerifyVerificationEmailToken(token, userId)
+	err := s.authService.VerifyVerificationEmailToken(ctx, token, userId)
 	if err != nil {
-		return apperrors.NewForbidden(err.Error())
+		if err == domain.ErrInvalidToken || err == domain.ErrTokenExpired || err == domain.ErrTokenAlreadyUsed {
+			return apperrors.NewForbidden(err.Error())
+		}
+
+		s.logger.Error("Failed to verify verification email token", err)
+		return apperrors.NewInternal()
 	}
 
 	// 2. update user account status in database
@@ -99,6 +424,8 @@ func (s *authApplicationService) VerifyAccount(ctx context.Context, token, userI
 		return apperrors.NewInternal()
 	}
 
+	s.recordEvent(ctx, domain.AuthEventEmailVerified, userId, "", "")
+
 	return nil
 }
 
@@ -109,11 +436,206 @@ func (s *authApplicationService) ResendVerification(ctx context.Context, user *d
 	}
 
 	// 2. send verification email
-	err := s.authService.SendVerificationEmail(user)
+	err := s.authService.SendVerificationEmail(ctx, user)
 	if err != nil {
 		s.logger.Error("Failed to send verification email", err)
 		return apperrors.NewInternal()
 	}
 
+	s.recordEvent(ctx, domain.AuthEventVerificationResent, user.ID, user.Email, "")
+
 	return nil
 }
+
+func (s *authApplicationService) RequestPasswordReset(ctx context.Context, email string) *apperrors.Error {
+	// 1. find the user, unknown emails are not reported to avoid account enumeration
+	user, err := s.authService.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			s.logger.Debug("Password reset requested for unknown email", err)
+			return nil
+		}
+
+		s.logger.Error("Failed to find user for password reset", err)
+		return apperrors.NewInternal()
+	}
+
+	// 2. send password reset email
+	err = s.authService.SendPasswordResetEmail(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to send password reset email", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (s *authApplicationService) ConfirmPasswordReset(ctx context.Context, token, newPassword string) *apperrors.Error {
+	// 1. reset the password and revoke the outstanding tokens and sessions
+	user, err := s.authService.ResetPassword(ctx, token, newPassword)
+	if err != nil {
+		if err == domain.ErrInvalidToken || err == domain.ErrTokenExpired || err == domain.ErrTokenAlreadyUsed {
+			return apperrors.NewForbidden(err.Error())
+		}
+
+		if appErr := passwordPolicyError(err); appErr != nil {
+			s.logger.Debug("Password does not meet the policy", err)
+			return appErr
+		}
+
+		s.logger.Error("Failed to reset password", err)
+		return apperrors.NewInternal()
+	}
+
+	s.recordEvent(ctx, domain.AuthEventPasswordChanged, user.ID, user.Email, passwordChangedReset)
+
+	// 2. notify the user, the password is already changed so a failure is only logged
+	err = s.authService.SendPasswordChangedNotification(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to send password changed notification", err)
+	}
+
+	return nil
+}
+
+func (s *authApplicationService) RequestEmailChange(ctx context.Context, user *domain.AuthUser, newEmail, currentPassword string) *apperrors.Error {
+	// 1. the current password is required, wrong guesses are throttled like logins
+	if err := s.loginGuard.Check(ctx, user.Email, ""); err != nil {
+		return err
+	}
+
+	if !domain.VerifyPassword(user, currentPassword) {
+		s.logger.Debug("Failed to request email change", domain.ErrInvalidPassword)
+		s.loginGuard.Failure(ctx, user.Email, "")
+		return apperrors.NewForbidden(domain.ErrInvalidPassword.Error())
+	}
+
+	// 2. store the pending address and send it the verification token
+	err := s.authService.RequestEmailChange(ctx, user, newEmail)
+	if err != nil {
+		switch err {
+		case domain.ErrSameEmail:
+			return apperrors.NewUnprocessableEntity(err.Error())
+		case domain.ErrUserAlreadyExists:
+			s.logger.Debug("Email change requested for a taken address", err)
+			return apperrors.NewConflict("email already in use")
+		}
+
+		s.logger.Error("Failed to request email change", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+func (s *authApplicationService) ConfirmEmailChange(ctx context.Context, token string) *apperrors.Error {
+	// 1. switch to the pending address
+	user, oldEmail, err := s.authService.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidToken, domain.ErrTokenExpired, domain.ErrTokenAlreadyUsed:
+			return apperrors.NewForbidden(err.Error())
+		case domain.ErrDuplicateEntry:
+			s.logger.Debug("Email change confirmed for a taken address", err)
+			return apperrors.NewConflict("email already in use")
+		}
+
+		s.logger.Error("Failed to confirm email change", err)
+		return apperrors.NewInternal()
+	}
+
+	s.recordEvent(ctx, domain.AuthEventEmailVerified, user.ID, user.Email, emailVerifiedChange)
+
+	// 2. notify the old address, the email is already changed so a failure is only logged
+	err = s.authService.SendEmailChangedNotification(ctx, user, oldEmail)
+	if err != nil {
+		s.logger.Error("Failed to send email changed notification", err)
+	}
+
+	return nil
+}
+
+func (s *authApplicationService) DeleteAccount(ctx context.Context, user *domain.AuthUser, currentPassword string) (time.Time, *apperrors.Error) {
+	// 1. the current password is required, wrong guesses are throttled like logins
+	if err := s.loginGuard.Check(ctx, user.Email, ""); err != nil {
+		return time.Time{}, err
+	}
+
+	if !domain.VerifyPassword(user, currentPassword) {
+		s.logger.Debug("Failed to delete account", domain.ErrInvalidPassword)
+		s.loginGuard.Failure(ctx, user.Email, "")
+		return time.Time{}, apperrors.NewForbidden(domain.ErrInvalidPassword.Error())
+	}
+
+	// 2. soft delete the account, it is purged after the grace period
+	purgeAfter, err := s.authService.DeleteUser(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to delete account", err)
+		return time.Time{}, apperrors.NewInternal()
+	}
+
+	// 3. notify the user, the account is already deleted so a failure is only logged
+	err = s.authService.SendAccountDeletedNotification(ctx, user, purgeAfter)
+	if err != nil {
+		s.logger.Error("Failed to send account deleted notification", err)
+	}
+
+	return purgeAfter, nil
+}
+
+// ActivityPage is a page of the audit trail of a user, the newest events first
+type ActivityPage struct {
+	Events   []*domain.AuthEvent
+	Page     int
+	PageSize int
+}
+
+// ListActivity falls back to the first page and the default page size, the page size is capped
+func (s *authApplicationService) ListActivity(ctx context.Context, user *domain.AuthUser, page, pageSize int) (*ActivityPage, *apperrors.Error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultActivityPageSize
+	}
+	pageSize = min(pageSize, maxActivityPageSize)
+
+	events, err := s.authService.ListEvents(ctx, user.ID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list activity", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return &ActivityPage{Events: events, Page: page, PageSize: pageSize}, nil
+}
+
+// recordLogin stores the last login and appends the event, the session is already started so a failure is only logged
+func (s *authApplicationService) recordLogin(ctx context.Context, user *domain.AuthUser, detail string) {
+	if err := s.authService.RecordLogin(ctx, user); err != nil {
+		s.logger.Error("Failed to record last login", err)
+	}
+
+	s.recordEvent(ctx, domain.AuthEventLoginSucceeded, user.ID, user.Email, detail)
+}
+
+// recordEvent appends the event to the audit trail, the action already happened so a failure is only logged
+func (s *authApplicationService) recordEvent(ctx context.Context, eventType domain.AuthEventType, userID, email, detail string) {
+	if err := s.authService.RecordEvent(ctx, domain.NewAuthEvent(eventType, userID, email, detail)); err != nil {
+		s.logger.Error("Failed to record auth event", err)
+	}
+}
+
+// passwordPolicyError maps a policy violation to a 422 listing every failed rule, nil for other errors
+func passwordPolicyError(err error) *apperrors.Error {
+	var policyErr *domain.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+
+	details := make([]apperrors.Detail, 0, len(policyErr.Violations))
+	for _, violation := range policyErr.Violations {
+		details = append(details, apperrors.Detail{Code: violation.Rule, Message: violation.Message})
+	}
+
+	return apperrors.NewUnprocessableEntityWithDetails("password does not meet the policy", details)
+}
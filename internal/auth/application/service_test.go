@@ -0,0 +1,124 @@
+package application
+
+import (
+	"context"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/mfa"
+	"go-template/internal/auth/domain/throttle"
+	"go-template/internal/auth/domain/token"
+	"go-template/internal/auth/infrastructure"
+	"net/http"
+	"testing"
+)
+
+type fakeAuthRepository struct {
+	domain.AuthRepository
+	user *domain.AuthUser
+}
+
+func (r *fakeAuthRepository) FindUserByID(ctx context.Context, id string) (*domain.AuthUser, error) {
+	if r.user.ID != id {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *r.user
+	return &copied, nil
+}
+
+type fakeTokenRepository struct{}
+
+func (r *fakeTokenRepository) Create(ctx context.Context, record *token.Record) error { return nil }
+func (r *fakeTokenRepository) Consume(ctx context.Context, id string) error           { return nil }
+func (r *fakeTokenRepository) RevokeByUser(ctx context.Context, userID string, purpose token.Purpose) error {
+	return nil
+}
+
+// fakeAuthService keeps the recorded events and the stored user
+type fakeAuthService struct {
+	domain.AuthService
+	events []*domain.AuthEvent
+	user   *domain.AuthUser
+}
+
+func (s *fakeAuthService) UpdateUser(ctx context.Context, user *domain.AuthUser) error {
+	copied := *user
+	s.user = &copied
+	return nil
+}
+
+func (s *fakeAuthService) RecordEvent(ctx context.Context, event *domain.AuthEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestCompleteSecondFactor(t *testing.T) {
+	ctx := context.Background()
+
+	mockUser, _ := domain.NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	tokenService := token.NewTokenService(&fakeTokenRepository{}, token.NewKeyring("test-secret-key"))
+	cipher, _ := mfa.NewSecretCipher("test-encryption-key")
+	mfaService := mfa.NewMFAService(nil, &fakeAuthRepository{user: mockUser}, tokenService, cipher, "Webapp")
+
+	policy := throttle.DefaultPolicy()
+	policy.FreeAttempts = 1
+	policy.MaxAccountFailures = 2
+	loginGuard := NewLoginGuard(throttle.NewGuard(infrastructure.NewMemoryAttemptStore(), policy), new(MockLogger), &fakeCloudWatchModule{})
+
+	authService := &fakeAuthService{}
+	service := NewAuthApplicationService(authService, nil, nil, mfaService, loginGuard, new(MockLogger))
+
+	t.Run("Test invalid challenge", func(t *testing.T) {
+		_, _, err := service.CompleteSecondFactor(ctx, "invalid", "000000", "10.0.0.1")
+		if err == nil || err.Status() != http.StatusUnauthorized {
+			t.Errorf("Error should be unauthorized, got %v", err)
+		}
+	})
+
+	t.Run("Test locked out account", func(t *testing.T) {
+		// the failures come from rotating IPs, only the account count locks them out
+		for _, ip := range []string{"10.0.0.2", "10.0.0.3"} {
+			loginGuard.Failure(ctx, mockUser.Email, ip)
+		}
+
+		challengeToken, _ := mfaService.StartChallenge(ctx, mockUser)
+		_, _, err := service.CompleteSecondFactor(ctx, challengeToken, "000000", "10.0.0.4")
+		if err == nil || err.Status() != http.StatusTooManyRequests {
+			t.Fatalf("Error should be too many requests, got %v", err)
+		}
+
+		if len(authService.events) != 1 || authService.events[0].UserID != mockUser.ID {
+			t.Errorf("Throttled attempt should be recorded for the account, got %v", authService.events)
+		}
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	ctx := context.Background()
+
+	mockUser, _ := domain.NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	loginGuard := NewLoginGuard(throttle.NewGuard(infrastructure.NewMemoryAttemptStore(), throttle.DefaultPolicy()), new(MockLogger), &fakeCloudWatchModule{})
+	authService := &fakeAuthService{}
+	service := NewAuthApplicationService(authService, nil, nil, nil, loginGuard, new(MockLogger))
+
+	t.Run("Test new password without the current one", func(t *testing.T) {
+		user := *mockUser
+		_, err := service.UpdateUser(ctx, &user, "Jane", "Smith", "newpassword", "")
+		if err == nil || err.Status() != http.StatusForbidden {
+			t.Errorf("Error should be forbidden, got %v", err)
+		}
+
+		if authService.user != nil {
+			t.Errorf("User should not be stored, got %v", authService.user)
+		}
+	})
+
+	t.Run("Test current password", func(t *testing.T) {
+		user := *mockUser
+		if _, err := service.UpdateUser(ctx, &user, "Jane", "Smith", "iampassword", ""); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if authService.user.FirstName != "Jane" || authService.user.PasswordHash != mockUser.PasswordHash {
+			t.Errorf("Only the names should be updated, got %v", authService.user)
+		}
+	})
+}
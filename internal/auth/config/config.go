@@ -2,7 +2,45 @@ package config
 
 type AuthConfig struct {
 	Auth struct {
-		VerifyEmailExpirationTime int    `mapstructure:"verify_email_expiration_time"`
-		VerificationEmailTopicArn string `mapstructure:"verification_email_topic_arn"`
+		VerifyEmailExpirationTime  int    `mapstructure:"verify_email_expiration_time"`
+		VerificationEmailTopicArn  string `mapstructure:"verification_email_topic_arn"`
+		AccessTokenExpirationTime  int    `mapstructure:"access_token_expiration_time"`
+		RefreshTokenExpirationTime int    `mapstructure:"refresh_token_expiration_time"`
+
+		PasswordResetExpirationTime int    `mapstructure:"password_reset_expiration_time"`
+		PasswordResetTopicArn       string `mapstructure:"password_reset_topic_arn"`
+
+		// TOTP second factor
+		TOTPIssuer        string `mapstructure:"totp_issuer"`
+		TOTPEncryptionKey string `mapstructure:"totp_encryption_key"`
+		// SecondFactorRoutes lists the routes that need a session with a completed second factor, e.g. "PUT /v1/user/self"
+		SecondFactorRoutes []string `mapstructure:"second_factor_routes"`
+
+		// brute-force protection
+		ThrottleStore              string `mapstructure:"throttle_store"`
+		ThrottleMaxAccountFailures int    `mapstructure:"throttle_max_account_failures"`
+		ThrottleMaxIPFailures      int    `mapstructure:"throttle_max_ip_failures"`
+		ThrottleLockoutDuration    int    `mapstructure:"throttle_lockout_duration"`
+
+		// password policy
+		PasswordMinLength        int  `mapstructure:"password_min_length"`
+		PasswordMaxLength        int  `mapstructure:"password_max_length"`
+		PasswordRequireUppercase bool `mapstructure:"password_require_uppercase"`
+		PasswordRequireLowercase bool `mapstructure:"password_require_lowercase"`
+		PasswordRequireDigit     bool `mapstructure:"password_require_digit"`
+		PasswordRequireSymbol    bool `mapstructure:"password_require_symbol"`
+		PasswordHistorySize      int  `mapstructure:"password_history_size"`
+		// BreachedPasswordsFile is a list of breached SHA-1 hashes, the check is disabled when empty
+		BreachedPasswordsFile string `mapstructure:"breached_passwords_file"`
+
+		// password hashing, hashes with other parameters are upgraded on the next login
+		PasswordHashAlgorithm     string `mapstructure:"password_hash_algorithm"`
+		PasswordBcryptCost        int    `mapstructure:"password_bcrypt_cost"`
+		PasswordArgon2Memory      int    `mapstructure:"password_argon2_memory"`
+		PasswordArgon2Iterations  int    `mapstructure:"password_argon2_iterations"`
+		PasswordArgon2Parallelism int    `mapstructure:"password_argon2_parallelism"`
+
+		// AccountDeletionGracePeriod is how long a deleted account is kept before it is purged (in seconds)
+		AccountDeletionGracePeriod int `mapstructure:"account_deletion_grace_period"`
 	} `mapstructure:"auth"`
 }
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type AccountStatus string
+
+/*
+The account status lifecycle
+- pending: registered, the email is not verified yet, the user can only manage the account
+- active: verified, the user has full access
+- suspended: an admin keeps the user from authenticating, e.g. during an investigation
+- deactivated: the account is closed, the user cannot authenticate until an admin activates it again
+*/
+const (
+	AccountStatusPending     AccountStatus = "pending"
+	AccountStatusActive      AccountStatus = "active"
+	AccountStatusSuspended   AccountStatus = "suspended"
+	AccountStatusDeactivated AccountStatus = "deactivated"
+)
+
+var (
+	ErrAccountPending          = errors.New("account pending verification")
+	ErrAccountSuspended        = errors.New("account suspended")
+	ErrAccountDeactivated      = errors.New("account deactivated")
+	ErrInvalidStatusTransition = errors.New("invalid account status transition")
+	ErrStatusReasonRequired    = errors.New("a reason is required to suspend or deactivate an account")
+)
+
+// accountStatusTransitions lists the statuses each status can move to
+var accountStatusTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusPending:     {AccountStatusActive, AccountStatusSuspended, AccountStatusDeactivated},
+	AccountStatusActive:      {AccountStatusSuspended, AccountStatusDeactivated},
+	AccountStatusSuspended:   {AccountStatusActive, AccountStatusDeactivated},
+	AccountStatusDeactivated: {AccountStatusActive},
+}
+
+// CheckAuthentication returns the error of the status when it keeps the user from authenticating,
+// a pending user can authenticate to manage the account, the verification middleware gates the rest
+func (u *AuthUser) CheckAuthentication() error {
+	switch u.Status {
+	case AccountStatusSuspended:
+		return ErrAccountSuspended
+	case AccountStatusDeactivated:
+		return ErrAccountDeactivated
+	}
+
+	return nil
+}
+
+// IsAuthenticationError reports whether the error comes from CheckAuthentication
+func IsAuthenticationError(err error) bool {
+	return errors.Is(err, ErrAccountSuspended) || errors.Is(err, ErrAccountDeactivated)
+}
+
+/*
+ChangeStatus moves the account to the status if the lifecycle allows it
+- Suspending and deactivating need a reason, it is cleared when the account is activated
+- The first activation is kept in ActivatedAt
+*/
+func (u *AuthUser) ChangeStatus(status AccountStatus, reason string) error {
+	if !u.canChangeStatus(status) {
+		return fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, u.Status, status)
+	}
+
+	if (status == AccountStatusSuspended || status == AccountStatusDeactivated) && reason == "" {
+		return ErrStatusReasonRequired
+	}
+
+	now := time.Now()
+	if status == AccountStatusActive {
+		reason = ""
+		if u.ActivatedAt == nil {
+			u.ActivatedAt = &now
+		}
+	}
+
+	u.Status = status
+	u.StatusReason = reason
+	u.StatusChangedAt = &now
+
+	return nil
+}
+
+// MarkVerified records the verified email, a pending account becomes active
+func (u *AuthUser) MarkVerified() {
+	u.Verify = true
+	if u.Status == AccountStatusPending {
+		_ = u.ChangeStatus(AccountStatusActive, "")
+	}
+}
+
+func (u *AuthUser) canChangeStatus(status AccountStatus) bool {
+	for _, allowed := range accountStatusTransitions[u.Status] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccountStatus(t *testing.T) {
+	user, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+
+	t.Run("Test verification activates", func(t *testing.T) {
+		if user.Status != AccountStatusPending || user.CheckAuthentication() != nil {
+			t.Fatalf("New user should be pending and able to authenticate, got %s", user.Status)
+		}
+
+		user.MarkVerified()
+		if user.Status != AccountStatusActive || user.ActivatedAt == nil {
+			t.Errorf("Verified user should be active, got %s", user.Status)
+		}
+	})
+
+	t.Run("Test lifecycle", func(t *testing.T) {
+		if err := user.ChangeStatus(AccountStatusSuspended, ""); err != ErrStatusReasonRequired {
+			t.Errorf("Error should be reason required, got %v", err)
+		}
+
+		if err := user.ChangeStatus(AccountStatusSuspended, "chargeback"); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if err := user.CheckAuthentication(); err != ErrAccountSuspended {
+			t.Errorf("Error should be account suspended, got %v", err)
+		}
+
+		if err := user.ChangeStatus(AccountStatusDeactivated, "closed"); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if err := user.CheckAuthentication(); err != ErrAccountDeactivated {
+			t.Errorf("Error should be account deactivated, got %v", err)
+		}
+
+		// a deactivated account cannot be suspended, only activated again
+		if err := user.ChangeStatus(AccountStatusSuspended, "fraud"); !errors.Is(err, ErrInvalidStatusTransition) {
+			t.Errorf("Error should be invalid transition, got %v", err)
+		}
+
+		if err := user.ChangeStatus(AccountStatusActive, ""); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if user.StatusReason != "" || user.CheckAuthentication() != nil {
+			t.Errorf("Active user should authenticate without a reason, got %q", user.StatusReason)
+		}
+
+		if err := user.ChangeStatus(AccountStatusPending, ""); !errors.Is(err, ErrInvalidStatusTransition) {
+			t.Errorf("Error should be invalid transition, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"go-template/internal/auth/domain"
+	"time"
+)
+
+// ErrSelfAction keeps an admin from locking themselves out
+var ErrSelfAction = errors.New("cannot change the status of or delete your own account")
+
+// AdminService manages the accounts of other users, the permissions are checked by the routes
+type AdminService struct {
+	authRepository domain.AuthRepository
+	authService    domain.AuthService
+}
+
+func NewAdminService(authRepository domain.AuthRepository, authService domain.AuthService) *AdminService {
+	return &AdminService{
+		authRepository: authRepository,
+		authService:    authService,
+	}
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, query domain.UserQuery) ([]*domain.AuthUser, int, error) {
+	return s.authRepository.ListUsers(ctx, query)
+}
+
+func (s *AdminService) GetUser(ctx context.Context, id string) (*domain.AuthUser, error) {
+	return s.authRepository.FindUserByID(ctx, id)
+}
+
+// Suspend keeps the user from authenticating until it is activated again, its sessions are revoked
+func (s *AdminService) Suspend(ctx context.Context, actor *domain.AuthUser, id, reason string) (*domain.AuthUser, error) {
+	return s.changeStatus(ctx, actor, id, domain.AccountStatusSuspended, reason)
+}
+
+// Deactivate closes the account of the user, its sessions are revoked
+func (s *AdminService) Deactivate(ctx context.Context, actor *domain.AuthUser, id, reason string) (*domain.AuthUser, error) {
+	return s.changeStatus(ctx, actor, id, domain.AccountStatusDeactivated, reason)
+}
+
+// Activate lets a suspended or deactivated user authenticate again, a pending user skips the verification
+func (s *AdminService) Activate(ctx context.Context, actor *domain.AuthUser, id string) (*domain.AuthUser, error) {
+	return s.changeStatus(ctx, actor, id, domain.AccountStatusActive, "")
+}
+
+func (s *AdminService) changeStatus(ctx context.Context, actor *domain.AuthUser, id string, status domain.AccountStatus, reason string) (*domain.AuthUser, error) {
+	if actor.ID == id {
+		return nil, ErrSelfAction
+	}
+
+	user, err := s.authRepository.FindUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authService.ChangeAccountStatus(ctx, user, status, reason); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Verify marks the email of the user as verified without the verification link, a pending account becomes active
+func (s *AdminService) Verify(ctx context.Context, id string) (*domain.AuthUser, error) {
+	user, err := s.authRepository.FindUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Verify {
+		return user, nil
+	}
+
+	user.MarkVerified()
+	if err := s.authRepository.VerifyAccount(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Delete soft deletes the user like a self-service deletion, it is purged after the grace period
+func (s *AdminService) Delete(ctx context.Context, actor *domain.AuthUser, id string) (time.Time, error) {
+	if actor.ID == id {
+		return time.Time{}, ErrSelfAction
+	}
+
+	user, err := s.authRepository.FindUserByID(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.authService.DeleteUser(ctx, user)
+}
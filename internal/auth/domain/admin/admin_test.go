@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"context"
+	"go-template/internal/auth/domain"
+	"testing"
+	"time"
+)
+
+// fakeAuthRepository keeps the users in memory by id
+type fakeAuthRepository struct {
+	domain.AuthRepository
+	users map[string]*domain.AuthUser
+}
+
+func (r *fakeAuthRepository) FindUserByID(ctx context.Context, id string) (*domain.AuthUser, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeAuthRepository) VerifyAccount(ctx context.Context, user *domain.AuthUser) error {
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+// fakeAuthService records the deleted users and stores the status changes in the repository
+type fakeAuthService struct {
+	domain.AuthService
+	repository *fakeAuthRepository
+	deleted    []string
+}
+
+func (s *fakeAuthService) ChangeAccountStatus(ctx context.Context, user *domain.AuthUser, status domain.AccountStatus, reason string) error {
+	if err := user.ChangeStatus(status, reason); err != nil {
+		return err
+	}
+	copied := *user
+	s.repository.users[user.ID] = &copied
+	return nil
+}
+
+func (s *fakeAuthService) DeleteUser(ctx context.Context, user *domain.AuthUser) (time.Time, error) {
+	s.deleted = append(s.deleted, user.ID)
+	return time.Now(), nil
+}
+
+func TestAdminService(t *testing.T) {
+	ctx := context.Background()
+
+	actor := &domain.AuthUser{ID: "admin", Roles: []string{domain.RoleAdmin}}
+	repository := &fakeAuthRepository{users: map[string]*domain.AuthUser{
+		"admin":   actor,
+		"user":    {ID: "user", Verify: true, Status: domain.AccountStatusActive},
+		"pending": {ID: "pending", Status: domain.AccountStatusPending},
+	}}
+	authService := &fakeAuthService{repository: repository}
+	service := NewAdminService(repository, authService)
+
+	t.Run("Test status", func(t *testing.T) {
+		user, err := service.Suspend(ctx, actor, "user", "fraud")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if user.Status != domain.AccountStatusSuspended || user.StatusReason != "fraud" {
+			t.Errorf("User should be suspended for fraud, got %s %s", user.Status, user.StatusReason)
+		}
+
+		if _, err := service.Activate(ctx, actor, "user"); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		if _, err := service.Deactivate(ctx, actor, "unknown", "closed"); err != domain.ErrUserNotFound {
+			t.Errorf("Error should be user not found, got %v", err)
+		}
+	})
+
+	t.Run("Test Verify", func(t *testing.T) {
+		user, err := service.Verify(ctx, "pending")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		stored := repository.users["pending"]
+		if !user.Verify || !stored.Verify || stored.Status != domain.AccountStatusActive {
+			t.Errorf("User should be verified and active, got %v %s", stored.Verify, stored.Status)
+		}
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		if _, err := service.Delete(ctx, actor, "user"); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if len(authService.deleted) != 1 || authService.deleted[0] != "user" {
+			t.Errorf("User should be deleted, got %v", authService.deleted)
+		}
+
+		if _, err := service.Delete(ctx, actor, "unknown"); err != domain.ErrUserNotFound {
+			t.Errorf("Error should be user not found, got %v", err)
+		}
+	})
+
+	t.Run("Test self action", func(t *testing.T) {
+		if _, err := service.Suspend(ctx, actor, actor.ID, "mistake"); err != ErrSelfAction {
+			t.Errorf("Error should be self action, got %v", err)
+		}
+
+		if _, err := service.Delete(ctx, actor, actor.ID); err != ErrSelfAction {
+			t.Errorf("Error should be self action, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+var (
+	ErrApiKeyNotFound = errors.New("api key not found")
+	ErrInvalidScope   = errors.New("invalid scope")
+)
+
+const apiKeyTokenPrefix = "wak"
+
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeProfileWrite = "profile:write"
+	ScopePicRead      = "pic:read"
+	ScopePicWrite     = "pic:write"
+)
+
+var AllScopes = []string{ScopeProfileRead, ScopeProfileWrite, ScopePicRead, ScopePicWrite}
+
+// ApiKey is a personal, long-lived credential for service-to-service access.
+// Only the SHA-256 hash of the key is stored, the plain key is handed to the user once.
+type ApiKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+}
+
+// NewApiKey creates an api key and returns it together with the plain key "wak_<prefix>_<secret>"
+func NewApiKey(userID, name string, scopes []string, expiresAt *time.Time) (*ApiKey, string, error) {
+	if name == "" {
+		return nil, "", errors.New("name cannot be empty")
+	}
+
+	if err := ValidateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := fmt.Sprintf("%s_%s_%s", apiKeyTokenPrefix, prefix, secret)
+
+	return &ApiKey{
+		ID:        uuidv7.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   HashApiKey(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}, key, nil
+}
+
+// ParseApiKeyPrefix extracts the lookup prefix from a plain api key
+func ParseApiKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTokenPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+func HashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	for _, scope := range scopes {
+		if !contains(AllScopes, scope) {
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	return nil
+}
+
+func (k *ApiKey) Update(name string, scopes []string) error {
+	if name != "" {
+		k.Name = name
+	}
+
+	if scopes != nil {
+		if err := ValidateScopes(scopes); err != nil {
+			return err
+		}
+		k.Scopes = scopes
+	}
+
+	return nil
+}
+
+func (k *ApiKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+func (k *ApiKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+func randomHex(length int) (string, error) {
+	randomBytes := make([]byte, length)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
+
+func contains(slice []string, item string) bool {
+	for _, a := range slice {
+		if a == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"go-template/internal/auth/domain"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+const Scheme = "ApiKey"
+
+type ApiKeyService struct {
+	authRepository   domain.AuthRepository
+	apiKeyRepository domain.ApiKeyRepository
+}
+
+func NewApiKeyService(authRepository domain.AuthRepository, apiKeyRepository domain.ApiKeyRepository) *ApiKeyService {
+	return &ApiKeyService{
+		authRepository:   authRepository,
+		apiKeyRepository: apiKeyRepository,
+	}
+}
+
+func (s *ApiKeyService) Scheme() string {
+	return Scheme
+}
+
+// Authenticate looks the key up by its prefix and compares the hash in constant time
+func (s *ApiKeyService) Authenticate(token string) (*domain.AuthUser, error) {
+	prefix, ok := domain.ParseApiKeyPrefix(token)
+	if !ok {
+		return &domain.AuthUser{}, ErrInvalidToken
+	}
+
+	ctx := context.Background()
+
+	apiKey, err := s.apiKeyRepository.FindByPrefix(ctx, prefix)
+	if err != nil {
+		if err == domain.ErrApiKeyNotFound {
+			return &domain.AuthUser{}, ErrInvalidToken
+		}
+		return &domain.AuthUser{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(domain.HashApiKey(token))) != 1 {
+		return &domain.AuthUser{}, ErrInvalidToken
+	}
+
+	if apiKey.IsRevoked() {
+		return &domain.AuthUser{}, ErrInvalidToken
+	}
+
+	if apiKey.IsExpired() {
+		return &domain.AuthUser{}, ErrTokenExpired
+	}
+
+	user, err := s.authRepository.FindUserByID(ctx, apiKey.UserID)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return &domain.AuthUser{}, ErrInvalidToken
+		}
+		return &domain.AuthUser{}, err
+	}
+
+	if err := user.CheckAuthentication(); err != nil {
+		return &domain.AuthUser{}, err
+	}
+
+	if err := s.apiKeyRepository.TouchLastUsed(ctx, apiKey); err != nil {
+		return &domain.AuthUser{}, err
+	}
+
+	user.Credential = &domain.Credential{
+		Type:   domain.CredentialApiKey,
+		ID:     apiKey.ID,
+		Scopes: apiKey.Scopes,
+	}
+
+	return user, nil
+}
+
+// Create issues a new api key for the user, the plain key is only returned here
+func (s *ApiKeyService) Create(ctx context.Context, user *domain.AuthUser, name string, scopes []string, expiresAt *time.Time) (*domain.ApiKey, string, error) {
+	apiKey, key, err := domain.NewApiKey(user.ID, name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.apiKeyRepository.Create(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+func (s *ApiKeyService) List(ctx context.Context, user *domain.AuthUser) ([]*domain.ApiKey, error) {
+	return s.apiKeyRepository.ListByUser(ctx, user.ID)
+}
+
+func (s *ApiKeyService) Update(ctx context.Context, user *domain.AuthUser, id, name string, scopes []string) (*domain.ApiKey, error) {
+	apiKey, err := s.apiKeyRepository.FindByID(ctx, user.ID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := apiKey.Update(name, scopes); err != nil {
+		return nil, err
+	}
+
+	if err := s.apiKeyRepository.Update(ctx, apiKey); err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (s *ApiKeyService) Revoke(ctx context.Context, user *domain.AuthUser, id string) error {
+	apiKey, err := s.apiKeyRepository.FindByID(ctx, user.ID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.apiKeyRepository.Revoke(ctx, apiKey)
+}
@@ -0,0 +1,115 @@
+package apikey
+
+import (
+	"context"
+	"go-template/internal/auth/domain"
+	"testing"
+	"time"
+)
+
+// fakeAuthRepository only implements the lookups used by the api key service
+type fakeAuthRepository struct {
+	domain.AuthRepository
+	user *domain.AuthUser
+}
+
+func (r *fakeAuthRepository) FindUserByID(ctx context.Context, id string) (*domain.AuthUser, error) {
+	if r.user.ID != id {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *r.user
+	return &copied, nil
+}
+
+type fakeApiKeyRepository struct {
+	domain.ApiKeyRepository
+	apiKeys map[string]*domain.ApiKey
+}
+
+func (r *fakeApiKeyRepository) Create(ctx context.Context, apiKey *domain.ApiKey) error {
+	r.apiKeys[apiKey.Prefix] = apiKey
+	return nil
+}
+
+func (r *fakeApiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*domain.ApiKey, error) {
+	apiKey, ok := r.apiKeys[prefix]
+	if !ok {
+		return nil, domain.ErrApiKeyNotFound
+	}
+	return apiKey, nil
+}
+
+func (r *fakeApiKeyRepository) TouchLastUsed(ctx context.Context, apiKey *domain.ApiKey) error {
+	now := time.Now()
+	apiKey.LastUsedAt = &now
+	return nil
+}
+
+func TestApiKeyService(t *testing.T) {
+	mockUser, _ := domain.NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	apiKeyRepository := &fakeApiKeyRepository{apiKeys: make(map[string]*domain.ApiKey)}
+	apiKeyService := NewApiKeyService(&fakeAuthRepository{user: mockUser}, apiKeyRepository)
+
+	ctx := context.Background()
+
+	t.Run("Test Authenticate", func(t *testing.T) {
+		apiKey, key, err := apiKeyService.Create(ctx, mockUser, "ci", []string{domain.ScopeProfileRead}, nil)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		user, err := apiKeyService.Authenticate(key)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if user.Credential.Type != domain.CredentialApiKey || user.Credential.ID != apiKey.ID {
+			t.Errorf("Credential should be the api key, got %+v", user.Credential)
+		}
+
+		if !user.HasScope(domain.ScopeProfileRead) || user.HasScope(domain.ScopeProfileWrite) {
+			t.Errorf("Scopes should be limited to %s, got %v", domain.ScopeProfileRead, user.Credential.Scopes)
+		}
+
+		if apiKey.LastUsedAt == nil {
+			t.Errorf("Last used time should be recorded")
+		}
+
+		// test wrong secret with a valid prefix
+		prefix, _ := domain.ParseApiKeyPrefix(key)
+		if _, err := apiKeyService.Authenticate("wak_" + prefix + "_wrongsecret"); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+
+		// test malformed key
+		if _, err := apiKeyService.Authenticate("not-an-api-key"); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+
+		// test revoked key
+		now := time.Now()
+		apiKey.RevokedAt = &now
+		if _, err := apiKeyService.Authenticate(key); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test expired key", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+		_, key, _ := apiKeyService.Create(ctx, mockUser, "expired", []string{domain.ScopePicRead}, &expiresAt)
+
+		if _, err := apiKeyService.Authenticate(key); err != ErrTokenExpired {
+			t.Errorf("Error should be token expired, got %v", err)
+		}
+	})
+
+	t.Run("Test invalid scopes", func(t *testing.T) {
+		if _, _, err := apiKeyService.Create(ctx, mockUser, "admin", []string{"admin:all"}, nil); err == nil {
+			t.Errorf("Error should not be nil")
+		}
+
+		if _, _, err := apiKeyService.Create(ctx, mockUser, "empty", []string{}, nil); err == nil {
+			t.Errorf("Error should not be nil")
+		}
+	})
+}
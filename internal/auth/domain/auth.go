@@ -2,27 +2,39 @@ package domain
 
 import (
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// passwordHasher hashes all passwords of the process, it is replaced once at startup by SetPasswordHasher
+var passwordHasher = DefaultPasswordHasher()
 
-	return string(hashedPassword), err
+func SetPasswordHasher(hasher *PasswordHasher) {
+	passwordHasher = hasher
+}
+
+func HashPassword(password string) (string, error) {
+	return passwordHasher.Hash(password)
 }
 
+// VerifyPassword accepts hashes of every supported algorithm, not only the configured one
 func VerifyPassword(user *AuthUser, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-	return err == nil
+	return passwordMatches(user.PasswordHash, password)
+}
+
+// PasswordNeedsRehash reports a hash created with an older algorithm or weaker parameters
+func PasswordNeedsRehash(user *AuthUser) bool {
+	return passwordHasher.NeedsRehash(user.PasswordHash)
+}
+
+func passwordMatches(passwordHash, password string) bool {
+	return passwordHasher.Verify(passwordHash, password)
 }
 
 func UpdatePassword(user *AuthUser, newPassword string) error {
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	passwordHash, err := HashPassword(newPassword)
 	if err != nil {
 		return err
 	}
-	user.PasswordHash = string(passwordHash)
+	user.PasswordHash = passwordHash
 	user.UpdatedAt = time.Now()
 	return nil
 }
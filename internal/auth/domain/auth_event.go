@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// AuthEventType is the security relevant action recorded in the audit trail
+type AuthEventType string
+
+const (
+	AuthEventRegistered         AuthEventType = "registered"
+	AuthEventLoginSucceeded     AuthEventType = "login_succeeded"
+	AuthEventLoginFailed        AuthEventType = "login_failed"
+	AuthEventEmailVerified      AuthEventType = "email_verified"
+	AuthEventPasswordChanged    AuthEventType = "password_changed"
+	AuthEventVerificationResent AuthEventType = "verification_resent"
+)
+
+// maxUserAgentLength is the size of the user_agent column, longer user agents are truncated (in characters)
+const maxUserAgentLength = 512
+
+// AuthEvent is an entry of the append-only audit trail of an account
+type AuthEvent struct {
+	ID string
+	// UserID is empty when the email does not belong to an account
+	UserID string
+	Email  string
+	Type   AuthEventType
+	// Detail qualifies the event, e.g. the reason of a failed login
+	Detail string
+
+	// the request that caused the event
+	IP        string
+	UserAgent string
+	RequestID string
+
+	CreatedAt time.Time
+}
+
+func NewAuthEvent(eventType AuthEventType, userID, email, detail string) *AuthEvent {
+	return &AuthEvent{
+		ID:        uuidv7.New().String(),
+		UserID:    userID,
+		Email:     email,
+		Type:      eventType,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}
+}
+
+// truncate cuts s to n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
@@ -14,9 +14,25 @@ type AuthUser struct {
 	LastName     string
 	PasswordHash string
 	Verify       bool
+	// PendingEmail is the requested new address until its verification token is confirmed
+	PendingEmail *string
 
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// Status is the step of the account lifecycle, the reason explains a suspension or a deactivation
+	Status          AccountStatus
+	StatusReason    string
+	StatusChangedAt *time.Time
+	ActivatedAt     *time.Time
+
+	// Roles and the Permissions they grant are loaded with the user
+	Roles       []string
+	Permissions []string
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	LastLoginAt *time.Time
+
+	// Credential is set by the authentication strategy that authenticated the request
+	Credential *Credential
 }
 
 var (
@@ -53,24 +69,54 @@ func NewAuthUser(
 		LastName:     lastName,
 		PasswordHash: string(hashedPassword),
 		Verify:       false,
+		Status:       AccountStatusPending,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}, nil
 }
 
+// HasScope reports whether the credential of the request allows the scope
+func (u *AuthUser) HasScope(scope string) bool {
+	if u.Credential == nil || u.Credential.Scopes == nil {
+		return true
+	}
+
+	return contains(u.Credential.Scopes, scope)
+}
+
+// HasPermission reports whether one of the roles of the user grants the permission
+func (u *AuthUser) HasPermission(permission string) bool {
+	return contains(u.Permissions, permission)
+}
+
 func (u *AuthUser) UpdateLastLogin() {
+	now := time.Now()
+	u.LastLoginAt = &now
+}
+
+// Update replaces the names, a new password is set with UpdatePassword
+func (u *AuthUser) Update(firstName, lastName string) {
+	u.FirstName = firstName
+	u.LastName = lastName
 	u.UpdatedAt = time.Now()
 }
 
-func (u *AuthUser) Update(firstName, lastName, password string) error {
-	hashedPassword, err := HashPassword(password)
-	if err != nil {
-		return err
+// Patch replaces the names that are set, the password is changed through the AuthService
+func (u *AuthUser) Patch(firstName, lastName *string) error {
+	if (firstName != nil && *firstName == "") || (lastName != nil && *lastName == "") {
+		return errors.New("first name and last name cannot be empty")
 	}
 
-	u.FirstName = firstName
-	u.LastName = lastName
-	u.PasswordHash = hashedPassword
+	if firstName == nil && lastName == nil {
+		return nil
+	}
+
+	if firstName != nil {
+		u.FirstName = *firstName
+	}
+	if lastName != nil {
+		u.LastName = *lastName
+	}
 	u.UpdatedAt = time.Now()
 
 	return nil
@@ -8,18 +8,35 @@ import (
 	"strings"
 )
 
-var ErrInvalidToken = errors.New("invalid token")
+var (
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrSecondFactorRequired = errors.New("second factor required, sign in for a bearer token")
+)
+
+const Scheme = "Basic"
+
+// SecondFactorChecker reports whether the user has a second factor, the password alone does not authenticate such a user
+type SecondFactorChecker interface {
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+}
 
 type BasicService struct {
 	authRepository domain.AuthRepository
+	secondFactors  SecondFactorChecker
 }
 
-func NewBasicService(authRepository domain.AuthRepository) *BasicService {
+// NewBasicService creates the basic service, without second factor checker no user has a second factor
+func NewBasicService(authRepository domain.AuthRepository, secondFactors SecondFactorChecker) *BasicService {
 	return &BasicService{
 		authRepository: authRepository,
+		secondFactors:  secondFactors,
 	}
 }
 
+func (bs *BasicService) Scheme() string {
+	return Scheme
+}
+
 func (bs *BasicService) Authenticate(token string) (*domain.AuthUser, error) {
 	// 0. decode token from base64 format
 	decodedByte64Token, err := base64.StdEncoding.DecodeString(token)
@@ -35,6 +52,42 @@ func (bs *BasicService) Authenticate(token string) (*domain.AuthUser, error) {
 	}
 
 	// 2. check if username and password are valid
+	user, err := bs.AuthenticateCredentials(email, password)
+	if err != nil {
+		return user, err
+	}
+
+	// 3. a user with a second factor has to complete it at login, the Basic scheme cannot carry it
+	if bs.secondFactors != nil {
+		enabled, err := bs.secondFactors.IsEnabled(context.Background(), user.ID)
+		if err != nil {
+			return &domain.AuthUser{}, err
+		}
+		if enabled {
+			return &domain.AuthUser{}, ErrSecondFactorRequired
+		}
+	}
+
+	return user, nil
+}
+
+// Account returns the email the credentials claim, so failed guesses can be counted per account
+func (bs *BasicService) Account(token string) string {
+	decodedByte64Token, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return ""
+	}
+
+	email, _, err := bs.splitToken(string(decodedByte64Token))
+	if err != nil {
+		return ""
+	}
+
+	return email
+}
+
+// AuthenticateCredentials checks the email and password pair against the stored user
+func (bs *BasicService) AuthenticateCredentials(email, password string) (*domain.AuthUser, error) {
 	user, err := bs.authRepository.FindUserByEmail(context.Background(), email)
 	if err != nil {
 		return &domain.AuthUser{}, err
@@ -44,9 +97,40 @@ func (bs *BasicService) Authenticate(token string) (*domain.AuthUser, error) {
 		return &domain.AuthUser{}, ErrInvalidToken
 	}
 
+	// only the owner of the password learns the status of the account
+	if err := user.CheckAuthentication(); err != nil {
+		return &domain.AuthUser{}, err
+	}
+
+	bs.upgradePasswordHash(user, password)
+
+	user.Credential = &domain.Credential{Type: domain.CredentialPassword}
+
 	return user, nil
 }
 
+/*
+upgradePasswordHash re-hashes a password stored with an older algorithm or weaker parameters
+- The plain password is only known right after a successful check
+- A failure does not fail the login, the old hash still verifies and the upgrade is tried again next time
+*/
+func (bs *BasicService) upgradePasswordHash(user *domain.AuthUser, password string) {
+	if !domain.PasswordNeedsRehash(user) {
+		return
+	}
+
+	passwordHash, err := domain.HashPassword(password)
+	if err != nil {
+		return
+	}
+
+	previousHash := user.PasswordHash
+	user.PasswordHash = passwordHash
+	if err := bs.authRepository.Update(context.Background(), user); err != nil {
+		user.PasswordHash = previousHash
+	}
+}
+
 func (bs *BasicService) splitToken(token string) (email, password string, err error) {
 	slices := strings.Split(token, ":")
 	if len(slices) != 2 {
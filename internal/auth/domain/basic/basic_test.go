@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"go-template/internal/auth/domain"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type MockAuthRepository struct {
@@ -31,12 +33,72 @@ func (m *MockAuthRepository) Update(ctx context.Context, user *domain.AuthUser)
 func (m *MockAuthRepository) VerifyAccount(ctx context.Context, user *domain.AuthUser) error {
 	return nil
 }
+func (m *MockAuthRepository) SetPendingEmail(ctx context.Context, user *domain.AuthUser, email string) error {
+	return nil
+}
+func (m *MockAuthRepository) ConfirmPendingEmail(ctx context.Context, user *domain.AuthUser, email string) error {
+	return nil
+}
+func (m *MockAuthRepository) SoftDelete(ctx context.Context, user *domain.AuthUser, deletedAt, purgeAfter time.Time) error {
+	return nil
+}
+func (m *MockAuthRepository) UpdateLastLogin(ctx context.Context, user *domain.AuthUser) error {
+	return nil
+}
+func (m *MockAuthRepository) ListUsers(ctx context.Context, query domain.UserQuery) ([]*domain.AuthUser, int, error) {
+	return nil, 0, nil
+}
+func (m *MockAuthRepository) UpdateStatus(ctx context.Context, user *domain.AuthUser) error {
+	return nil
+}
+func (m *MockAuthRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	return nil
+}
+func (m *MockAuthRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	return nil, nil
+}
+func (m *MockAuthRepository) MarkRefreshTokenRotated(ctx context.Context, token *domain.RefreshToken) error {
+	return nil
+}
+func (m *MockAuthRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+func (m *MockAuthRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	return nil
+}
+func (m *MockAuthRepository) IsRefreshTokenFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	return true, nil
+}
+func (m *MockAuthRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+func (m *MockAuthRepository) AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error {
+	return nil
+}
+func (m *MockAuthRepository) ListPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error) {
+	return nil, nil
+}
+func (m *MockAuthRepository) AppendAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	return nil
+}
+func (m *MockAuthRepository) ListAuthEvents(ctx context.Context, userID string, limit, offset int) ([]*domain.AuthEvent, error) {
+	return nil, nil
+}
+
+// fakeSecondFactorChecker reports a second factor for the listed users
+type fakeSecondFactorChecker struct {
+	enabled map[string]bool
+}
+
+func (c *fakeSecondFactorChecker) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	return c.enabled[userID], nil
+}
 
 func TestBasicService(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockAuthRepository := new(MockAuthRepository)
-	baseService := NewBasicService(mockAuthRepository)
+	baseService := NewBasicService(mockAuthRepository, nil)
 	t.Run("Test splitToken", func(t *testing.T) {
 		token := "username:password"
 		email, password, err := baseService.splitToken(token)
@@ -95,4 +157,85 @@ func TestBasicService(t *testing.T) {
 			t.Errorf("Error should be invalid credentials, got %v", err)
 		}
 	})
+	t.Run("Test account status", func(t *testing.T) {
+		mockUser, _ := domain.NewAuthUser("suspended@example.com", "First", "Last", "iampassword")
+
+		mockAuthRepository := new(MockAuthRepository)
+		mockAuthRepository.On("FindUserByEmail", mock.Anything, mock.Anything).Return(mockUser, nil)
+		baseService := NewBasicService(mockAuthRepository, nil)
+
+		// a pending user can still manage the account
+		if _, err := baseService.AuthenticateCredentials(mockUser.Email, "iampassword"); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		for status, expected := range map[domain.AccountStatus]error{
+			domain.AccountStatusSuspended:   domain.ErrAccountSuspended,
+			domain.AccountStatusDeactivated: domain.ErrAccountDeactivated,
+		} {
+			mockUser.Status = status
+			if _, err := baseService.AuthenticateCredentials(mockUser.Email, "iampassword"); err != expected {
+				t.Errorf("Error should be %v, got %v", expected, err)
+			}
+		}
+
+		// a wrong password does not reveal the status
+		if _, err := baseService.AuthenticateCredentials(mockUser.Email, "invalidpassword"); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid credentials, got %v", err)
+		}
+	})
+
+	t.Run("Test password hash upgrade", func(t *testing.T) {
+		mockUser, _ := domain.NewAuthUser("legacy@example.com", "First", "Last", "iampassword")
+		legacyHash := mockUser.PasswordHash
+
+		mockAuthRepository := new(MockAuthRepository)
+		mockAuthRepository.On("FindUserByEmail", mock.Anything, mock.Anything).Return(mockUser, nil)
+		baseService := NewBasicService(mockAuthRepository, nil)
+
+		hasher, _ := domain.NewPasswordHasher(domain.PasswordAlgorithmArgon2id, bcrypt.DefaultCost, domain.Argon2Params{
+			Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32,
+		})
+		domain.SetPasswordHasher(hasher)
+		defer domain.SetPasswordHasher(domain.DefaultPasswordHasher())
+
+		// the bcrypt hash still verifies and is replaced
+		user, err := baseService.AuthenticateCredentials(mockUser.Email, "iampassword")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if user.PasswordHash == legacyHash || domain.PasswordNeedsRehash(user) {
+			t.Errorf("Password hash should be upgraded, got %s", user.PasswordHash)
+		}
+
+		if _, err := baseService.AuthenticateCredentials(mockUser.Email, "iampassword"); err != nil {
+			t.Errorf("Error should be nil with the upgraded hash, got %v", err)
+		}
+	})
+
+	t.Run("Test second factor", func(t *testing.T) {
+		mockUser, _ := domain.NewAuthUser("mfa@example.com", "First", "Last", "iampassword")
+
+		mockAuthRepository := new(MockAuthRepository)
+		mockAuthRepository.On("FindUserByEmail", mock.Anything, mock.Anything).Return(mockUser, nil)
+		secondFactors := &fakeSecondFactorChecker{enabled: map[string]bool{}}
+		baseService := NewBasicService(mockAuthRepository, secondFactors)
+
+		base64Token := base64.StdEncoding.EncodeToString([]byte(mockUser.Email + ":iampassword"))
+		if _, err := baseService.Authenticate(base64Token); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		// the password alone does not authenticate a user with a second factor
+		secondFactors.enabled[mockUser.ID] = true
+		if _, err := baseService.Authenticate(base64Token); err != ErrSecondFactorRequired {
+			t.Errorf("Error should be %v, got %v", ErrSecondFactorRequired, err)
+		}
+
+		// the login still checks the password before asking for the second factor
+		if _, err := baseService.AuthenticateCredentials(mockUser.Email, "iampassword"); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+	})
 }
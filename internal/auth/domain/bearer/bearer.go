@@ -0,0 +1,271 @@
+package bearer
+
+import (
+	"context"
+	"errors"
+	"go-template/internal/auth/config"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/token"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+var (
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenExpired        = errors.New("token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+)
+
+const Scheme = "Bearer"
+
+const (
+	// defaultAccessTokenExpirationTime is used when auth.access_token_expiration_time is not configured (in seconds)
+	defaultAccessTokenExpirationTime = 15 * 60
+
+	// defaultRefreshTokenExpirationTime is used when auth.refresh_token_expiration_time is not configured (in seconds)
+	defaultRefreshTokenExpirationTime = 30 * 24 * 60 * 60
+)
+
+type AccessToken struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+// Session is the pair of tokens handed to the client after login or refresh
+type Session struct {
+	AccessToken           *AccessToken
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type BearerService struct {
+	authRepository domain.AuthRepository
+	authConfig     *config.AuthConfig
+	tokenService   *token.TokenService
+}
+
+func NewBearerService(authRepository domain.AuthRepository, authConfig *config.AuthConfig, tokenService *token.TokenService) *BearerService {
+	return &BearerService{
+		authRepository: authRepository,
+		authConfig:     authConfig,
+		tokenService:   tokenService,
+	}
+}
+
+/*
+Generate a signed access token for the user
+- The token is a JWT with the access purpose and the user id as subject
+- The session id links the token to its refresh token family, so revoking the family revokes the token
+- The lifetime is configured by auth.access_token_expiration_time
+*/
+func (bs *BearerService) GenerateAccessToken(user *domain.AuthUser, sessionID string) (*AccessToken, error) {
+	lifetime := bs.accessTokenExpirationTime()
+	expiredAt := time.Now().Add(lifetime)
+
+	tokenString, err := bs.tokenService.Issue(context.Background(), user.ID, token.PurposeAccess, lifetime, &token.Claims{
+		SessionID:    sessionID,
+		SecondFactor: hasSecondFactor(user),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessToken{
+		Value:     tokenString,
+		ExpiresAt: expiredAt,
+	}, nil
+}
+
+func (bs *BearerService) Scheme() string {
+	return Scheme
+}
+
+// Authenticate validates the access token and loads the user it was issued for
+func (bs *BearerService) Authenticate(accessToken string) (*domain.AuthUser, error) {
+	claims, err := bs.tokenService.Parse(accessToken, token.PurposeAccess)
+	if err != nil {
+		if err == token.ErrTokenExpired {
+			return &domain.AuthUser{}, ErrTokenExpired
+		}
+		return &domain.AuthUser{}, ErrInvalidToken
+	}
+
+	ctx := context.Background()
+
+	if claims.SessionID != "" {
+		active, err := bs.authRepository.IsRefreshTokenFamilyActive(ctx, claims.SessionID)
+		if err != nil {
+			return &domain.AuthUser{}, err
+		}
+		if !active {
+			return &domain.AuthUser{}, ErrSessionRevoked
+		}
+	}
+
+	user, err := bs.authRepository.FindUserByID(ctx, claims.Subject)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return &domain.AuthUser{}, ErrInvalidToken
+		}
+		return &domain.AuthUser{}, err
+	}
+
+	if err := user.CheckAuthentication(); err != nil {
+		return &domain.AuthUser{}, err
+	}
+
+	user.Credential = &domain.Credential{
+		Type:         domain.CredentialAccessToken,
+		ID:           claims.SessionID,
+		SecondFactor: claims.SecondFactor,
+	}
+
+	return user, nil
+}
+
+// StartSession issues a new refresh token family and the first access token of it.
+// The session keeps the second factor of the credential the user logged in with.
+func (bs *BearerService) StartSession(ctx context.Context, user *domain.AuthUser) (*Session, error) {
+	return bs.issueSession(ctx, user, uuidv7.New().String())
+}
+
+/*
+Refresh exchanges a refresh token for a new session
+- The refresh token is rotated, it cannot be used again
+- Presenting an already rotated token means it was leaked, so the whole family is revoked
+*/
+func (bs *BearerService) Refresh(ctx context.Context, refreshToken string) (*Session, *domain.AuthUser, error) {
+	token, err := bs.findRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, &domain.AuthUser{}, err
+	}
+
+	if token.IsRotated() {
+		if err := bs.authRepository.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, &domain.AuthUser{}, err
+		}
+		return nil, &domain.AuthUser{}, ErrRefreshTokenReused
+	}
+
+	if token.IsExpired() {
+		return nil, &domain.AuthUser{}, ErrTokenExpired
+	}
+
+	if err := bs.authRepository.MarkRefreshTokenRotated(ctx, token); err != nil {
+		if err != domain.ErrRefreshTokenNotActive {
+			return nil, &domain.AuthUser{}, err
+		}
+
+		// the token was rotated by a concurrent request
+		if err := bs.authRepository.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, &domain.AuthUser{}, err
+		}
+		return nil, &domain.AuthUser{}, ErrRefreshTokenReused
+	}
+
+	user, err := bs.authRepository.FindUserByID(ctx, token.UserID)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil, &domain.AuthUser{}, ErrInvalidRefreshToken
+		}
+		return nil, &domain.AuthUser{}, err
+	}
+
+	if err := user.CheckAuthentication(); err != nil {
+		return nil, &domain.AuthUser{}, err
+	}
+
+	// the new tokens carry the second factor of the family
+	user.Credential = &domain.Credential{
+		Type:         domain.CredentialAccessToken,
+		ID:           token.FamilyID,
+		SecondFactor: token.SecondFactor,
+	}
+
+	session, err := bs.issueSession(ctx, user, token.FamilyID)
+	if err != nil {
+		return nil, &domain.AuthUser{}, err
+	}
+
+	return session, user, nil
+}
+
+// RevokeSession revokes the refresh token family the given refresh token belongs to
+func (bs *BearerService) RevokeSession(ctx context.Context, refreshToken string) error {
+	token, err := bs.findRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return bs.authRepository.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+// RevokeAllSessions revokes every refresh token family of the user
+func (bs *BearerService) RevokeAllSessions(ctx context.Context, user *domain.AuthUser) error {
+	return bs.authRepository.RevokeUserRefreshTokens(ctx, user.ID)
+}
+
+func (bs *BearerService) findRefreshToken(ctx context.Context, refreshToken string) (*domain.RefreshToken, error) {
+	token, err := bs.authRepository.FindRefreshTokenByHash(ctx, domain.HashRefreshToken(refreshToken))
+	if err != nil {
+		if err == domain.ErrRefreshTokenNotFound {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if token.IsRevoked() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return token, nil
+}
+
+func (bs *BearerService) issueSession(ctx context.Context, user *domain.AuthUser, familyID string) (*Session, error) {
+	refreshToken, refreshTokenValue, err := domain.NewRefreshToken(user.ID, familyID, bs.refreshTokenExpirationTime())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken.SecondFactor = hasSecondFactor(user)
+
+	if err := bs.authRepository.CreateRefreshToken(ctx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := bs.GenerateAccessToken(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		AccessToken:           accessToken,
+		RefreshToken:          refreshTokenValue,
+		RefreshTokenExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
+
+func hasSecondFactor(user *domain.AuthUser) bool {
+	return user.Credential != nil && user.Credential.SecondFactor
+}
+
+func (bs *BearerService) accessTokenExpirationTime() time.Duration {
+	expirationTime := bs.authConfig.Auth.AccessTokenExpirationTime
+	if expirationTime <= 0 {
+		expirationTime = defaultAccessTokenExpirationTime
+	}
+
+	return time.Duration(expirationTime) * time.Second
+}
+
+func (bs *BearerService) refreshTokenExpirationTime() time.Duration {
+	expirationTime := bs.authConfig.Auth.RefreshTokenExpirationTime
+	if expirationTime <= 0 {
+		expirationTime = defaultRefreshTokenExpirationTime
+	}
+
+	return time.Duration(expirationTime) * time.Second
+}
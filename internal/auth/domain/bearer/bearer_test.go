@@ -0,0 +1,245 @@
+package bearer
+
+import (
+	"context"
+	"go-template/internal/auth/config"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/token"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuthRepository keeps refresh tokens in memory so rotation can be exercised
+type MockAuthRepository struct {
+	mock.Mock
+	refreshTokens map[string]*domain.RefreshToken
+}
+
+func NewMockAuthRepository() *MockAuthRepository {
+	return &MockAuthRepository{refreshTokens: make(map[string]*domain.RefreshToken)}
+}
+
+func (m *MockAuthRepository) FindUserByEmail(ctx context.Context, email string) (*domain.AuthUser, error) {
+	return nil, nil
+}
+func (m *MockAuthRepository) Create(ctx context.Context, user *domain.AuthUser) error { return nil }
+func (m *MockAuthRepository) FindUserByUsername(ctx context.Context, username string) (*domain.AuthUser, error) {
+	return nil, nil
+}
+func (m *MockAuthRepository) FindUserByID(ctx context.Context, id string) (*domain.AuthUser, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.AuthUser), args.Error(1)
+}
+func (m *MockAuthRepository) Update(ctx context.Context, user *domain.AuthUser) error { return nil }
+func (m *MockAuthRepository) VerifyAccount(ctx context.Context, user *domain.AuthUser) error {
+	return nil
+}
+func (m *MockAuthRepository) SetPendingEmail(ctx context.Context, user *domain.AuthUser, email string) error {
+	return nil
+}
+func (m *MockAuthRepository) ConfirmPendingEmail(ctx context.Context, user *domain.AuthUser, email string) error {
+	return nil
+}
+func (m *MockAuthRepository) SoftDelete(ctx context.Context, user *domain.AuthUser, deletedAt, purgeAfter time.Time) error {
+	return nil
+}
+func (m *MockAuthRepository) UpdateLastLogin(ctx context.Context, user *domain.AuthUser) error {
+	return nil
+}
+func (m *MockAuthRepository) ListUsers(ctx context.Context, query domain.UserQuery) ([]*domain.AuthUser, int, error) {
+	return nil, 0, nil
+}
+func (m *MockAuthRepository) UpdateStatus(ctx context.Context, user *domain.AuthUser) error {
+	return nil
+}
+func (m *MockAuthRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	m.refreshTokens[token.TokenHash] = token
+	return nil
+}
+func (m *MockAuthRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	token, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return nil, domain.ErrRefreshTokenNotFound
+	}
+	return token, nil
+}
+func (m *MockAuthRepository) MarkRefreshTokenRotated(ctx context.Context, token *domain.RefreshToken) error {
+	if token.IsRotated() || token.IsRevoked() {
+		return domain.ErrRefreshTokenNotActive
+	}
+	now := time.Now()
+	token.RotatedAt = &now
+	return nil
+}
+func (m *MockAuthRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, token := range m.refreshTokens {
+		if token.FamilyID == familyID && !token.IsRevoked() {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+func (m *MockAuthRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	now := time.Now()
+	for _, token := range m.refreshTokens {
+		if token.UserID == userID && !token.IsRevoked() {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+func (m *MockAuthRepository) IsRefreshTokenFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	for _, token := range m.refreshTokens {
+		if token.FamilyID == familyID && !token.IsRevoked() && !token.IsExpired() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *MockAuthRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+func (m *MockAuthRepository) AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error {
+	return nil
+}
+func (m *MockAuthRepository) ListPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error) {
+	return nil, nil
+}
+func (m *MockAuthRepository) AppendAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	return nil
+}
+func (m *MockAuthRepository) ListAuthEvents(ctx context.Context, userID string, limit, offset int) ([]*domain.AuthEvent, error) {
+	return nil, nil
+}
+
+// fakeTokenRepository accepts the single-use tokens without storing them
+type fakeTokenRepository struct {
+	token.Repository
+}
+
+func (r *fakeTokenRepository) Create(ctx context.Context, record *token.Record) error { return nil }
+
+func TestBearerService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokenService := token.NewTokenService(&fakeTokenRepository{}, token.NewKeyring("test-secret-key"))
+	mockAuthRepository := NewMockAuthRepository()
+	bearerService := NewBearerService(mockAuthRepository, &config.AuthConfig{}, tokenService)
+
+	mockUser, _ := domain.NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	mockAuthRepository.On("FindUserByID", mock.Anything, mockUser.ID).Return(mockUser, nil)
+
+	t.Run("Test GenerateAccessToken", func(t *testing.T) {
+		accessToken, err := bearerService.GenerateAccessToken(mockUser, "")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if accessToken.Value == "" {
+			t.Errorf("Token should not be empty")
+		}
+
+		if time.Until(accessToken.ExpiresAt) > defaultAccessTokenExpirationTime*time.Second {
+			t.Errorf("Token should expire within the default expiration time, got %v", accessToken.ExpiresAt)
+		}
+	})
+
+	t.Run("Test Authenticate", func(t *testing.T) {
+		accessToken, _ := bearerService.GenerateAccessToken(mockUser, "")
+
+		user, err := bearerService.Authenticate(accessToken.Value)
+		if err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		if user.ID != mockUser.ID {
+			t.Errorf("User ID should be %s, got %s", mockUser.ID, user.ID)
+		}
+
+		// test expired token
+		expiredToken, _ := tokenService.Issue(context.Background(), mockUser.ID, token.PurposeAccess, -time.Minute, nil)
+
+		_, err = bearerService.Authenticate(expiredToken)
+		if err != ErrTokenExpired {
+			t.Errorf("Error should be token expired, got %v", err)
+		}
+
+		// test token issued for another purpose (e.g. a verification email token)
+		verificationToken, _ := tokenService.Issue(context.Background(), mockUser.ID, token.PurposeVerifyEmail, time.Minute, nil)
+
+		_, err = bearerService.Authenticate(verificationToken)
+		if err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+
+		// test token signed with another key
+		forgedService := token.NewTokenService(&fakeTokenRepository{}, token.NewKeyring("another-secret-key"))
+		forgedToken, _ := forgedService.Issue(context.Background(), mockUser.ID, token.PurposeAccess, time.Minute, nil)
+
+		_, err = bearerService.Authenticate(forgedToken)
+		if err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test Refresh", func(t *testing.T) {
+		ctx := context.Background()
+
+		session, err := bearerService.StartSession(ctx, mockUser)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		refreshed, _, err := bearerService.Refresh(ctx, session.RefreshToken)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if refreshed.RefreshToken == session.RefreshToken {
+			t.Errorf("Refresh token should be rotated")
+		}
+
+		// test reuse of the rotated refresh token revokes the family
+		_, _, err = bearerService.Refresh(ctx, session.RefreshToken)
+		if err != ErrRefreshTokenReused {
+			t.Errorf("Error should be refresh token reused, got %v", err)
+		}
+
+		_, _, err = bearerService.Refresh(ctx, refreshed.RefreshToken)
+		if err != ErrInvalidRefreshToken {
+			t.Errorf("Error should be invalid refresh token, got %v", err)
+		}
+
+		_, err = bearerService.Authenticate(refreshed.AccessToken.Value)
+		if err != ErrSessionRevoked {
+			t.Errorf("Error should be session revoked, got %v", err)
+		}
+
+		// test unknown refresh token
+		_, _, err = bearerService.Refresh(ctx, "unknown")
+		if err != ErrInvalidRefreshToken {
+			t.Errorf("Error should be invalid refresh token, got %v", err)
+		}
+	})
+
+	t.Run("Test RevokeAllSessions", func(t *testing.T) {
+		ctx := context.Background()
+
+		first, _ := bearerService.StartSession(ctx, mockUser)
+		second, _ := bearerService.StartSession(ctx, mockUser)
+
+		if err := bearerService.RevokeAllSessions(ctx, mockUser); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		for _, session := range []*Session{first, second} {
+			if _, err := bearerService.Authenticate(session.AccessToken.Value); err != ErrSessionRevoked {
+				t.Errorf("Error should be session revoked, got %v", err)
+			}
+		}
+	})
+}
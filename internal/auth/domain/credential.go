@@ -0,0 +1,20 @@
+package domain
+
+type CredentialType string
+
+const (
+	CredentialPassword    CredentialType = "password"
+	CredentialAccessToken CredentialType = "access_token"
+	CredentialApiKey      CredentialType = "api_key"
+)
+
+// Credential describes how the current request was authenticated
+type Credential struct {
+	Type CredentialType
+	// ID identifies the credential, e.g. the session id of an access token or the id of an api key
+	ID string
+	// Scopes restricts what the credential may do, nil means unrestricted
+	Scopes []string
+	// SecondFactor is set when the session was started with a completed TOTP challenge
+	SecondFactor bool
+}
@@ -0,0 +1,58 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrDecryptSecret = errors.New("failed to decrypt secret")
+
+// SecretCipher encrypts the TOTP secrets at rest with AES-256-GCM
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher derives the AES key from the configured encryption key
+func NewSecretCipher(encryptionKey string) (*SecretCipher, error) {
+	key := sha256.Sum256([]byte(encryptionKey))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretCipher{aead: aead}, nil
+}
+
+// Encrypt returns the random nonce followed by the sealed secret.
+// The user id is authenticated with it, so a secret cannot be moved to another account.
+func (c *SecretCipher) Encrypt(userID, secret string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, []byte(secret), []byte(userID)), nil
+}
+
+func (c *SecretCipher) Decrypt(userID string, encrypted []byte) (string, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(encrypted) < nonceSize {
+		return "", ErrDecryptSecret
+	}
+
+	secret, err := c.aead.Open(nil, encrypted[:nonceSize], encrypted[nonceSize:], []byte(userID))
+	if err != nil {
+		return "", ErrDecryptSecret
+	}
+
+	return string(secret), nil
+}
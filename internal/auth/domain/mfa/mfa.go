@@ -0,0 +1,309 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/token"
+	"strings"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+var (
+	ErrNotEnrolled      = errors.New("second factor is not enrolled")
+	ErrAlreadyEnabled   = errors.New("second factor is already enabled")
+	ErrInvalidCode      = errors.New("invalid second factor code")
+	ErrCodeAlreadyUsed  = errors.New("second factor code already used")
+	ErrInvalidChallenge = errors.New("invalid second factor challenge")
+)
+
+const (
+	recoveryCodeCount = 10
+	// challengeLifetime is how long the user has to enter the code after the password was accepted
+	challengeLifetime = 5 * time.Minute
+)
+
+// TOTP is the authenticator enrolled by a user, it is only used once confirmed with a first code
+type TOTP struct {
+	UserID          string
+	SecretEncrypted []byte
+	ConfirmedAt     *time.Time
+	LastUsedStep    *int64
+	CreatedAt       time.Time
+}
+
+func (t *TOTP) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// RecoveryCode is a one-time code to pass the second factor without the authenticator.
+// Only the SHA-256 hash is stored, the plain codes are shown once on confirmation.
+type RecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type Repository interface {
+	// SaveTOTP stores a new enrollment, replacing an unconfirmed one
+	SaveTOTP(ctx context.Context, totp *TOTP) error
+	FindTOTP(ctx context.Context, userID string) (*TOTP, error)
+	// ConfirmTOTP enables the enrollment and replaces the recovery codes of the user
+	ConfirmTOTP(ctx context.Context, userID string, confirmedAt time.Time, codes []*RecoveryCode) error
+	// UseTOTPStep records the accepted step, it fails with ErrCodeAlreadyUsed if the step is not newer than the last one
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	// UseRecoveryCode marks the code as used, it fails with ErrInvalidCode if there is no unused code with the hash
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	DeleteTOTP(ctx context.Context, userID string) error
+}
+
+// Enrollment is returned once when the user starts enrolling an authenticator
+type Enrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type MFAService struct {
+	repository     Repository
+	authRepository domain.AuthRepository
+	tokenService   *token.TokenService
+	cipher         *SecretCipher
+	issuer         string
+}
+
+func NewMFAService(repository Repository, authRepository domain.AuthRepository, tokenService *token.TokenService, cipher *SecretCipher, issuer string) *MFAService {
+	return &MFAService{
+		repository:     repository,
+		authRepository: authRepository,
+		tokenService:   tokenService,
+		cipher:         cipher,
+		issuer:         issuer,
+	}
+}
+
+/*
+Enroll generates a new TOTP secret for the user
+- The secret is stored encrypted and unconfirmed, starting over replaces a pending enrollment
+- An enabled authenticator has to be disabled first
+*/
+func (s *MFAService) Enroll(ctx context.Context, user *domain.AuthUser) (*Enrollment, error) {
+	existing, err := s.repository.FindTOTP(ctx, user.ID)
+	if err != nil && err != ErrNotEnrolled {
+		return nil, err
+	}
+	if existing != nil && existing.IsConfirmed() {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.cipher.Encrypt(user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repository.SaveTOTP(ctx, &TOTP{
+		UserID:          user.ID,
+		SecretEncrypted: encrypted,
+		CreatedAt:       time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningURI: ProvisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables the pending enrollment with a first code and returns the plain recovery codes
+func (s *MFAService) Confirm(ctx context.Context, user *domain.AuthUser, code string) ([]string, error) {
+	totp, err := s.repository.FindTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if totp.IsConfirmed() {
+		return nil, ErrAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	plainCodes, codes, err := generateRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.ConfirmTOTP(ctx, user.ID, time.Now(), codes); err != nil {
+		return nil, err
+	}
+
+	return plainCodes, nil
+}
+
+// Disable removes the authenticator and the recovery codes, a valid code is required
+func (s *MFAService) Disable(ctx context.Context, user *domain.AuthUser, code string) error {
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		return err
+	}
+
+	return s.repository.DeleteTOTP(ctx, user.ID)
+}
+
+func (s *MFAService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	totp, err := s.repository.FindTOTP(ctx, userID)
+	if err != nil {
+		if err == ErrNotEnrolled {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return totp.IsConfirmed(), nil
+}
+
+// Verify accepts a current TOTP code or an unused recovery code of a confirmed enrollment
+func (s *MFAService) Verify(ctx context.Context, userID, code string) error {
+	totp, err := s.repository.FindTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !totp.IsConfirmed() {
+		return ErrNotEnrolled
+	}
+
+	if isTOTPCode(code) {
+		return s.verifyTOTP(ctx, totp, code)
+	}
+
+	return s.repository.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
+
+// StartChallenge issues the single-use token the second login step is bound to
+func (s *MFAService) StartChallenge(ctx context.Context, user *domain.AuthUser) (string, error) {
+	return s.tokenService.Issue(ctx, user.ID, token.PurposeSecondFactor, challengeLifetime, nil)
+}
+
+// ChallengeUser returns the user of the challenge without consuming it, so the attempts on the account can be checked first
+func (s *MFAService) ChallengeUser(ctx context.Context, challengeToken string) (*domain.AuthUser, error) {
+	claims, err := s.tokenService.Parse(challengeToken, token.PurposeSecondFactor)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.authRepository.FindUserByID(ctx, claims.Subject)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+/*
+CompleteChallenge finishes the login of a user with a second factor
+- The challenge token proves the password was accepted
+- The token is consumed before the code is checked, a replayed challenge cannot burn the recovery codes
+- The returned user carries a password credential with the second factor set
+- The user is also returned with a refused account or code, so the failure can be recorded for it
+*/
+func (s *MFAService) CompleteChallenge(ctx context.Context, challengeToken, code string) (*domain.AuthUser, error) {
+	user, err := s.ChallengeUser(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := user.CheckAuthentication(); err != nil {
+		return user, err
+	}
+
+	if _, err := s.tokenService.Consume(ctx, challengeToken, token.PurposeSecondFactor); err != nil {
+		if err == token.ErrTokenAlreadyUsed || err == token.ErrInvalidToken {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	// the user is returned with a wrong code, so the failure can be counted for the account
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		return user, err
+	}
+
+	user.Credential = &domain.Credential{Type: domain.CredentialPassword, SecondFactor: true}
+
+	return user, nil
+}
+
+func (s *MFAService) verifyTOTP(ctx context.Context, totp *TOTP, code string) error {
+	secret, err := s.cipher.Decrypt(totp.UserID, totp.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+
+	step, ok := ValidateCode(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	// a code is accepted once, an observed code cannot be replayed
+	return s.repository.UseTOTPStep(ctx, totp.UserID, step)
+}
+
+func generateRecoveryCodes(userID string) ([]string, []*RecoveryCode, error) {
+	now := time.Now()
+	plainCodes := make([]string, 0, recoveryCodeCount)
+	codes := make([]*RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		randomBytes := make([]byte, 5)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, nil, err
+		}
+		encoded := hex.EncodeToString(randomBytes)
+		plainCode := encoded[:5] + "-" + encoded[5:]
+
+		plainCodes = append(plainCodes, plainCode)
+		codes = append(codes, &RecoveryCode{
+			ID:        uuidv7.New().String(),
+			UserID:    userID,
+			CodeHash:  hashRecoveryCode(plainCode),
+			CreatedAt: now,
+		})
+	}
+
+	return plainCodes, codes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, the way users tend to retype the codes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,241 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base32"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/token"
+	"testing"
+	"time"
+)
+
+// fakeRepository keeps the enrollments in memory
+type fakeRepository struct {
+	totps         map[string]*TOTP
+	recoveryCodes map[string]*RecoveryCode
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{totps: map[string]*TOTP{}, recoveryCodes: map[string]*RecoveryCode{}}
+}
+
+func (r *fakeRepository) SaveTOTP(ctx context.Context, totp *TOTP) error {
+	r.totps[totp.UserID] = totp
+	return nil
+}
+
+func (r *fakeRepository) FindTOTP(ctx context.Context, userID string) (*TOTP, error) {
+	totp, ok := r.totps[userID]
+	if !ok {
+		return nil, ErrNotEnrolled
+	}
+	return totp, nil
+}
+
+func (r *fakeRepository) ConfirmTOTP(ctx context.Context, userID string, confirmedAt time.Time, codes []*RecoveryCode) error {
+	r.totps[userID].ConfirmedAt = &confirmedAt
+	for _, code := range codes {
+		r.recoveryCodes[code.CodeHash] = code
+	}
+	return nil
+}
+
+func (r *fakeRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	totp := r.totps[userID]
+	if totp.LastUsedStep != nil && *totp.LastUsedStep >= step {
+		return ErrCodeAlreadyUsed
+	}
+	totp.LastUsedStep = &step
+	return nil
+}
+
+func (r *fakeRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	code, ok := r.recoveryCodes[codeHash]
+	if !ok || code.UserID != userID || code.UsedAt != nil {
+		return ErrInvalidCode
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	return nil
+}
+
+func (r *fakeRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	delete(r.totps, userID)
+	return nil
+}
+
+type fakeAuthRepository struct {
+	domain.AuthRepository
+	user *domain.AuthUser
+}
+
+func (r *fakeAuthRepository) FindUserByID(ctx context.Context, id string) (*domain.AuthUser, error) {
+	if r.user.ID != id {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *r.user
+	return &copied, nil
+}
+
+type fakeTokenRepository struct {
+	consumed map[string]bool
+}
+
+func (r *fakeTokenRepository) Create(ctx context.Context, record *token.Record) error { return nil }
+
+func (r *fakeTokenRepository) Consume(ctx context.Context, id string) error {
+	if r.consumed[id] {
+		return token.ErrTokenAlreadyUsed
+	}
+	r.consumed[id] = true
+	return nil
+}
+
+func (r *fakeTokenRepository) RevokeByUser(ctx context.Context, userID string, purpose token.Purpose) error {
+	return nil
+}
+
+func TestValidateCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 secret truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, _ := GenerateCode(secret, time.Unix(unix, 0))
+		if code != expected {
+			t.Errorf("Code at %d should be %s, got %s", unix, expected, code)
+		}
+	}
+
+	now := time.Unix(1111111109, 0)
+	previous, _ := GenerateCode(secret, now.Add(-totpPeriod*time.Second))
+	if _, ok := ValidateCode(secret, previous, now); !ok {
+		t.Errorf("Code of the previous step should be accepted")
+	}
+
+	old, _ := GenerateCode(secret, now.Add(-2*totpPeriod*time.Second))
+	if _, ok := ValidateCode(secret, old, now); ok {
+		t.Errorf("Code two steps old should be refused")
+	}
+}
+
+func TestMFAService(t *testing.T) {
+	ctx := context.Background()
+
+	mockUser, _ := domain.NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	repository := newFakeRepository()
+	tokenService := token.NewTokenService(&fakeTokenRepository{consumed: map[string]bool{}}, token.NewKeyring("test-secret-key"))
+	cipher, _ := NewSecretCipher("test-encryption-key")
+	service := NewMFAService(repository, &fakeAuthRepository{user: mockUser}, tokenService, cipher, "Webapp")
+
+	enrollment, err := service.Enroll(ctx, mockUser)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if string(repository.totps[mockUser.ID].SecretEncrypted) == enrollment.Secret {
+		t.Errorf("Secret should be stored encrypted")
+	}
+
+	var recoveryCodes []string
+
+	t.Run("Test Confirm", func(t *testing.T) {
+		if enabled, _ := service.IsEnabled(ctx, mockUser.ID); enabled {
+			t.Errorf("Second factor should not be enabled before the confirmation")
+		}
+
+		if _, err := service.Confirm(ctx, mockUser, "000000"); err != ErrInvalidCode {
+			t.Errorf("Error should be invalid code, got %v", err)
+		}
+
+		code, _ := GenerateCode(enrollment.Secret, time.Now())
+		recoveryCodes, err = service.Confirm(ctx, mockUser, code)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if len(recoveryCodes) != recoveryCodeCount {
+			t.Errorf("Confirmation should return %d recovery codes, got %d", recoveryCodeCount, len(recoveryCodes))
+		}
+
+		if enabled, _ := service.IsEnabled(ctx, mockUser.ID); !enabled {
+			t.Errorf("Second factor should be enabled")
+		}
+
+		if _, err := service.Enroll(ctx, mockUser); err != ErrAlreadyEnabled {
+			t.Errorf("Error should be already enabled, got %v", err)
+		}
+	})
+
+	t.Run("Test CompleteChallenge", func(t *testing.T) {
+		challengeToken, _ := service.StartChallenge(ctx, mockUser)
+
+		// the code accepted by the confirmation cannot be replayed
+		code, _ := GenerateCode(enrollment.Secret, time.Now())
+		if _, err := service.CompleteChallenge(ctx, challengeToken, code); err != ErrCodeAlreadyUsed {
+			t.Errorf("Error should be code already used, got %v", err)
+		}
+
+		challengeToken, _ = service.StartChallenge(ctx, mockUser)
+		user, err := service.CompleteChallenge(ctx, challengeToken, recoveryCodes[0])
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if user.Credential == nil || !user.Credential.SecondFactor {
+			t.Errorf("Credential should carry the second factor")
+		}
+
+		if _, err := service.CompleteChallenge(ctx, challengeToken, recoveryCodes[1]); err != ErrInvalidChallenge {
+			t.Errorf("Error should be invalid challenge, got %v", err)
+		}
+
+		// the refused challenge did not use up the recovery code
+		if err := service.Verify(ctx, mockUser.ID, recoveryCodes[1]); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		// recovery codes are single-use as well
+		challengeToken, _ = service.StartChallenge(ctx, mockUser)
+		if _, err := service.CompleteChallenge(ctx, challengeToken, recoveryCodes[0]); err != ErrInvalidCode {
+			t.Errorf("Error should be invalid code, got %v", err)
+		}
+	})
+
+	t.Run("Test suspended account", func(t *testing.T) {
+		challengeToken, _ := service.StartChallenge(ctx, mockUser)
+
+		status := mockUser.Status
+		mockUser.Status = domain.AccountStatusSuspended
+		defer func() { mockUser.Status = status }()
+
+		user, err := service.CompleteChallenge(ctx, challengeToken, recoveryCodes[1])
+		if err != domain.ErrAccountSuspended {
+			t.Errorf("Error should be account suspended, got %v", err)
+		}
+
+		// the refused account is returned, so the failure can be recorded for it
+		if user == nil || user.ID != mockUser.ID {
+			t.Errorf("User should be returned with the refused account, got %v", user)
+		}
+	})
+
+	t.Run("Test Disable", func(t *testing.T) {
+		if err := service.Disable(ctx, mockUser, "AAAAA-AAAAA"); err != ErrInvalidCode {
+			t.Errorf("Error should be invalid code, got %v", err)
+		}
+
+		// surrounding spaces are ignored
+		if err := service.Disable(ctx, mockUser, " "+recoveryCodes[2]+" "); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if enabled, _ := service.IsEnabled(ctx, mockUser.ID); enabled {
+			t.Errorf("Second factor should be disabled")
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+const (
+	// BcryptMaxPasswordLength is the number of bytes bcrypt hashes, anything after it is silently ignored
+	BcryptMaxPasswordLength = 72
+	// Argon2idMaxPasswordLength only bounds the work of a single hash, Argon2id uses the whole password
+	Argon2idMaxPasswordLength = 1024
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// Argon2Params are encoded in every Argon2id hash, a hash keeps the parameters it was created with
+type Argon2Params struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP recommendation for Argon2id
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+/*
+PasswordHasher hashes new passwords with the configured algorithm
+- The hashes are self-describing, bcrypt "$2a$<cost>$..." and Argon2id "$argon2id$v=19$m=...,t=...,p=...$<salt>$<key>"
+- Verify accepts a hash of either algorithm with any parameters, so older hashes keep working
+- NeedsRehash reports the hashes that were not created with the current algorithm and parameters
+*/
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Params
+}
+
+// DefaultPasswordHasher is bcrypt with the default cost, the hashes created before the hasher was configurable
+func DefaultPasswordHasher() *PasswordHasher {
+	hasher, _ := NewPasswordHasher(PasswordAlgorithmBcrypt, bcrypt.DefaultCost, DefaultArgon2Params())
+	return hasher
+}
+
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*PasswordHasher, error) {
+	switch algorithm {
+	case PasswordAlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case PasswordAlgorithmArgon2id:
+		if argon2Params.Memory == 0 || argon2Params.Iterations == 0 || argon2Params.Parallelism == 0 {
+			return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+		}
+		if argon2Params.SaltLength < 8 || argon2Params.KeyLength < 16 {
+			return nil, errors.New("argon2id salt must be at least 8 bytes and the key at least 16 bytes")
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm: %s", algorithm)
+	}
+
+	return &PasswordHasher{
+		algorithm:  algorithm,
+		bcryptCost: bcryptCost,
+		argon2:     argon2Params,
+	}, nil
+}
+
+func (h *PasswordHasher) Algorithm() string {
+	return h.algorithm
+}
+
+// MaxPasswordLength is the longest password in bytes the algorithm accepts
+func (h *PasswordHasher) MaxPasswordLength() int {
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		return BcryptMaxPasswordLength
+	}
+
+	return Argon2idMaxPasswordLength
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordAlgorithmArgon2id {
+		return hashArgon2id(password, h.argon2)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	return string(hashedPassword), err
+}
+
+func (h *PasswordHasher) Verify(passwordHash, password string) bool {
+	if isArgon2idHash(passwordHash) {
+		params, salt, key, err := decodeArgon2id(passwordHash)
+		if err != nil {
+			return false
+		}
+
+		derived := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(derived, key) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
+
+// NeedsRehash is false for hashes in an unknown format, they cannot be verified to be rehashed anyway
+func (h *PasswordHasher) NeedsRehash(passwordHash string) bool {
+	if isArgon2idHash(passwordHash) {
+		if h.algorithm != PasswordAlgorithmArgon2id {
+			return true
+		}
+
+		params, salt, _, err := decodeArgon2id(passwordHash)
+		if err != nil {
+			return false
+		}
+		params.SaltLength = uint32(len(salt))
+
+		return params != h.argon2
+	}
+
+	cost, err := bcrypt.Cost([]byte(passwordHash))
+	if err != nil {
+		return false
+	}
+
+	return h.algorithm != PasswordAlgorithmBcrypt || cost != h.bcryptCost
+}
+
+func isArgon2idHash(passwordHash string) bool {
+	return strings.HasPrefix(passwordHash, "$"+PasswordAlgorithmArgon2id+"$")
+}
+
+func hashArgon2id(password string, params Argon2Params) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordAlgorithmArgon2id,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id returns the parameters, the salt and the key of an encoded hash, the salt length is not set
+func decodeArgon2id(passwordHash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
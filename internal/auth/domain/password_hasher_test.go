@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2Params keep the tests quick, they are far too weak for real use
+var fastArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher(t *testing.T) {
+	bcryptHasher, _ := NewPasswordHasher(PasswordAlgorithmBcrypt, bcrypt.MinCost, fastArgon2Params)
+	argon2Hasher, _ := NewPasswordHasher(PasswordAlgorithmArgon2id, bcrypt.MinCost, fastArgon2Params)
+
+	bcryptHash, _ := bcryptHasher.Hash("iampassword")
+	argon2Hash, err := argon2Hasher.Hash("iampassword")
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	t.Run("Test Verify", func(t *testing.T) {
+		if !strings.HasPrefix(argon2Hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+			t.Errorf("Hash should encode the parameters, got %s", argon2Hash)
+		}
+
+		for _, hasher := range []*PasswordHasher{bcryptHasher, argon2Hasher} {
+			// both algorithms verify the hashes of the other one
+			for _, hash := range []string{bcryptHash, argon2Hash} {
+				if !hasher.Verify(hash, "iampassword") {
+					t.Errorf("%s hasher should verify %s", hasher.Algorithm(), hash)
+				}
+				if hasher.Verify(hash, "wrongpassword") {
+					t.Errorf("%s hasher should refuse a wrong password for %s", hasher.Algorithm(), hash)
+				}
+			}
+		}
+
+		if argon2Hasher.Verify("$argon2id$v=19$m=64,t=1,p=1$bm90YmFzZTY0$", "iampassword") {
+			t.Errorf("Malformed hash should be refused")
+		}
+	})
+
+	t.Run("Test NeedsRehash", func(t *testing.T) {
+		legacyHash, _ := bcrypt.GenerateFromPassword([]byte("iampassword"), bcrypt.DefaultCost)
+
+		strongerArgon2Params := fastArgon2Params
+		strongerArgon2Params.Iterations = 2
+		strongerArgon2Hasher, _ := NewPasswordHasher(PasswordAlgorithmArgon2id, bcrypt.MinCost, strongerArgon2Params)
+
+		for _, c := range []struct {
+			hasher   *PasswordHasher
+			hash     string
+			expected bool
+		}{
+			{bcryptHasher, bcryptHash, false},
+			{bcryptHasher, string(legacyHash), true},
+			{bcryptHasher, argon2Hash, true},
+			{argon2Hasher, argon2Hash, false},
+			{argon2Hasher, bcryptHash, true},
+			{strongerArgon2Hasher, argon2Hash, true},
+			{argon2Hasher, "plain", false},
+		} {
+			if needsRehash := c.hasher.NeedsRehash(c.hash); needsRehash != c.expected {
+				t.Errorf("NeedsRehash of %s with %s should be %v", c.hash, c.hasher.Algorithm(), c.expected)
+			}
+		}
+	})
+
+	t.Run("Test invalid config", func(t *testing.T) {
+		if _, err := NewPasswordHasher(PasswordAlgorithmBcrypt, bcrypt.MaxCost+1, fastArgon2Params); err == nil {
+			t.Errorf("Error should not be nil for an invalid bcrypt cost")
+		}
+
+		if _, err := NewPasswordHasher(PasswordAlgorithmArgon2id, bcrypt.DefaultCost, Argon2Params{}); err == nil {
+			t.Errorf("Error should not be nil for empty argon2id parameters")
+		}
+
+		if _, err := NewPasswordHasher("md5", bcrypt.DefaultCost, fastArgon2Params); err == nil {
+			t.Errorf("Error should not be nil for an unknown algorithm")
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// the rules a password can violate
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleUppercase    = "uppercase"
+	PasswordRuleLowercase    = "lowercase"
+	PasswordRuleDigit        = "digit"
+	PasswordRuleSymbol       = "symbol"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleReused       = "reused"
+	PasswordRuleBreached     = "breached"
+)
+
+type PasswordViolation struct {
+	Rule    string
+	Message string
+}
+
+// PasswordPolicyError lists every rule the password violates
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+
+	return "password does not meet the policy: " + strings.Join(messages, ", ")
+}
+
+type PasswordPolicy struct {
+	// MinLength is counted in characters, MaxLength in bytes since that is what the hash sees
+	MinLength int
+	MaxLength int
+
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+
+	// HistorySize is the number of previous passwords, the current one included, that cannot be reused
+	HistorySize int
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   BcryptMaxPasswordLength,
+		HistorySize: 5,
+	}
+}
+
+/*
+BreachedPasswords looks up leaked passwords by the k-anonymity range of their SHA-1 hash,
+the same way as the Pwned Passwords range API
+- Range gets the first 5 hex characters of the hash and returns the remaining 35 of every breached hash
+*/
+type BreachedPasswords interface {
+	Range(ctx context.Context, prefix string) ([]string, error)
+}
+
+type PasswordValidator struct {
+	policy     PasswordPolicy
+	repository AuthRepository
+	breached   BreachedPasswords
+}
+
+// NewPasswordValidator creates a validator, the breached passwords are not checked when breached is nil
+func NewPasswordValidator(policy PasswordPolicy, repository AuthRepository, breached BreachedPasswords) *PasswordValidator {
+	return &PasswordValidator{
+		policy:     policy,
+		repository: repository,
+		breached:   breached,
+	}
+}
+
+func (v *PasswordValidator) HistorySize() int {
+	return v.policy.HistorySize
+}
+
+/*
+Validate checks the password of the user against the policy
+- All rules are checked, a PasswordPolicyError lists every violation
+- The user of a registration has no ID yet, the reuse check is skipped for it
+- Other errors come from the password history or the breached passwords lookup
+*/
+func (v *PasswordValidator) Validate(ctx context.Context, user *AuthUser, password string) error {
+	var violations []PasswordViolation
+	violate := func(rule, message string) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: message})
+	}
+
+	if length := len([]rune(password)); length < v.policy.MinLength {
+		violate(PasswordRuleMinLength, fmt.Sprintf("password must be at least %d characters long", v.policy.MinLength))
+	}
+	if v.policy.MaxLength > 0 && len(password) > v.policy.MaxLength {
+		violate(PasswordRuleMaxLength, fmt.Sprintf("password must be at most %d bytes long", v.policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			hasSymbol = true
+		}
+	}
+	if v.policy.RequireUppercase && !hasUpper {
+		violate(PasswordRuleUppercase, "password must contain an uppercase letter")
+	}
+	if v.policy.RequireLowercase && !hasLower {
+		violate(PasswordRuleLowercase, "password must contain a lowercase letter")
+	}
+	if v.policy.RequireDigit && !hasDigit {
+		violate(PasswordRuleDigit, "password must contain a digit")
+	}
+	if v.policy.RequireSymbol && !hasSymbol {
+		violate(PasswordRuleSymbol, "password must contain a symbol")
+	}
+
+	if containsPersonalInfo(user, password) {
+		violate(PasswordRulePersonalInfo, "password must not contain your email or name")
+	}
+
+	reused, err := v.isReused(ctx, user, password)
+	if err != nil {
+		return err
+	}
+	if reused {
+		violate(PasswordRuleReused, fmt.Sprintf("password must not be one of your last %d passwords", v.policy.HistorySize))
+	}
+
+	breached, err := v.isBreached(ctx, password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		violate(PasswordRuleBreached, "password has appeared in a data breach")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// isReused compares the password with the current hash and the hashes of the history
+func (v *PasswordValidator) isReused(ctx context.Context, user *AuthUser, password string) (bool, error) {
+	if v.policy.HistorySize <= 0 || user.ID == "" {
+		return false, nil
+	}
+
+	hashes, err := v.repository.ListPasswordHistory(ctx, user.ID, v.policy.HistorySize)
+	if err != nil {
+		return false, err
+	}
+
+	// users from before the history only have their current password
+	if user.PasswordHash != "" && !contains(hashes, user.PasswordHash) {
+		hashes = append([]string{user.PasswordHash}, hashes...)
+	}
+
+	for i, hash := range hashes {
+		if i >= v.policy.HistorySize {
+			break
+		}
+		if passwordMatches(hash, password) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isBreached only sends the prefix of the hash to the lookup
+func (v *PasswordValidator) isBreached(ctx context.Context, password string) (bool, error) {
+	if v.breached == nil {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := v.breached.Range(ctx, hash[:5])
+	if err != nil {
+		return false, err
+	}
+
+	return contains(suffixes, hash[5:]), nil
+}
+
+// containsPersonalInfo ignores the case and the parts shorter than 3 characters
+func containsPersonalInfo(user *AuthUser, password string) bool {
+	password = strings.ToLower(password)
+
+	email := strings.ToLower(user.Email)
+	localPart, _, _ := strings.Cut(email, "@")
+
+	for _, info := range []string{email, localPart, strings.ToLower(user.FirstName), strings.ToLower(user.LastName)} {
+		if len(info) >= 3 && strings.Contains(password, info) {
+			return true
+		}
+	}
+
+	return false
+}
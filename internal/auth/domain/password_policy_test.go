@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeBreachedPasswords serves the ranges of a few plain passwords
+type fakeBreachedPasswords struct {
+	ranges   map[string][]string
+	prefixes []string
+}
+
+func newFakeBreachedPasswords(passwords ...string) *fakeBreachedPasswords {
+	breached := &fakeBreachedPasswords{ranges: map[string][]string{}}
+	for _, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		breached.ranges[hash[:5]] = append(breached.ranges[hash[:5]], hash[5:])
+	}
+	return breached
+}
+
+func (b *fakeBreachedPasswords) Range(ctx context.Context, prefix string) ([]string, error) {
+	b.prefixes = append(b.prefixes, prefix)
+	return b.ranges[prefix], nil
+}
+
+func isPolicyViolation(err error, rule string) bool {
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	for _, violation := range policyErr.Violations {
+		if violation.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPasswordValidator(t *testing.T) {
+	ctx := context.Background()
+
+	mockUser, _ := NewAuthUser("jane.doe@example.com", "Jane", "Doe", "currentPassword1!")
+	repository := &fakeAuthRepository{user: mockUser}
+	breached := newFakeBreachedPasswords("Password123!")
+
+	policy := DefaultPasswordPolicy()
+	policy.RequireUppercase = true
+	policy.RequireLowercase = true
+	policy.RequireDigit = true
+	policy.RequireSymbol = true
+	validator := NewPasswordValidator(policy, repository, breached)
+
+	t.Run("Test valid password", func(t *testing.T) {
+		if err := validator.Validate(ctx, mockUser, "Correct-Horse-9"); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		// only the prefix of the hash leaves the validator
+		if prefix := breached.prefixes[len(breached.prefixes)-1]; len(prefix) != 5 {
+			t.Errorf("Range should be looked up by a 5 character prefix, got %s", prefix)
+		}
+	})
+
+	t.Run("Test every rule is reported", func(t *testing.T) {
+		err := validator.Validate(ctx, mockUser, "jane")
+
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Fatalf("Error should be a policy error, got %v", err)
+		}
+
+		for _, rule := range []string{PasswordRuleMinLength, PasswordRuleUppercase, PasswordRuleDigit, PasswordRuleSymbol, PasswordRulePersonalInfo} {
+			if !isPolicyViolation(err, rule) {
+				t.Errorf("Violations should contain %s, got %v", rule, policyErr.Violations)
+			}
+		}
+
+		if isPolicyViolation(err, PasswordRuleLowercase) {
+			t.Errorf("Violations should not contain %s", PasswordRuleLowercase)
+		}
+	})
+
+	t.Run("Test max length", func(t *testing.T) {
+		// bcrypt would ignore everything after the 72nd byte
+		if err := validator.Validate(ctx, mockUser, "Aa1!"+strings.Repeat("x", BcryptMaxPasswordLength)); !isPolicyViolation(err, PasswordRuleMaxLength) {
+			t.Errorf("Error should be a max length violation, got %v", err)
+		}
+	})
+
+	t.Run("Test personal info", func(t *testing.T) {
+		for _, password := range []string{"My-DOE-password-1", "jane.doe-Secret1", "x-Jane-Secret-1"} {
+			if err := validator.Validate(ctx, mockUser, password); !isPolicyViolation(err, PasswordRulePersonalInfo) {
+				t.Errorf("Error should be a personal info violation for %s, got %v", password, err)
+			}
+		}
+	})
+
+	t.Run("Test breached", func(t *testing.T) {
+		if err := validator.Validate(ctx, mockUser, "Password123!"); !isPolicyViolation(err, PasswordRuleBreached) {
+			t.Errorf("Error should be a breached violation, got %v", err)
+		}
+	})
+
+	t.Run("Test reuse", func(t *testing.T) {
+		// the current password counts before it is in the history
+		if err := validator.Validate(ctx, mockUser, "currentPassword1!"); !isPolicyViolation(err, PasswordRuleReused) {
+			t.Errorf("Error should be a reused violation, got %v", err)
+		}
+
+		old, _ := HashPassword("oldPassword1!")
+		repository.history = []string{mockUser.PasswordHash, old}
+		if err := validator.Validate(ctx, mockUser, "oldPassword1!"); !isPolicyViolation(err, PasswordRuleReused) {
+			t.Errorf("Error should be a reused violation, got %v", err)
+		}
+
+		// a new user has no history to compare with
+		if err := validator.Validate(ctx, &AuthUser{Email: "new@example.com"}, "oldPassword1!"); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+	})
+}
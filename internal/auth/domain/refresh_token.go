@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+var (
+	ErrRefreshTokenNotFound  = errors.New("refresh token not found")
+	ErrRefreshTokenNotActive = errors.New("refresh token is not active")
+)
+
+// RefreshToken is a long-lived credential used to obtain new access tokens.
+// Only the SHA-256 hash of the token is stored, the plain token is handed to the client once.
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	// SecondFactor is inherited by every token of the family
+	SecondFactor bool
+}
+
+// NewRefreshToken creates a refresh token for the user in the given token family
+// and returns it together with the plain token value
+func NewRefreshToken(userID, familyID string, lifetime time.Duration) (*RefreshToken, string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	now := time.Now()
+	return &RefreshToken{
+		ID:        uuidv7.New().String(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		TokenHash: HashRefreshToken(token),
+		ExpiresAt: now.Add(lifetime),
+		CreatedAt: now,
+	}, token, nil
+}
+
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *RefreshToken) IsRotated() bool {
+	return t.RotatedAt != nil
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
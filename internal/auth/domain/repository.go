@@ -2,13 +2,72 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
 type AuthRepository interface {
+	// Transaction runs fn in a transaction, the calls made with the context given to fn are part of it
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+
 	Create(ctx context.Context, user *AuthUser) error
 	FindUserByEmail(ctx context.Context, email string) (*AuthUser, error)
 	FindUserByUsername(ctx context.Context, username string) (*AuthUser, error)
 	FindUserByID(ctx context.Context, id string) (*AuthUser, error)
 	Update(ctx context.Context, user *AuthUser) error
+	// VerifyAccount stores the verified email and the status it activated
 	VerifyAccount(ctx context.Context, user *AuthUser) error
+	SetPendingEmail(ctx context.Context, user *AuthUser, email string) error
+	// ConfirmPendingEmail switches the email to the pending address if it is still the given one,
+	// it fails with ErrInvalidToken when it is not and with ErrDuplicateEntry when the address is taken,
+	// the status is stored as well since confirming the address activates a pending account
+	ConfirmPendingEmail(ctx context.Context, user *AuthUser, email string) error
+	// SoftDelete hides the user from the Find methods until the purge removes it
+	SoftDelete(ctx context.Context, user *AuthUser, deletedAt, purgeAfter time.Time) error
+	UpdateLastLogin(ctx context.Context, user *AuthUser) error
+
+	// admin
+	// ListUsers returns a page of the users matching the query and the number of matching users
+	ListUsers(ctx context.Context, query UserQuery) ([]*AuthUser, int, error)
+	// UpdateStatus stores the status of the user, its reason and timestamps
+	UpdateStatus(ctx context.Context, user *AuthUser) error
+
+	// refresh tokens
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	MarkRefreshTokenRotated(ctx context.Context, token *RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	IsRefreshTokenFamilyActive(ctx context.Context, familyID string) (bool, error)
+
+	// password history
+	// AddPasswordHistory records the hash once and keeps the newest entries of the user
+	AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error
+	// ListPasswordHistory returns the newest hashes first
+	ListPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error)
+
+	// auth events
+	// AppendAuthEvent records the event, an empty user ID is stored as NULL
+	AppendAuthEvent(ctx context.Context, event *AuthEvent) error
+	// ListAuthEvents returns the newest events of the user first
+	ListAuthEvents(ctx context.Context, userID string, limit, offset int) ([]*AuthEvent, error)
+}
+
+// UserQuery filters and paginates the users listed by the admin API
+type UserQuery struct {
+	// Search matches the email and the names, case-insensitively
+	Search string
+	// Status only lists the users with the status when set
+	Status AccountStatus
+	Limit  int
+	Offset int
+}
+
+type ApiKeyRepository interface {
+	Create(ctx context.Context, apiKey *ApiKey) error
+	FindByPrefix(ctx context.Context, prefix string) (*ApiKey, error)
+	FindByID(ctx context.Context, userID, id string) (*ApiKey, error)
+	ListByUser(ctx context.Context, userID string) ([]*ApiKey, error)
+	Update(ctx context.Context, apiKey *ApiKey) error
+	Revoke(ctx context.Context, apiKey *ApiKey) error
+	TouchLastUsed(ctx context.Context, apiKey *ApiKey) error
 }
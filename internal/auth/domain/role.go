@@ -0,0 +1,11 @@
+package domain
+
+// RoleAdmin is seeded by the migrations with every permission, more roles can be added to the roles table
+const RoleAdmin = "admin"
+
+// the permissions granted by the roles, a user has the union of the permissions of its roles
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionUsersDelete = "users:delete"
+)
@@ -2,59 +2,129 @@ package domain
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"go-template/internal/auth/config"
-	"go-template/internal/aws/sns"
-	appConfig "go-template/internal/config"
+	"go-template/internal/auth/domain/token"
 	"go-template/internal/shared/infrastructure/logger"
+	"go-template/internal/shared/infrastructure/outbox"
+	"go-template/internal/shared/requestinfo"
 	"time"
-
-	"github.com/golang-jwt/jwt"
 )
 
 var (
 	ErrInvalidToken        = fmt.Errorf("invalid token")
 	ErrTokenExpired        = fmt.Errorf("token expired")
+	ErrTokenAlreadyUsed    = fmt.Errorf("token already used")
 	ErrUserAlreadyVerified = fmt.Errorf("user already verified")
+	ErrInvalidPassword     = fmt.Errorf("invalid current password")
+	ErrSameEmail           = fmt.Errorf("new email is the current email")
 )
 
+// defaultPasswordResetExpirationTime is used when auth.password_reset_expiration_time is not configured (in seconds)
+const defaultPasswordResetExpirationTime = 60 * 60
+
+// defaultAccountDeletionGracePeriod is used when auth.account_deletion_grace_period is not configured (in seconds)
+const defaultAccountDeletionGracePeriod = 30 * 24 * 60 * 60
+
 type AuthService interface {
 	CreateUser(ctx context.Context, email, firstName, lastName, password string) (*AuthUser, error)
+	RegisterUser(ctx context.Context, email, firstName, lastName, password string) (*AuthUser, error)
 	CheckUserExists(ctx context.Context, email string) (bool, error)
 	UpdateUser(ctx context.Context, user *AuthUser) error
-	SendVerificationEmail(user *AuthUser) error
-	VerifyVerificationEmailToken(token, userId string) error
+	ValidatePassword(ctx context.Context, user *AuthUser, password string) error
+	ChangePassword(ctx context.Context, user *AuthUser, currentPassword, newPassword string) (bool, error)
+	SendVerificationEmail(ctx context.Context, user *AuthUser) error
+	VerifyVerificationEmailToken(ctx context.Context, token, userId string) error
 	VerifiedUserAccountStatus(ctx context.Context, userId string) error
+	GetUserByEmail(ctx context.Context, email string) (*AuthUser, error)
+	SendPasswordResetEmail(ctx context.Context, user *AuthUser) error
+	ResetPassword(ctx context.Context, resetToken, newPassword string) (*AuthUser, error)
+	SendPasswordChangedNotification(ctx context.Context, user *AuthUser) error
+	RequestEmailChange(ctx context.Context, user *AuthUser, newEmail string) error
+	ConfirmEmailChange(ctx context.Context, changeToken string) (*AuthUser, string, error)
+	SendEmailChangedNotification(ctx context.Context, user *AuthUser, oldEmail string) error
+	DeleteUser(ctx context.Context, user *AuthUser) (time.Time, error)
+	ChangeAccountStatus(ctx context.Context, user *AuthUser, status AccountStatus, reason string) error
+	SendAccountDeletedNotification(ctx context.Context, user *AuthUser, purgeAfter time.Time) error
+	RecordLogin(ctx context.Context, user *AuthUser) error
+	RecordEvent(ctx context.Context, event *AuthEvent) error
+	ListEvents(ctx context.Context, userID string, limit, offset int) ([]*AuthEvent, error)
 }
 
 type authService struct {
-	repository AuthRepository
-	logger     logger.Logger
-	authConfig *config.AuthConfig
-	snsModule  sns.SNSModule
+	repository   AuthRepository
+	logger       logger.Logger
+	authConfig   *config.AuthConfig
+	outbox       outbox.Outbox
+	tokenService *token.TokenService
+
+	passwordValidator *PasswordValidator
 }
 
-func NewAuthService(repo AuthRepository, logger logger.Logger, authConfig *config.AuthConfig, snsModule sns.SNSModule) AuthService {
+func NewAuthService(
+	repo AuthRepository,
+	logger logger.Logger,
+	authConfig *config.AuthConfig,
+	notificationOutbox outbox.Outbox,
+	tokenService *token.TokenService,
+	passwordValidator *PasswordValidator,
+) AuthService {
 	return &authService{
-		repository: repo,
-		logger:     logger,
-		authConfig: authConfig,
-		snsModule:  snsModule,
+		repository:        repo,
+		logger:            logger,
+		authConfig:        authConfig,
+		outbox:            notificationOutbox,
+		tokenService:      tokenService,
+		passwordValidator: passwordValidator,
 	}
 }
 
 func (s *authService) CreateUser(ctx context.Context, email, firstName, lastName, password string) (*AuthUser, error) {
+	err := s.ValidatePassword(ctx, &AuthUser{Email: email, FirstName: firstName, LastName: lastName}, password)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := NewAuthUser(email, firstName, lastName, password)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.repository.Create(ctx, user)
+	// the user is not kept without its password history
+	err = s.repository.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.Create(ctx, user); err != nil {
+			return err
+		}
+
+		user, err = s.repository.FindUserByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		return s.rememberPassword(ctx, user)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	user, err = s.repository.FindUserByEmail(ctx, email)
+	return user, nil
+}
+
+// RegisterUser creates the user and queues its verification email in one transaction, neither is kept when the other fails
+func (s *authService) RegisterUser(ctx context.Context, email, firstName, lastName, password string) (*AuthUser, error) {
+	var user *AuthUser
+	err := s.repository.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.CreateUser(ctx, email, firstName, lastName, password)
+		if err != nil {
+			return err
+		}
+
+		return s.SendVerificationEmail(ctx, user)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -76,24 +146,71 @@ func (s *authService) CheckUserExists(ctx context.Context, email string) (bool,
 	return false, nil
 }
 
+// UpdateUser stores the user, a changed password is added to the password history
 func (s *authService) UpdateUser(ctx context.Context, user *AuthUser) error {
-	return s.repository.Update(ctx, user)
+	if err := s.repository.Update(ctx, user); err != nil {
+		return err
+	}
+
+	return s.rememberPassword(ctx, user)
+}
+
+// ValidatePassword returns a PasswordPolicyError listing the violated rules
+func (s *authService) ValidatePassword(ctx context.Context, user *AuthUser, password string) error {
+	return s.passwordValidator.Validate(ctx, user, password)
+}
+
+/*
+ChangePassword sets the hash of a new password on the user, the caller stores the user
+- The current password has to match, ErrInvalidPassword otherwise
+- The new password is checked against the policy
+- It reports false and keeps the hash when the new password is the current one
+*/
+func (s *authService) ChangePassword(ctx context.Context, user *AuthUser, currentPassword, newPassword string) (bool, error) {
+	if !VerifyPassword(user, currentPassword) {
+		return false, ErrInvalidPassword
+	}
+
+	// checked before the policy, the reuse rule would reject the current password
+	if newPassword == currentPassword {
+		return false, nil
+	}
+
+	if err := s.ValidatePassword(ctx, user, newPassword); err != nil {
+		return false, err
+	}
+
+	if err := UpdatePassword(user, newPassword); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// rememberPassword keeps the current hash in the history the reuse check compares with
+func (s *authService) rememberPassword(ctx context.Context, user *AuthUser) error {
+	keep := s.passwordValidator.HistorySize()
+	if keep <= 0 {
+		return nil
+	}
+
+	return s.repository.AddPasswordHistory(ctx, user.ID, user.PasswordHash, keep)
 }
 
 /*
 Send Verification Email to the user after registration.
 - Generate a verification token
-- Send an email with the verification token
+- Queue an email with the verification token in the outbox, it joins the transaction of the context
   - The Email Service is be implemented in a micro service
   - The service will be called by Amazon Lambda
 */
-func (s *authService) SendVerificationEmail(user *AuthUser) error {
-	message, err := s.generateVerificationEmailMessage(user)
+func (s *authService) SendVerificationEmail(ctx context.Context, user *AuthUser) error {
+	message, err := s.generateVerificationEmailMessage(ctx, user)
 	if err != nil {
 		return err
 	}
 
-	return s.snsModule.PublishMessage(s.authConfig.Auth.VerificationEmailTopicArn, message)
+	return s.outbox.Enqueue(ctx, s.authConfig.Auth.VerificationEmailTopicArn, message)
 }
 
 /*
@@ -101,8 +218,8 @@ Construct the verification email message
 - The message will construct as JSON format
 - And Serialize the message to string
 */
-func (s *authService) generateVerificationEmailMessage(user *AuthUser) (string, error) {
-	token, err := s.generateVerificationEmailToken(user, s.authConfig.Auth.VerifyEmailExpirationTime)
+func (s *authService) generateVerificationEmailMessage(ctx context.Context, user *AuthUser) (string, error) {
+	token, err := s.generateVerificationEmailToken(ctx, user, s.authConfig.Auth.VerifyEmailExpirationTime)
 	if err != nil {
 		return "", err
 	}
@@ -119,40 +236,28 @@ func (s *authService) generateVerificationEmailMessage(user *AuthUser) (string,
 
 /*
 Generate a token for verifying email
-Using JWT to generate a token
+Using JWT to generate a single-use token with the verify email purpose
 */
-func (s *authService) generateVerificationEmailToken(user *AuthUser, expiredTime int) (string, error) {
-	expiredAt := time.Now().Add(time.Duration(expiredTime) * time.Second)
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expiredAt.Unix(),
-		Subject:   user.ID,
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(appConfig.App.SecretKey))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+func (s *authService) generateVerificationEmailToken(ctx context.Context, user *AuthUser, expiredTime int) (string, error) {
+	return s.tokenService.Issue(ctx, user.ID, token.PurposeVerifyEmail, time.Duration(expiredTime)*time.Second, nil)
 }
 
-func (s *authService) VerifyVerificationEmailToken(token, userId string) error {
-	claims := &jwt.StandardClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(appConfig.App.SecretKey), nil
-	})
+// VerifyVerificationEmailToken checks the token belongs to the user and consumes it
+func (s *authService) VerifyVerificationEmailToken(ctx context.Context, verificationToken, userId string) error {
+	claims, err := s.tokenService.Parse(verificationToken, token.PurposeVerifyEmail)
 	if err != nil {
-		if (err.(*jwt.ValidationError)).Errors == jwt.ValidationErrorExpired {
-			return ErrTokenExpired
-		}
-		return ErrInvalidToken
+		return mapTokenError(err)
 	}
 
+	// check the subject before consuming, so a mismatched link does not burn the token
 	if claims.Subject != userId {
 		return ErrInvalidToken
 	}
 
+	if _, err := s.tokenService.Consume(ctx, verificationToken, token.PurposeVerifyEmail); err != nil {
+		return mapTokenError(err)
+	}
+
 	return nil
 }
 
@@ -166,5 +271,350 @@ func (s *authService) VerifiedUserAccountStatus(ctx context.Context, userId stri
 		return ErrUserAlreadyVerified
 	}
 
+	user.MarkVerified()
 	return s.repository.VerifyAccount(ctx, user)
 }
+
+func (s *authService) GetUserByEmail(ctx context.Context, email string) (*AuthUser, error) {
+	return s.repository.FindUserByEmail(ctx, email)
+}
+
+/*
+Send Password Reset Email to the user
+- Generate a single-use password reset token bound to the current password
+- Publish the message to the password reset topic, the email is sent by the Lambda email worker
+*/
+func (s *authService) SendPasswordResetEmail(ctx context.Context, user *AuthUser) error {
+	expiredTime := s.authConfig.Auth.PasswordResetExpirationTime
+	if expiredTime <= 0 {
+		expiredTime = defaultPasswordResetExpirationTime
+	}
+
+	resetToken, err := s.tokenService.Issue(ctx, user.ID, token.PurposePasswordReset, time.Duration(expiredTime)*time.Second, &token.Claims{
+		PasswordFingerprint: passwordFingerprint(user),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.publishNotification(ctx, s.passwordResetTopicArn(), map[string]string{
+		"type":    "password_reset",
+		"to_name": user.FirstName,
+		"to_addr": user.Email,
+		"user_id": user.ID,
+		"token":   resetToken,
+	})
+}
+
+/*
+Reset the password with a password reset token
+- Consume the token, it cannot be used twice
+- Check the new password against the policy
+- Store the new password hash
+- Invalidate the other outstanding reset tokens and revoke all sessions
+*/
+func (s *authService) ResetPassword(ctx context.Context, resetToken, newPassword string) (*AuthUser, error) {
+	claims, err := s.tokenService.Parse(resetToken, token.PurposePasswordReset)
+	if err != nil {
+		return nil, mapTokenError(err)
+	}
+
+	user, err := s.repository.FindUserByID(ctx, claims.Subject)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// the password was changed after the token was issued
+	if claims.PasswordFingerprint != passwordFingerprint(user) {
+		return nil, ErrInvalidToken
+	}
+
+	// an invalid password does not burn the token, the user can try another one
+	if err := s.ValidatePassword(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+
+	if err := UpdatePassword(user, newPassword); err != nil {
+		return nil, err
+	}
+
+	// the token is only burnt when the password is stored
+	err = s.repository.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.tokenService.Consume(ctx, resetToken, token.PurposePasswordReset); err != nil {
+			return mapTokenError(err)
+		}
+
+		if err := s.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+
+		if err := s.tokenService.RevokeUserTokens(ctx, user.ID, token.PurposePasswordReset); err != nil {
+			return err
+		}
+
+		return s.repository.RevokeUserRefreshTokens(ctx, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *authService) SendPasswordChangedNotification(ctx context.Context, user *AuthUser) error {
+	return s.publishNotification(ctx, s.passwordResetTopicArn(), map[string]string{
+		"type":    "password_changed",
+		"to_name": user.FirstName,
+		"to_addr": user.Email,
+		"user_id": user.ID,
+	})
+}
+
+/*
+RequestEmailChange stores the new address as pending and sends it a verification token
+- The address must not be the current one or belong to another user
+- A new request replaces the pending address and invalidates the earlier tokens
+- The message is published to the verification email topic, addressed to the new address
+- The pending address, the token and the message are stored in one transaction, a failure leaves the earlier request in place
+*/
+func (s *authService) RequestEmailChange(ctx context.Context, user *AuthUser, newEmail string) error {
+	if newEmail == user.Email {
+		return ErrSameEmail
+	}
+
+	exists, err := s.CheckUserExists(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrUserAlreadyExists
+	}
+
+	return s.repository.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.tokenService.RevokeUserTokens(ctx, user.ID, token.PurposeChangeEmail); err != nil {
+			return err
+		}
+
+		if err := s.repository.SetPendingEmail(ctx, user, newEmail); err != nil {
+			return err
+		}
+
+		changeToken, err := s.tokenService.Issue(ctx, user.ID, token.PurposeChangeEmail, time.Duration(s.authConfig.Auth.VerifyEmailExpirationTime)*time.Second, &token.Claims{
+			Email: newEmail,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.publishNotification(ctx, s.authConfig.Auth.VerificationEmailTopicArn, map[string]string{
+			"type":    "email_change",
+			"to_name": user.FirstName,
+			"to_addr": newEmail,
+			"user_id": user.ID,
+			"token":   changeToken,
+		})
+	})
+}
+
+/*
+ConfirmEmailChange switches the email of the user to the address of the token
+- The token must be for the current pending address, a newer request invalidates it
+- The new address is verified by the confirmation
+- The user is returned with the old address, to notify it
+*/
+func (s *authService) ConfirmEmailChange(ctx context.Context, changeToken string) (*AuthUser, string, error) {
+	claims, err := s.tokenService.Parse(changeToken, token.PurposeChangeEmail)
+	if err != nil {
+		return nil, "", mapTokenError(err)
+	}
+
+	user, err := s.repository.FindUserByID(ctx, claims.Subject)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return nil, "", ErrInvalidToken
+		}
+		return nil, "", err
+	}
+
+	if user.PendingEmail == nil || *user.PendingEmail != claims.Email {
+		return nil, "", ErrInvalidToken
+	}
+
+	// the address may have been taken since the request, the token is kept then
+	exists, err := s.CheckUserExists(ctx, claims.Email)
+	if err != nil {
+		return nil, "", err
+	}
+	if exists {
+		return nil, "", ErrDuplicateEntry
+	}
+
+	if _, err := s.tokenService.Consume(ctx, changeToken, token.PurposeChangeEmail); err != nil {
+		return nil, "", mapTokenError(err)
+	}
+
+	// the new address is verified by the confirmation
+	oldEmail := user.Email
+	user.MarkVerified()
+	if err := s.repository.ConfirmPendingEmail(ctx, user, claims.Email); err != nil {
+		return nil, "", err
+	}
+
+	return user, oldEmail, nil
+}
+
+// SendEmailChangedNotification tells the old address about the change, in case it was not the owner
+func (s *authService) SendEmailChangedNotification(ctx context.Context, user *AuthUser, oldEmail string) error {
+	return s.publishNotification(ctx, s.authConfig.Auth.VerificationEmailTopicArn, map[string]string{
+		"type":      "email_changed",
+		"to_name":   user.FirstName,
+		"to_addr":   oldEmail,
+		"user_id":   user.ID,
+		"new_email": user.Email,
+	})
+}
+
+/*
+DeleteUser soft deletes the user and returns when the account will be purged
+- The user cannot authenticate anymore, every lookup skips deleted users
+- The sessions are revoked right away, in the same transaction as the deletion
+- The files and rows are removed by the purge of the user module after the grace period
+*/
+func (s *authService) DeleteUser(ctx context.Context, user *AuthUser) (time.Time, error) {
+	gracePeriod := s.authConfig.Auth.AccountDeletionGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultAccountDeletionGracePeriod
+	}
+
+	now := time.Now()
+	purgeAfter := now.Add(time.Duration(gracePeriod) * time.Second)
+
+	err := s.repository.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.SoftDelete(ctx, user, now, purgeAfter); err != nil {
+			return err
+		}
+
+		return s.repository.RevokeUserRefreshTokens(ctx, user.ID)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return purgeAfter, nil
+}
+
+/*
+ChangeAccountStatus moves the account along its lifecycle
+- A suspended or deactivated user loses its sessions and every outstanding one-time token, in the same transaction as the status
+- The api keys are kept, they are refused while the account cannot authenticate
+*/
+func (s *authService) ChangeAccountStatus(ctx context.Context, user *AuthUser, status AccountStatus, reason string) error {
+	if err := user.ChangeStatus(status, reason); err != nil {
+		return err
+	}
+
+	return s.repository.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.UpdateStatus(ctx, user); err != nil {
+			return err
+		}
+
+		if user.CheckAuthentication() == nil {
+			return nil
+		}
+
+		if err := s.repository.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+			return err
+		}
+
+		for _, purpose := range []token.Purpose{token.PurposeVerifyEmail, token.PurposePasswordReset, token.PurposeChangeEmail, token.PurposeSecondFactor} {
+			if err := s.tokenService.RevokeUserTokens(ctx, user.ID, purpose); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *authService) SendAccountDeletedNotification(ctx context.Context, user *AuthUser, purgeAfter time.Time) error {
+	return s.publishNotification(ctx, s.authConfig.Auth.VerificationEmailTopicArn, map[string]string{
+		"type":        "account_deleted",
+		"to_name":     user.FirstName,
+		"to_addr":     user.Email,
+		"user_id":     user.ID,
+		"purge_after": purgeAfter.UTC().Format(time.RFC3339),
+	})
+}
+
+// RecordLogin stores when the user last started a session
+func (s *authService) RecordLogin(ctx context.Context, user *AuthUser) error {
+	user.UpdateLastLogin()
+	return s.repository.UpdateLastLogin(ctx, user)
+}
+
+// RecordEvent appends the event to the audit trail with the IP, user agent and ID of the request in the context,
+// an event without user ID is linked to the live account of its email if there is one
+func (s *authService) RecordEvent(ctx context.Context, event *AuthEvent) error {
+	info := requestinfo.FromContext(ctx)
+	event.IP = info.ClientIP
+	event.UserAgent = truncate(info.UserAgent, maxUserAgentLength)
+	event.RequestID = info.RequestID
+
+	// a failed login only knows the email it was aimed at, the event goes to the activity of that account
+	if event.UserID == "" && event.Email != "" {
+		user, err := s.repository.FindUserByEmail(ctx, event.Email)
+		if err != nil && err != ErrUserNotFound {
+			return err
+		}
+		if user != nil {
+			event.UserID = user.ID
+		}
+	}
+
+	return s.repository.AppendAuthEvent(ctx, event)
+}
+
+func (s *authService) ListEvents(ctx context.Context, userID string, limit, offset int) ([]*AuthEvent, error) {
+	return s.repository.ListAuthEvents(ctx, userID, limit, offset)
+}
+
+func (s *authService) passwordResetTopicArn() string {
+	if s.authConfig.Auth.PasswordResetTopicArn != "" {
+		return s.authConfig.Auth.PasswordResetTopicArn
+	}
+
+	return s.authConfig.Auth.VerificationEmailTopicArn
+}
+
+// publishNotification queues the payload in the outbox, the dispatcher publishes it once the transaction of the context commits
+func (s *authService) publishNotification(ctx context.Context, topicArn string, payload map[string]string) error {
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return s.outbox.Enqueue(ctx, topicArn, string(message))
+}
+
+// passwordFingerprint is a short digest of the password hash, it changes whenever the password does
+func passwordFingerprint(user *AuthUser) string {
+	hash := sha256.Sum256([]byte(user.PasswordHash))
+	return hex.EncodeToString(hash[:8])
+}
+
+// mapTokenError translates the token errors to the errors of the auth domain
+func mapTokenError(err error) error {
+	switch err {
+	case token.ErrTokenExpired:
+		return ErrTokenExpired
+	case token.ErrTokenAlreadyUsed:
+		return ErrTokenAlreadyUsed
+	case token.ErrInvalidToken:
+		return ErrInvalidToken
+	}
+
+	return err
+}
@@ -0,0 +1,635 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-template/internal/auth/config"
+	"go-template/internal/auth/domain/token"
+	"go-template/internal/shared/requestinfo"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLogger struct {
+	mock.Mock
+}
+
+func (m *MockLogger) Info(args ...interface{})  {}
+func (m *MockLogger) Error(args ...interface{}) {}
+func (m *MockLogger) Debug(args ...interface{}) {}
+func (m *MockLogger) Warn(args ...interface{})  {}
+
+// MockOutbox records the queued messages, it fails with err when it is set
+type MockOutbox struct {
+	messages []string
+	err      error
+}
+
+func (m *MockOutbox) Enqueue(ctx context.Context, topic, payload string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, payload)
+	return nil
+}
+
+// fakeAuthRepository keeps a single user in memory
+type fakeAuthRepository struct {
+	AuthRepository
+	user    *AuthUser
+	revoked bool
+	history []string
+	// takenEmails belong to other users
+	takenEmails []string
+	purgeAfter  time.Time
+	events      []*AuthEvent
+	// historyErr fails AddPasswordHistory when it is set
+	historyErr error
+	// revokeErr fails RevokeUserRefreshTokens when it is set
+	revokeErr error
+}
+
+func (r *fakeAuthRepository) FindUserByID(ctx context.Context, id string) (*AuthUser, error) {
+	if r.user.ID != id {
+		return nil, ErrUserNotFound
+	}
+	copied := *r.user
+	return &copied, nil
+}
+
+func (r *fakeAuthRepository) FindUserByEmail(ctx context.Context, email string) (*AuthUser, error) {
+	if r.user.Email == email {
+		copied := *r.user
+		return &copied, nil
+	}
+	if contains(r.takenEmails, email) {
+		return &AuthUser{Email: email}, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+// Transaction keeps the changes to the user, its history and its sessions only when fn succeeds
+func (r *fakeAuthRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	user, history, revoked, purgeAfter := *r.user, r.history, r.revoked, r.purgeAfter
+	if err := fn(ctx); err != nil {
+		r.user, r.history, r.revoked, r.purgeAfter = &user, history, revoked, purgeAfter
+		return err
+	}
+	return nil
+}
+
+func (r *fakeAuthRepository) Create(ctx context.Context, user *AuthUser) error {
+	copied := *user
+	r.user = &copied
+	return nil
+}
+
+func (r *fakeAuthRepository) SetPendingEmail(ctx context.Context, user *AuthUser, email string) error {
+	user.PendingEmail = &email
+	r.user.PendingEmail = &email
+	return nil
+}
+
+func (r *fakeAuthRepository) ConfirmPendingEmail(ctx context.Context, user *AuthUser, email string) error {
+	if r.user.PendingEmail == nil || *r.user.PendingEmail != email {
+		return ErrInvalidToken
+	}
+	r.user.Email = email
+	r.user.PendingEmail = nil
+	user.Email = email
+	user.PendingEmail = nil
+	return nil
+}
+
+func (r *fakeAuthRepository) Update(ctx context.Context, user *AuthUser) error {
+	copied := *user
+	r.user = &copied
+	return nil
+}
+
+func (r *fakeAuthRepository) AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error {
+	if r.historyErr != nil {
+		return r.historyErr
+	}
+	if !contains(r.history, passwordHash) {
+		r.history = append([]string{passwordHash}, r.history...)
+	}
+	r.history = r.history[:min(len(r.history), keep)]
+	return nil
+}
+
+func (r *fakeAuthRepository) ListPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error) {
+	return r.history[:min(len(r.history), limit)], nil
+}
+
+func (r *fakeAuthRepository) SoftDelete(ctx context.Context, user *AuthUser, deletedAt, purgeAfter time.Time) error {
+	if !r.purgeAfter.IsZero() {
+		return ErrUserNotFound
+	}
+	r.purgeAfter = purgeAfter
+	return nil
+}
+
+func (r *fakeAuthRepository) UpdateStatus(ctx context.Context, user *AuthUser) error {
+	copied := *user
+	r.user = &copied
+	return nil
+}
+
+func (r *fakeAuthRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	if r.revokeErr != nil {
+		return r.revokeErr
+	}
+	r.revoked = true
+	return nil
+}
+
+func (r *fakeAuthRepository) UpdateLastLogin(ctx context.Context, user *AuthUser) error {
+	r.user.LastLoginAt = user.LastLoginAt
+	return nil
+}
+
+func (r *fakeAuthRepository) AppendAuthEvent(ctx context.Context, event *AuthEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// fakeTokenRepository records the consumed token ids and the revoked purposes
+type fakeTokenRepository struct {
+	issued   map[string]bool
+	consumed map[string]bool
+	revoked  []token.Purpose
+}
+
+func (r *fakeTokenRepository) Create(ctx context.Context, record *token.Record) error {
+	r.issued[record.ID] = true
+	return nil
+}
+
+func (r *fakeTokenRepository) Consume(ctx context.Context, id string) error {
+	if !r.issued[id] {
+		return token.ErrTokenNotFound
+	}
+	if r.consumed[id] {
+		return token.ErrTokenAlreadyUsed
+	}
+	r.consumed[id] = true
+	return nil
+}
+
+func (r *fakeTokenRepository) RevokeByUser(ctx context.Context, userID string, purpose token.Purpose) error {
+	r.revoked = append(r.revoked, purpose)
+	return nil
+}
+
+func newTestAuthService(repository AuthRepository, notificationOutbox *MockOutbox) *authService {
+	tokenRepository := &fakeTokenRepository{issued: map[string]bool{}, consumed: map[string]bool{}}
+	tokenService := token.NewTokenService(tokenRepository, token.NewKeyring("test-secret-key"))
+	passwordValidator := NewPasswordValidator(DefaultPasswordPolicy(), repository, nil)
+	return NewAuthService(repository, new(MockLogger), &config.AuthConfig{}, notificationOutbox, tokenService, passwordValidator).(*authService)
+}
+
+func TestVerificationEmailToken(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	service := newTestAuthService(&fakeAuthRepository{user: mockUser}, &MockOutbox{})
+
+	ctx := context.Background()
+
+	verificationToken, err := service.generateVerificationEmailToken(ctx, mockUser, 60)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	// a link for another user does not burn the token
+	if err := service.VerifyVerificationEmailToken(ctx, verificationToken, "other-user"); err != ErrInvalidToken {
+		t.Errorf("Error should be invalid token, got %v", err)
+	}
+
+	if err := service.VerifyVerificationEmailToken(ctx, verificationToken, mockUser.ID); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if err := service.VerifyVerificationEmailToken(ctx, verificationToken, mockUser.ID); err != ErrTokenAlreadyUsed {
+		t.Errorf("Error should be token already used, got %v", err)
+	}
+
+	expiredToken, _ := service.tokenService.Issue(ctx, mockUser.ID, token.PurposeVerifyEmail, -time.Minute, nil)
+	if err := service.VerifyVerificationEmailToken(ctx, expiredToken, mockUser.ID); err != ErrTokenExpired {
+		t.Errorf("Error should be token expired, got %v", err)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	historyErr := errors.New("history unavailable")
+	repository := &fakeAuthRepository{user: &AuthUser{}, historyErr: historyErr}
+	service := newTestAuthService(repository, &MockOutbox{})
+
+	if _, err := service.CreateUser(context.Background(), "test@example.com", "First", "Last", "iampassword"); err != historyErr {
+		t.Errorf("Error should be %v, got %v", historyErr, err)
+	}
+
+	// the user is rolled back with its password history
+	if repository.user.Email != "" {
+		t.Errorf("User should not be stored, got %v", repository.user)
+	}
+}
+
+func TestRegisterUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Test verification email is queued", func(t *testing.T) {
+		repository := &fakeAuthRepository{user: &AuthUser{}}
+		notificationOutbox := &MockOutbox{}
+		service := newTestAuthService(repository, notificationOutbox)
+
+		user, err := service.RegisterUser(ctx, "test@example.com", "First", "Last", "iampassword")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if repository.user.Email != user.Email {
+			t.Errorf("User should be stored, got %v", repository.user)
+		}
+
+		var message map[string]string
+		if len(notificationOutbox.messages) != 1 || json.Unmarshal([]byte(notificationOutbox.messages[0]), &message) != nil {
+			t.Fatalf("Verification email should be queued, got %v", notificationOutbox.messages)
+		}
+		if message["to_addr"] != "test@example.com" || message["token"] == "" {
+			t.Errorf("Message should be a verification email for test@example.com, got %v", message)
+		}
+	})
+
+	t.Run("Test user is rolled back with the verification email", func(t *testing.T) {
+		outboxErr := errors.New("outbox unavailable")
+		repository := &fakeAuthRepository{user: &AuthUser{}}
+		service := newTestAuthService(repository, &MockOutbox{err: outboxErr})
+
+		if _, err := service.RegisterUser(ctx, "test@example.com", "First", "Last", "iampassword"); err != outboxErr {
+			t.Errorf("Error should be %v, got %v", outboxErr, err)
+		}
+
+		if repository.user.Email != "" || len(repository.history) != 0 {
+			t.Errorf("User should not be stored, got %v", repository.user)
+		}
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	repository := &fakeAuthRepository{user: mockUser}
+	notificationOutbox := &MockOutbox{}
+	service := newTestAuthService(repository, notificationOutbox)
+
+	ctx := context.Background()
+
+	if err := service.SendPasswordResetEmail(ctx, mockUser); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	var message map[string]string
+	if err := json.Unmarshal([]byte(notificationOutbox.messages[0]), &message); err != nil {
+		t.Fatalf("Message should be JSON, got %v", err)
+	}
+
+	if message["type"] != "password_reset" || message["to_addr"] != mockUser.Email {
+		t.Errorf("Message should be a password reset email for %s, got %v", mockUser.Email, message)
+	}
+
+	resetToken := message["token"]
+
+	t.Run("Test token purpose", func(t *testing.T) {
+		if err := service.VerifyVerificationEmailToken(ctx, resetToken, mockUser.ID); err != ErrInvalidToken {
+			t.Errorf("Password reset token should not verify an email, got %v", err)
+		}
+
+		verificationToken, _ := service.generateVerificationEmailToken(ctx, mockUser, 60)
+		if _, err := service.ResetPassword(ctx, verificationToken, "newpassword"); err != ErrInvalidToken {
+			t.Errorf("Verification token should not reset a password, got %v", err)
+		}
+	})
+
+	t.Run("Test ResetPassword", func(t *testing.T) {
+		if _, err := service.ResetPassword(ctx, resetToken, "newpassword"); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if !VerifyPassword(repository.user, "newpassword") {
+			t.Errorf("Password should be updated")
+		}
+
+		if !repository.revoked {
+			t.Errorf("Sessions should be revoked")
+		}
+
+		// the token is bound to the old password
+		if _, err := service.ResetPassword(ctx, resetToken, "otherpassword"); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test single use", func(t *testing.T) {
+		repository.user = mockUser
+		if err := service.SendPasswordResetEmail(ctx, mockUser); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		var message map[string]string
+		json.Unmarshal([]byte(notificationOutbox.messages[len(notificationOutbox.messages)-1]), &message)
+
+		// "newpassword" is in the history now
+		if _, err := service.ResetPassword(ctx, message["token"], "newpassword"); !isPolicyViolation(err, PasswordRuleReused) {
+			t.Errorf("Error should be a reused password violation, got %v", err)
+		}
+
+		if _, err := service.ResetPassword(ctx, message["token"], "freshpassword"); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		// restore the password the token is bound to, the token is still refused
+		repository.user = mockUser
+		if _, err := service.ResetPassword(ctx, message["token"], "otherpassword"); err != ErrTokenAlreadyUsed {
+			t.Errorf("Error should be token already used, got %v", err)
+		}
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	repository := &fakeAuthRepository{user: mockUser}
+	service := newTestAuthService(repository, &MockOutbox{})
+
+	ctx := context.Background()
+	passwordHash := mockUser.PasswordHash
+
+	if _, err := service.ChangePassword(ctx, mockUser, "wrongpassword", "newpassword"); err != ErrInvalidPassword {
+		t.Errorf("Error should be invalid password, got %v", err)
+	}
+
+	if _, err := service.ChangePassword(ctx, mockUser, "iampassword", "short"); !isPolicyViolation(err, PasswordRuleMinLength) {
+		t.Errorf("Error should be a min length violation, got %v", err)
+	}
+
+	if mockUser.PasswordHash != passwordHash {
+		t.Errorf("Password hash should not change after a refused change")
+	}
+
+	// the current password is in the history, resending it is not a reuse
+	repository.history = []string{mockUser.PasswordHash}
+	if changed, err := service.ChangePassword(ctx, mockUser, "iampassword", "iampassword"); err != nil || changed {
+		t.Errorf("Unchanged password should be reported as not changed, got %v (%v)", changed, err)
+	}
+
+	changed, err := service.ChangePassword(ctx, mockUser, "iampassword", "newpassword")
+	if err != nil || !changed {
+		t.Fatalf("Password should be changed, got %v", err)
+	}
+
+	if !VerifyPassword(mockUser, "newpassword") {
+		t.Errorf("Password hash should match the new password")
+	}
+
+	// the names alone do not re-hash the password
+	passwordHash = mockUser.PasswordHash
+	mockUser.Update("New", "Name")
+
+	if mockUser.PasswordHash != passwordHash {
+		t.Errorf("Password hash should not change when the password does not")
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	mockUser, _ := NewAuthUser("old@example.com", "First", "Last", "iampassword")
+	repository := &fakeAuthRepository{user: mockUser, takenEmails: []string{"taken@example.com"}}
+	notificationOutbox := &MockOutbox{}
+	service := newTestAuthService(repository, notificationOutbox)
+
+	ctx := context.Background()
+
+	lastMessage := func() map[string]string {
+		var message map[string]string
+		json.Unmarshal([]byte(notificationOutbox.messages[len(notificationOutbox.messages)-1]), &message)
+		return message
+	}
+
+	if err := service.RequestEmailChange(ctx, mockUser, "old@example.com"); err != ErrSameEmail {
+		t.Errorf("Error should be same email, got %v", err)
+	}
+
+	if err := service.RequestEmailChange(ctx, mockUser, "taken@example.com"); err != ErrUserAlreadyExists {
+		t.Errorf("Error should be user already exists, got %v", err)
+	}
+
+	if err := service.RequestEmailChange(ctx, mockUser, "first@example.com"); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	firstToken := lastMessage()["token"]
+
+	if err := service.RequestEmailChange(ctx, mockUser, "new@example.com"); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	message := lastMessage()
+	if message["type"] != "email_change" || message["to_addr"] != "new@example.com" {
+		t.Errorf("Message should be sent to the new address, got %v", message)
+	}
+
+	if repository.user.Email != "old@example.com" {
+		t.Errorf("Email should not change before the confirmation, got %s", repository.user.Email)
+	}
+
+	// the earlier request was replaced
+	if _, _, err := service.ConfirmEmailChange(ctx, firstToken); err != ErrInvalidToken {
+		t.Errorf("Error should be invalid token, got %v", err)
+	}
+
+	user, oldEmail, err := service.ConfirmEmailChange(ctx, message["token"])
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if user.Email != "new@example.com" || oldEmail != "old@example.com" {
+		t.Errorf("Email should change from old@example.com to new@example.com, got %s to %s", oldEmail, user.Email)
+	}
+
+	if _, _, err := service.ConfirmEmailChange(ctx, message["token"]); err != ErrInvalidToken {
+		t.Errorf("Error should be invalid token, got %v", err)
+	}
+
+	if err := service.SendEmailChangedNotification(ctx, user, oldEmail); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if message := lastMessage(); message["type"] != "email_changed" || message["to_addr"] != "old@example.com" {
+		t.Errorf("Notification should be sent to the old address, got %v", message)
+	}
+
+	// the pending address is only stored with its message
+	notificationOutbox.err = errors.New("outbox unavailable")
+	if err := service.RequestEmailChange(ctx, user, "other@example.com"); err == nil {
+		t.Errorf("Error should not be nil")
+	}
+
+	if repository.user.PendingEmail != nil {
+		t.Errorf("Pending email should be rolled back, got %s", *repository.user.PendingEmail)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	repository := &fakeAuthRepository{user: mockUser}
+	notificationOutbox := &MockOutbox{}
+	service := newTestAuthService(repository, notificationOutbox)
+
+	ctx := context.Background()
+
+	// the account is only deleted with its sessions
+	repository.revokeErr = errors.New("database error")
+	if _, err := service.DeleteUser(ctx, mockUser); err == nil {
+		t.Errorf("Error should not be nil")
+	}
+
+	if !repository.purgeAfter.IsZero() {
+		t.Errorf("Deletion should be rolled back, got purge after %v", repository.purgeAfter)
+	}
+	repository.revokeErr = nil
+
+	purgeAfter, err := service.DeleteUser(ctx, mockUser)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	// the default grace period applies when none is configured
+	expected := time.Now().Add(defaultAccountDeletionGracePeriod * time.Second)
+	if purgeAfter.Before(expected.Add(-time.Minute)) || purgeAfter.After(expected) {
+		t.Errorf("Purge should be scheduled around %v, got %v", expected, purgeAfter)
+	}
+
+	if !repository.revoked {
+		t.Errorf("Refresh tokens should be revoked")
+	}
+
+	if _, err := service.DeleteUser(ctx, mockUser); err != ErrUserNotFound {
+		t.Errorf("Error should be user not found, got %v", err)
+	}
+
+	if err := service.SendAccountDeletedNotification(ctx, mockUser, purgeAfter); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	var message map[string]string
+	if err := json.Unmarshal([]byte(notificationOutbox.messages[len(notificationOutbox.messages)-1]), &message); err != nil {
+		t.Fatalf("Message should be JSON, got %v", err)
+	}
+
+	if message["type"] != "account_deleted" || message["purge_after"] != purgeAfter.UTC().Format(time.RFC3339) {
+		t.Errorf("Message should announce the purge date, got %v", message)
+	}
+}
+
+func TestChangeAccountStatus(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	mockUser.MarkVerified()
+	repository := &fakeAuthRepository{user: mockUser}
+	service := newTestAuthService(repository, &MockOutbox{})
+	tokenRepository := &fakeTokenRepository{issued: map[string]bool{}, consumed: map[string]bool{}}
+	service.tokenService = token.NewTokenService(tokenRepository, token.NewKeyring("test-secret-key"))
+
+	ctx := context.Background()
+
+	// the status is only stored with the revocations
+	repository.revokeErr = errors.New("database error")
+	user := *mockUser
+	if err := service.ChangeAccountStatus(ctx, &user, AccountStatusSuspended, "chargeback"); err == nil {
+		t.Errorf("Error should not be nil")
+	}
+
+	if repository.user.Status == AccountStatusSuspended {
+		t.Errorf("Status should be rolled back, got %s", repository.user.Status)
+	}
+	repository.revokeErr = nil
+
+	if err := service.ChangeAccountStatus(ctx, mockUser, AccountStatusSuspended, "chargeback"); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if repository.user.Status != AccountStatusSuspended || repository.user.StatusReason != "chargeback" {
+		t.Errorf("Status should be stored, got %s %s", repository.user.Status, repository.user.StatusReason)
+	}
+
+	// the sessions and the one-time tokens are revoked
+	if !repository.revoked || len(tokenRepository.revoked) == 0 {
+		t.Errorf("Sessions and tokens should be revoked, got %v and %v", repository.revoked, tokenRepository.revoked)
+	}
+
+	repository.revoked = false
+	if err := service.ChangeAccountStatus(ctx, mockUser, AccountStatusActive, ""); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if repository.revoked {
+		t.Errorf("Sessions should not be revoked on activation")
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	repository := &fakeAuthRepository{user: mockUser}
+	service := newTestAuthService(repository, &MockOutbox{})
+
+	ctx := requestinfo.NewContext(context.Background(), requestinfo.Info{
+		RequestID: "req-1",
+		ClientIP:  "203.0.113.7",
+		UserAgent: strings.Repeat("a", maxUserAgentLength+10),
+	})
+
+	if err := service.RecordEvent(ctx, NewAuthEvent(AuthEventLoginFailed, "", mockUser.Email, "invalid credentials")); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if len(repository.events) != 1 {
+		t.Fatalf("Event should be appended, got %d events", len(repository.events))
+	}
+
+	// the event carries the request it was recorded in
+	event := repository.events[0]
+	if event.RequestID != "req-1" || event.IP != "203.0.113.7" {
+		t.Errorf("Request should be recorded, got %s %s", event.RequestID, event.IP)
+	}
+
+	if len(event.UserAgent) != maxUserAgentLength {
+		t.Errorf("User agent should be truncated to %d, got %d", maxUserAgentLength, len(event.UserAgent))
+	}
+
+	// the failed login is linked to the account of its email
+	if event.UserID != mockUser.ID {
+		t.Errorf("User ID should be %s, got %q", mockUser.ID, event.UserID)
+	}
+
+	if err := service.RecordEvent(ctx, NewAuthEvent(AuthEventLoginFailed, "", "unknown@example.com", "invalid credentials")); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if event := repository.events[1]; event.UserID != "" {
+		t.Errorf("User ID should be empty for an unknown email, got %q", event.UserID)
+	}
+}
+
+func TestRecordLogin(t *testing.T) {
+	mockUser, _ := NewAuthUser("test@example.com", "First", "Last", "iampassword")
+	repository := &fakeAuthRepository{user: mockUser}
+	service := newTestAuthService(repository, &MockOutbox{})
+
+	user := *mockUser
+	if err := service.RecordLogin(context.Background(), &user); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if user.LastLoginAt == nil || repository.user.LastLoginAt == nil {
+		t.Errorf("Last login should be stored")
+	}
+}
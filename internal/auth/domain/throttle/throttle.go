@@ -0,0 +1,220 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindAccount Kind = "account"
+	KindIP      Kind = "ip"
+)
+
+// Subject is what the failed attempts are counted for, an account or a client IP
+type Subject struct {
+	Kind  Kind
+	Value string
+}
+
+func Account(email string) Subject {
+	return Subject{Kind: KindAccount, Value: strings.ToLower(strings.TrimSpace(email))}
+}
+
+func IP(ip string) Subject {
+	return Subject{Kind: KindIP, Value: ip}
+}
+
+func (s Subject) Key() string {
+	return string(s.Kind) + ":" + s.Value
+}
+
+// Attempts is the failure count of a subject within the current window
+type Attempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+type Store interface {
+	// Get returns nil when the key has no failures
+	Get(ctx context.Context, key string) (*Attempts, error)
+	// RecordFailure increments the failures of the key, a failure after the window starts the count over
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*Attempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// ThrottledError is returned while a subject is backing off or locked out
+type ThrottledError struct {
+	Subject    Subject
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("%s is locked out for %s", e.Subject.Kind, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s is throttled for %s", e.Subject.Kind, e.RetryAfter)
+}
+
+// Lockout is reported when a failure locks a subject out
+type Lockout struct {
+	Subject  Subject
+	Failures int
+	Until    time.Time
+}
+
+type Policy struct {
+	// FreeAttempts is the number of failures before the backoff starts
+	FreeAttempts int
+	// BaseDelay doubles with every failure after the free attempts, up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	MaxAccountFailures int
+	MaxIPFailures      int
+	LockoutDuration    time.Duration
+
+	// Window is how long a failure is remembered after the last one
+	Window time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		FreeAttempts:       3,
+		BaseDelay:          time.Second,
+		MaxDelay:           5 * time.Minute,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      100,
+		LockoutDuration:    15 * time.Minute,
+		Window:             15 * time.Minute,
+	}
+}
+
+func (p Policy) maxFailures(kind Kind) int {
+	if kind == KindIP {
+		return p.MaxIPFailures
+	}
+	return p.MaxAccountFailures
+}
+
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewGuard(store Store, policy Policy) *Guard {
+	return &Guard{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+/*
+Check returns a ThrottledError when one of the subjects may not attempt yet
+- A locked out subject waits until the end of the lockout
+- After the free attempts, every failure doubles the wait since the last failure
+- The longest wait of all subjects is returned
+*/
+func (g *Guard) Check(ctx context.Context, subjects ...Subject) error {
+	now := g.now()
+
+	var throttled *ThrottledError
+	for _, subject := range subjects {
+		attempts, err := g.store.Get(ctx, subject.Key())
+		if err != nil {
+			return err
+		}
+
+		retryAfter, locked := g.blockedFor(attempts, now)
+		if retryAfter <= 0 {
+			continue
+		}
+
+		if throttled == nil || retryAfter > throttled.RetryAfter {
+			throttled = &ThrottledError{Subject: subject, RetryAfter: retryAfter, Locked: locked}
+		}
+	}
+
+	if throttled != nil {
+		return throttled
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed attempt for every subject and returns the lockouts it caused
+func (g *Guard) RecordFailure(ctx context.Context, subjects ...Subject) ([]Lockout, error) {
+	now := g.now()
+
+	var lockouts []Lockout
+	for _, subject := range subjects {
+		attempts, err := g.store.RecordFailure(ctx, subject.Key(), now, g.policy.Window)
+		if err != nil {
+			return lockouts, err
+		}
+
+		maxFailures := g.policy.maxFailures(subject.Kind)
+		if maxFailures <= 0 || attempts.Failures < maxFailures {
+			continue
+		}
+		if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			continue
+		}
+
+		until := now.Add(g.policy.LockoutDuration)
+		if err := g.store.Lock(ctx, subject.Key(), until); err != nil {
+			return lockouts, err
+		}
+
+		lockouts = append(lockouts, Lockout{Subject: subject, Failures: attempts.Failures, Until: until})
+	}
+
+	return lockouts, nil
+}
+
+// RecordSuccess forgets the failures of the subjects
+func (g *Guard) RecordSuccess(ctx context.Context, subjects ...Subject) error {
+	for _, subject := range subjects {
+		if err := g.store.Reset(ctx, subject.Key()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *Guard) blockedFor(attempts *Attempts, now time.Time) (time.Duration, bool) {
+	if attempts == nil {
+		return 0, false
+	}
+
+	if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+		return attempts.LockedUntil.Sub(now), true
+	}
+
+	if now.Sub(attempts.LastFailureAt) > g.policy.Window {
+		return 0, false
+	}
+
+	exponent := attempts.Failures - g.policy.FreeAttempts
+	if exponent <= 0 {
+		return 0, false
+	}
+
+	delay := g.policy.BaseDelay
+	for i := 1; i < exponent && delay < g.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, g.policy.MaxDelay)
+
+	return attempts.LastFailureAt.Add(delay).Sub(now), false
+}
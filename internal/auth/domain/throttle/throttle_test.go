@@ -0,0 +1,122 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeStore keeps the attempts in a map
+type fakeStore struct {
+	attempts map[string]*Attempts
+}
+
+func (s *fakeStore) Get(ctx context.Context, key string) (*Attempts, error) {
+	return s.attempts[key], nil
+}
+
+func (s *fakeStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*Attempts, error) {
+	attempts, ok := s.attempts[key]
+	if !ok || now.Sub(attempts.LastFailureAt) > window {
+		attempts = &Attempts{Key: key}
+		s.attempts[key] = attempts
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	copied := *attempts
+	return &copied, nil
+}
+
+func (s *fakeStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.attempts[key].LockedUntil = &until
+	return nil
+}
+
+func (s *fakeStore) Reset(ctx context.Context, key string) error {
+	delete(s.attempts, key)
+	return nil
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	policy := DefaultPolicy()
+	guard := NewGuard(&fakeStore{attempts: map[string]*Attempts{}}, policy)
+	guard.now = func() time.Time { return now }
+
+	account := Account(" Test@Example.com ")
+	ip := IP("10.0.0.1")
+
+	retryAfter := func(subjects ...Subject) time.Duration {
+		var throttled *ThrottledError
+		if err := guard.Check(ctx, subjects...); errors.As(err, &throttled) {
+			return throttled.RetryAfter
+		}
+		return 0
+	}
+
+	t.Run("Test backoff", func(t *testing.T) {
+		for i := 0; i < policy.FreeAttempts; i++ {
+			guard.RecordFailure(ctx, account, ip)
+		}
+		if delay := retryAfter(account, ip); delay != 0 {
+			t.Errorf("Free attempts should not be throttled, got %v", delay)
+		}
+
+		// every failure after the free attempts doubles the delay
+		for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			guard.RecordFailure(ctx, account, ip)
+			if delay := retryAfter(account); delay != expected {
+				t.Errorf("Delay should be %v, got %v", expected, delay)
+			}
+		}
+
+		now = now.Add(4 * time.Second)
+		if delay := retryAfter(account, ip); delay != 0 {
+			t.Errorf("Delay should be over, got %v", delay)
+		}
+	})
+
+	t.Run("Test lockout", func(t *testing.T) {
+		var lockouts []Lockout
+		for i := policy.FreeAttempts + 3; i < policy.MaxAccountFailures; i++ {
+			lockouts, _ = guard.RecordFailure(ctx, account, ip)
+		}
+
+		if len(lockouts) != 1 || lockouts[0].Subject != account {
+			t.Fatalf("Account should be locked out, got %v", lockouts)
+		}
+
+		var throttled *ThrottledError
+		if err := guard.Check(ctx, ip, account); !errors.As(err, &throttled) || !throttled.Locked || throttled.RetryAfter != policy.LockoutDuration {
+			t.Errorf("Error should be a lockout of %v, got %v", policy.LockoutDuration, err)
+		}
+
+		// the IP has a higher threshold
+		if lockouts, _ := guard.RecordFailure(ctx, ip); len(lockouts) != 0 {
+			t.Errorf("IP should not be locked out, got %v", lockouts)
+		}
+	})
+
+	t.Run("Test RecordSuccess", func(t *testing.T) {
+		guard.RecordSuccess(ctx, Account("test@example.com"))
+
+		if delay := retryAfter(account); delay != 0 {
+			t.Errorf("Account should not be throttled after a success, got %v", delay)
+		}
+
+		if delay := retryAfter(ip); delay == 0 {
+			t.Errorf("IP should still be throttled")
+		}
+	})
+
+	t.Run("Test window", func(t *testing.T) {
+		now = now.Add(policy.Window + time.Second)
+
+		if delay := retryAfter(ip); delay != 0 {
+			t.Errorf("Failures outside the window should be forgotten, got %v", delay)
+		}
+	})
+}
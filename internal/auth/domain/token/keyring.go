@@ -0,0 +1,264 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	appConfig "go-template/internal/config"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is a key identified by the kid header of the tokens it signs
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signingKey is nil for a verify-only key
+	signingKey      interface{}
+	verificationKey interface{}
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.signingKey != nil
+}
+
+// PublicKey returns the verification key of an asymmetric key, nil for a HMAC secret
+func (k *SigningKey) PublicKey() interface{} {
+	switch k.verificationKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return k.verificationKey
+	}
+
+	return nil
+}
+
+/*
+Keyring holds the key used to sign new tokens and the keys that are still accepted for verification.
+Rotating a key means adding the new key as active and keeping the old one as verify-only,
+the tokens already sent out keep working until they expire.
+*/
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// order keeps the keys in the order they were configured
+	order []*SigningKey
+	// legacySecrets verify the tokens issued before the tokens carried a kid
+	legacySecrets [][]byte
+}
+
+// NewKeyring builds a HMAC keyring from the secret key and the previous secret keys
+func NewKeyring(activeSecret string, previousSecrets ...string) *Keyring {
+	keyring := &Keyring{keys: make(map[string]*SigningKey)}
+	keyring.add(newHMACKey("", activeSecret))
+	keyring.active = keyring.order[0]
+
+	for _, secret := range previousSecrets {
+		if secret == "" {
+			continue
+		}
+		keyring.add(newHMACKey("", secret))
+	}
+
+	keyring.SetLegacySecrets(append([]string{activeSecret}, previousSecrets...)...)
+	return keyring
+}
+
+// SetLegacySecrets sets the secrets that signed the tokens issued without a kid
+func (k *Keyring) SetLegacySecrets(secrets ...string) {
+	k.legacySecrets = nil
+	for _, secret := range secrets {
+		if secret != "" {
+			k.legacySecrets = append(k.legacySecrets, []byte(secret))
+		}
+	}
+}
+
+/*
+NewKeyringFromConfig builds the keyring from the keyring config
+- Exactly one key must be active and able to sign
+- Asymmetric keys without a private key are verify-only
+- The kid is derived from the key material when it is not set
+*/
+func NewKeyringFromConfig(configs []appConfig.SigningKeyConfig) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]*SigningKey)}
+
+	for _, keyConfig := range configs {
+		key, err := newSigningKeyFromConfig(keyConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := keyring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate kid %q in keyring", key.ID)
+		}
+		keyring.add(key)
+
+		if !keyConfig.Active {
+			continue
+		}
+		if keyring.active != nil {
+			return nil, fmt.Errorf("keyring has more than one active key")
+		}
+		if !key.CanSign() {
+			return nil, fmt.Errorf("active key %q has no private key", key.ID)
+		}
+		keyring.active = key
+	}
+
+	if keyring.active == nil {
+		return nil, fmt.Errorf("keyring has no active key")
+	}
+
+	return keyring, nil
+}
+
+func (k *Keyring) Active() *SigningKey {
+	return k.active
+}
+
+func (k *Keyring) Find(id string) (*SigningKey, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// Keys returns every key of the keyring, the active key included
+func (k *Keyring) Keys() []*SigningKey {
+	return k.order
+}
+
+func (k *Keyring) add(key *SigningKey) {
+	k.keys[key.ID] = key
+	k.order = append(k.order, key)
+}
+
+func newSigningKeyFromConfig(keyConfig appConfig.SigningKeyConfig) (*SigningKey, error) {
+	switch keyConfig.Algorithm {
+	case AlgorithmHS256, "":
+		if keyConfig.Secret == "" {
+			return nil, fmt.Errorf("key %q has no secret", keyConfig.ID)
+		}
+		return newHMACKey(keyConfig.ID, keyConfig.Secret), nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+		return newAsymmetricKey(keyConfig)
+	default:
+		return nil, fmt.Errorf("key %q has unsupported algorithm %q", keyConfig.ID, keyConfig.Algorithm)
+	}
+}
+
+func newHMACKey(id, secret string) *SigningKey {
+	if id == "" {
+		id = deriveKeyID([]byte(secret))
+	}
+
+	return &SigningKey{
+		ID:              id,
+		Method:          jwt.SigningMethodHS256,
+		signingKey:      []byte(secret),
+		verificationKey: []byte(secret),
+	}
+}
+
+func newAsymmetricKey(keyConfig appConfig.SigningKeyConfig) (*SigningKey, error) {
+	privatePEM, err := readPEM(keyConfig.PrivateKey, keyConfig.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+	}
+	publicPEM, err := readPEM(keyConfig.PublicKey, keyConfig.PublicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+	}
+	if privatePEM == nil && publicPEM == nil {
+		return nil, fmt.Errorf("key %q has neither a private nor a public key", keyConfig.ID)
+	}
+
+	key := &SigningKey{ID: keyConfig.ID}
+
+	// the public key is derived from the private key when both are given
+	if keyConfig.Algorithm == AlgorithmRS256 {
+		key.Method = jwt.SigningMethodRS256
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+			}
+			key.signingKey = privateKey
+			key.verificationKey = &privateKey.PublicKey
+		} else {
+			publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+			}
+			key.verificationKey = publicKey
+		}
+	} else {
+		key.Method = jwt.SigningMethodEdDSA
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+			}
+			edKey, ok := privateKey.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not an Ed25519 private key", keyConfig.ID)
+			}
+			key.signingKey = edKey
+			key.verificationKey = edKey.Public().(ed25519.PublicKey)
+		} else {
+			publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+			}
+			edKey, ok := publicKey.(ed25519.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not an Ed25519 public key", keyConfig.ID)
+			}
+			key.verificationKey = edKey
+		}
+	}
+
+	// derived from the public key, the kid stays the same once the key becomes verify-only
+	if key.ID == "" {
+		der, err := x509.MarshalPKIXPublicKey(key.verificationKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+		}
+		key.ID = deriveKeyID(der)
+	}
+
+	return key, nil
+}
+
+func readPEM(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+
+	return os.ReadFile(file)
+}
+
+// deriveKeyID hashes the key material, so the same key always gets the same kid
+func deriveKeyID(material []byte) string {
+	hash := sha256.Sum256(material)
+	return hex.EncodeToString(hash[:8])
+}
@@ -0,0 +1,149 @@
+package token
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	appConfig "go-template/internal/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func generateRSAKey(t *testing.T) (privatePEM, publicPEM string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	publicDER, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+}
+
+func generateEd25519Key(t *testing.T) (privatePEM, publicPEM string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDER, _ := x509.MarshalPKIXPublicKey(publicKey)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+}
+
+func TestKeyringFromConfig(t *testing.T) {
+	ctx := context.Background()
+	repository := newFakeRepository()
+
+	rsaPrivate, rsaPublic := generateRSAKey(t)
+	edPrivate, edPublic := generateEd25519Key(t)
+
+	t.Run("Test asymmetric algorithms", func(t *testing.T) {
+		for _, keyConfig := range []appConfig.SigningKeyConfig{
+			{Algorithm: AlgorithmRS256, PrivateKey: rsaPrivate, Active: true},
+			{Algorithm: AlgorithmEdDSA, PrivateKey: edPrivate, Active: true},
+		} {
+			keyring, err := NewKeyringFromConfig([]appConfig.SigningKeyConfig{keyConfig})
+			if err != nil {
+				t.Fatalf("Error should be nil, got %v", err)
+			}
+
+			tokenService := NewTokenService(repository, keyring)
+			tokenString, _ := tokenService.Issue(ctx, "user-id", PurposeAccess, time.Minute, nil)
+
+			parsed, _, _ := new(jwt.Parser).ParseUnverified(tokenString, &Claims{})
+			if parsed.Method.Alg() != keyConfig.Algorithm {
+				t.Errorf("Token should be signed with %s, got %s", keyConfig.Algorithm, parsed.Method.Alg())
+			}
+
+			if _, err := tokenService.Parse(tokenString, PurposeAccess); err != nil {
+				t.Errorf("Error should be nil, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Test rotation", func(t *testing.T) {
+		oldKeyring, _ := NewKeyringFromConfig([]appConfig.SigningKeyConfig{
+			{Algorithm: AlgorithmRS256, PrivateKey: rsaPrivate, Active: true},
+		})
+		tokenString, _ := NewTokenService(repository, oldKeyring).Issue(ctx, "user-id", PurposeAccess, time.Minute, nil)
+
+		// the old key is kept as verify-only with its public key
+		keyring, err := NewKeyringFromConfig([]appConfig.SigningKeyConfig{
+			{Algorithm: AlgorithmEdDSA, PrivateKey: edPrivate, Active: true},
+			{Algorithm: AlgorithmRS256, PublicKey: rsaPublic},
+		})
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if _, err := NewTokenService(repository, keyring).Parse(tokenString, PurposeAccess); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+	})
+
+	t.Run("Test algorithm confusion", func(t *testing.T) {
+		keyring, _ := NewKeyringFromConfig([]appConfig.SigningKeyConfig{
+			{Algorithm: AlgorithmRS256, PrivateKey: rsaPrivate, Active: true},
+		})
+
+		// a HS256 token signed with the published public key must be refused
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			StandardClaims: jwt.StandardClaims{
+				Audience:  string(PurposeAccess),
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+				Id:        "jti",
+				Subject:   "user-id",
+			},
+		})
+		forged.Header["kid"] = keyring.Active().ID
+		tokenString, _ := forged.SignedString([]byte(rsaPublic))
+
+		if _, err := NewTokenService(repository, keyring).Parse(tokenString, PurposeAccess); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test JWKS", func(t *testing.T) {
+		keyring, _ := NewKeyringFromConfig([]appConfig.SigningKeyConfig{
+			{ID: "hmac", Secret: "test-secret-key"},
+			{ID: "rsa", Algorithm: AlgorithmRS256, PrivateKey: rsaPrivate},
+			{ID: "ed", Algorithm: AlgorithmEdDSA, PublicKey: edPublic, Active: false},
+			{ID: "active", Algorithm: AlgorithmEdDSA, PrivateKey: edPrivate, Active: true},
+		})
+
+		jwks := keyring.JWKS()
+		if len(jwks.Keys) != 3 {
+			t.Fatalf("JWKS should publish the 3 asymmetric keys, got %d", len(jwks.Keys))
+		}
+
+		if jwks.Keys[0].Kid != "rsa" || jwks.Keys[0].Kty != "RSA" || jwks.Keys[0].N == "" || jwks.Keys[0].E != "AQAB" {
+			t.Errorf("RSA key should be published, got %+v", jwks.Keys[0])
+		}
+
+		if jwks.Keys[1].Kid != "ed" || jwks.Keys[1].Kty != "OKP" || jwks.Keys[1].Crv != "Ed25519" || jwks.Keys[1].X == "" {
+			t.Errorf("Ed25519 key should be published, got %+v", jwks.Keys[1])
+		}
+	})
+
+	t.Run("Test invalid config", func(t *testing.T) {
+		for name, configs := range map[string][]appConfig.SigningKeyConfig{
+			"no active key":         {{Secret: "test-secret-key"}},
+			"two active keys":       {{Secret: "first", Active: true}, {Secret: "second", Active: true}},
+			"verify-only active":    {{Algorithm: AlgorithmRS256, PublicKey: rsaPublic, Active: true}},
+			"duplicate kid":         {{ID: "key", Secret: "first", Active: true}, {ID: "key", Secret: "second"}},
+			"unsupported algorithm": {{Algorithm: "none", Active: true}},
+			"missing key material":  {{Algorithm: AlgorithmEdDSA, Active: true}},
+		} {
+			if _, err := NewKeyringFromConfig(configs); err == nil {
+				t.Errorf("Error should not be nil for %s", name)
+			}
+		}
+	})
+}
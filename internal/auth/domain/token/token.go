@@ -0,0 +1,247 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/samborkent/uuidv7"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenAlreadyUsed = errors.New("token already used")
+	ErrTokenNotFound    = errors.New("token not found")
+)
+
+// Purpose is stored in the audience claim, a token is only accepted for the purpose it was issued for
+type Purpose string
+
+const (
+	PurposeAccess        Purpose = "access"
+	PurposeVerifyEmail   Purpose = "verify_email"
+	PurposePasswordReset Purpose = "password_reset"
+	PurposeSecondFactor  Purpose = "second_factor"
+	PurposeChangeEmail   Purpose = "change_email"
+)
+
+// legacyPurposes are the purposes of the links sent before the tokens carried a kid, they are accepted until they expire
+var legacyPurposes = map[Purpose]bool{
+	PurposeVerifyEmail:   true,
+	PurposePasswordReset: true,
+}
+
+// singleUse reports whether tokens of the purpose are recorded and consumed on first use
+func (p Purpose) singleUse() bool {
+	return p != PurposeAccess
+}
+
+type Claims struct {
+	jwt.StandardClaims
+	// SessionID is the refresh token family an access token belongs to
+	SessionID string `json:"sid,omitempty"`
+	// SecondFactor marks an access token of a session started with a completed second factor
+	SecondFactor bool `json:"mfa,omitempty"`
+	// PasswordFingerprint binds a password reset token to the password it replaces
+	PasswordFingerprint string `json:"pwd,omitempty"`
+	// Email is the new address an email change token confirms
+	Email string `json:"email,omitempty"`
+	// LegacyPurpose is the purpose claim of the tokens issued before the audience and kid were introduced
+	LegacyPurpose string `json:"purpose,omitempty"`
+}
+
+func (c *Claims) Purpose() Purpose {
+	return Purpose(c.Audience)
+}
+
+// Record is the server-side trace of a single-use token
+type Record struct {
+	ID         string
+	UserID     string
+	Purpose    Purpose
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	ConsumedAt *time.Time
+}
+
+type Repository interface {
+	Create(ctx context.Context, record *Record) error
+	// Consume marks the token as used, it fails with ErrTokenAlreadyUsed if it was used before
+	Consume(ctx context.Context, id string) error
+	// RevokeByUser consumes every outstanding token of the purpose issued to the user
+	RevokeByUser(ctx context.Context, userID string, purpose Purpose) error
+}
+
+type TokenService struct {
+	repository Repository
+	keyring    *Keyring
+}
+
+func NewTokenService(repository Repository, keyring *Keyring) *TokenService {
+	return &TokenService{
+		repository: repository,
+		keyring:    keyring,
+	}
+}
+
+/*
+Issue signs a token for the subject
+- The purpose is set as the audience and a unique id as jti
+- The active key of the keyring signs it and the kid header names the key
+- Single-use tokens are recorded so they can be consumed later
+*/
+func (s *TokenService) Issue(ctx context.Context, subject string, purpose Purpose, lifetime time.Duration, claims *Claims) (string, error) {
+	if claims == nil {
+		claims = &Claims{}
+	}
+
+	now := time.Now()
+	claims.Id = uuidv7.New().String()
+	claims.Audience = string(purpose)
+	claims.Subject = subject
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(lifetime).Unix()
+
+	key := s.keyring.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.signingKey)
+	if err != nil {
+		return "", err
+	}
+
+	if purpose.singleUse() {
+		err = s.repository.Create(ctx, &Record{
+			ID:        claims.Id,
+			UserID:    subject,
+			Purpose:   purpose,
+			ExpiresAt: now.Add(lifetime),
+			CreatedAt: now,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return tokenString, nil
+}
+
+// Parse validates the signature, expiration and purpose of the token without consuming it
+func (s *TokenService) Parse(tokenString string, purpose Purpose) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errLegacyToken
+		}
+
+		key, err := s.keyring.Find(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// the algorithm is fixed by the key, never by the token header
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+
+		return key.verificationKey, nil
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && errors.Is(validationErr.Inner, errLegacyToken) {
+			return s.parseLegacy(tokenString, purpose)
+		}
+		return nil, mapValidationError(err)
+	}
+
+	if claims.Purpose() != purpose || claims.Subject == "" || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+var errLegacyToken = errors.New("token has no kid")
+
+/*
+parseLegacy accepts the links sent before the tokens carried a kid
+- They are signed with HS256 by one of the legacy secrets of the keyring
+- The purpose claim names their purpose, a verification link has none
+- They have no jti, they are not recorded and cannot be consumed, they stop working when they expire
+*/
+func (s *TokenService) parseLegacy(tokenString string, purpose Purpose) (*Claims, error) {
+	if !legacyPurposes[purpose] {
+		return nil, ErrInvalidToken
+	}
+
+	err := error(ErrInvalidToken)
+	for _, secret := range s.keyring.legacySecrets {
+		claims := &Claims{}
+		_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+				return nil, ErrInvalidToken
+			}
+			return secret, nil
+		})
+		if err != nil {
+			continue
+		}
+
+		legacyPurpose := Purpose(claims.LegacyPurpose)
+		if legacyPurpose == "" {
+			legacyPurpose = PurposeVerifyEmail
+		}
+		if legacyPurpose != purpose || claims.Audience != "" || claims.Id != "" || claims.Subject == "" || claims.ExpiresAt == 0 {
+			return nil, ErrInvalidToken
+		}
+
+		claims.Audience = string(purpose)
+		return claims, nil
+	}
+
+	return nil, mapValidationError(err)
+}
+
+// mapValidationError keeps the expiration apart, every other failure is an invalid token
+func mapValidationError(err error) error {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired {
+		return ErrTokenExpired
+	}
+	return ErrInvalidToken
+}
+
+// Consume parses the token and marks it as used, a second call with the same token fails
+func (s *TokenService) Consume(ctx context.Context, tokenString string, purpose Purpose) (*Claims, error) {
+	claims, err := s.Parse(tokenString, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !purpose.singleUse() {
+		return nil, ErrInvalidToken
+	}
+
+	// a legacy token has no record to consume
+	if claims.Id == "" {
+		return claims, nil
+	}
+
+	if err := s.repository.Consume(ctx, claims.Id); err != nil {
+		if err == ErrTokenNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// RevokeUserTokens invalidates every outstanding token of the purpose issued to the user
+func (s *TokenService) RevokeUserTokens(ctx context.Context, userID string, purpose Purpose) error {
+	return s.repository.RevokeByUser(ctx, userID, purpose)
+}
@@ -0,0 +1,192 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// fakeRepository keeps the issued tokens in memory
+type fakeRepository struct {
+	records map[string]*Record
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{records: make(map[string]*Record)}
+}
+
+func (r *fakeRepository) Create(ctx context.Context, record *Record) error {
+	r.records[record.ID] = record
+	return nil
+}
+
+func (r *fakeRepository) Consume(ctx context.Context, id string) error {
+	record, ok := r.records[id]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	if record.ConsumedAt != nil {
+		return ErrTokenAlreadyUsed
+	}
+	now := time.Now()
+	record.ConsumedAt = &now
+	return nil
+}
+
+func (r *fakeRepository) RevokeByUser(ctx context.Context, userID string, purpose Purpose) error {
+	now := time.Now()
+	for _, record := range r.records {
+		if record.UserID == userID && record.Purpose == purpose && record.ConsumedAt == nil {
+			record.ConsumedAt = &now
+		}
+	}
+	return nil
+}
+
+func TestTokenService(t *testing.T) {
+	ctx := context.Background()
+	repository := newFakeRepository()
+	tokenService := NewTokenService(repository, NewKeyring("test-secret-key"))
+
+	t.Run("Test purpose", func(t *testing.T) {
+		tokenString, err := tokenService.Issue(ctx, "user-id", PurposeVerifyEmail, time.Minute, nil)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		claims, err := tokenService.Parse(tokenString, PurposeVerifyEmail)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if claims.Subject != "user-id" || claims.Id == "" {
+			t.Errorf("Claims should carry the subject and a jti, got %+v", claims)
+		}
+
+		if _, err := tokenService.Parse(tokenString, PurposePasswordReset); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+
+		if _, err := tokenService.Parse(tokenString, PurposeAccess); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test single use", func(t *testing.T) {
+		tokenString, _ := tokenService.Issue(ctx, "user-id", PurposePasswordReset, time.Minute, nil)
+
+		if _, err := tokenService.Consume(ctx, tokenString, PurposePasswordReset); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if _, err := tokenService.Consume(ctx, tokenString, PurposePasswordReset); err != ErrTokenAlreadyUsed {
+			t.Errorf("Error should be token already used, got %v", err)
+		}
+
+		// access tokens are not recorded and cannot be consumed
+		accessToken, _ := tokenService.Issue(ctx, "user-id", PurposeAccess, time.Minute, nil)
+		if len(repository.records) != 2 {
+			t.Errorf("Access tokens should not be recorded, got %d records", len(repository.records))
+		}
+		if _, err := tokenService.Consume(ctx, accessToken, PurposeAccess); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test RevokeUserTokens", func(t *testing.T) {
+		first, _ := tokenService.Issue(ctx, "other-user", PurposePasswordReset, time.Minute, nil)
+		second, _ := tokenService.Issue(ctx, "other-user", PurposePasswordReset, time.Minute, nil)
+
+		if err := tokenService.RevokeUserTokens(ctx, "other-user", PurposePasswordReset); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		for _, tokenString := range []string{first, second} {
+			if _, err := tokenService.Consume(ctx, tokenString, PurposePasswordReset); err != ErrTokenAlreadyUsed {
+				t.Errorf("Error should be token already used, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Test expired token", func(t *testing.T) {
+		tokenString, _ := tokenService.Issue(ctx, "user-id", PurposeVerifyEmail, -time.Minute, nil)
+
+		if _, err := tokenService.Parse(tokenString, PurposeVerifyEmail); err != ErrTokenExpired {
+			t.Errorf("Error should be token expired, got %v", err)
+		}
+	})
+
+	t.Run("Test key rotation", func(t *testing.T) {
+		tokenString, _ := tokenService.Issue(ctx, "user-id", PurposeVerifyEmail, time.Minute, nil)
+
+		// the old secret is still accepted after the rotation
+		rotated := NewTokenService(repository, NewKeyring("new-secret-key", "test-secret-key"))
+		if _, err := rotated.Parse(tokenString, PurposeVerifyEmail); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		// until it is removed from the previous keys
+		removed := NewTokenService(repository, NewKeyring("new-secret-key"))
+		if _, err := removed.Parse(tokenString, PurposeVerifyEmail); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test token without kid", func(t *testing.T) {
+		tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			StandardClaims: jwt.StandardClaims{
+				Audience:  string(PurposeVerifyEmail),
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+				Id:        "jti",
+				Subject:   "user-id",
+			},
+		}).SignedString([]byte("test-secret-key"))
+
+		if _, err := tokenService.Parse(tokenString, PurposeVerifyEmail); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+	})
+
+	t.Run("Test legacy links", func(t *testing.T) {
+		legacyToken := func(secret, purpose string, lifetime time.Duration) string {
+			tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+				StandardClaims: jwt.StandardClaims{
+					ExpiresAt: time.Now().Add(lifetime).Unix(),
+					Subject:   "user-id",
+				},
+				LegacyPurpose: purpose,
+			}).SignedString([]byte(secret))
+			return tokenString
+		}
+
+		// the verification links had no purpose
+		verificationToken := legacyToken("test-secret-key", "", time.Minute)
+		claims, err := tokenService.Consume(ctx, verificationToken, PurposeVerifyEmail)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if claims.Subject != "user-id" || claims.Purpose() != PurposeVerifyEmail {
+			t.Errorf("Claims should carry the subject and the purpose, got %+v", claims)
+		}
+		if _, err := tokenService.Parse(verificationToken, PurposePasswordReset); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+
+		resetToken := legacyToken("test-secret-key", string(PurposePasswordReset), time.Minute)
+		if _, err := tokenService.Parse(resetToken, PurposePasswordReset); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+
+		if _, err := tokenService.Parse(legacyToken("test-secret-key", "", -time.Minute), PurposeVerifyEmail); err != ErrTokenExpired {
+			t.Errorf("Error should be token expired, got %v", err)
+		}
+		if _, err := tokenService.Parse(legacyToken("other-secret-key", "", time.Minute), PurposeVerifyEmail); err != ErrInvalidToken {
+			t.Errorf("Error should be invalid token, got %v", err)
+		}
+		if _, err := tokenService.Parse(legacyToken("test-secret-key", string(PurposeAccess), time.Minute), PurposeAccess); err != ErrInvalidToken {
+			t.Errorf("Legacy tokens should not grant access, got %v", err)
+		}
+	})
+}
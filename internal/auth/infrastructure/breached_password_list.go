@@ -0,0 +1,70 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go-template/internal/auth/domain"
+	"os"
+	"strings"
+)
+
+const sha1HexLength = 40
+
+// breachedPasswordList is a local copy of breached SHA-1 hashes indexed by the 5 character range prefix
+type breachedPasswordList struct {
+	ranges map[string][]string
+}
+
+/*
+LoadBreachedPasswordList reads a list in the format of the Pwned Passwords downloads
+- One uppercase or lowercase SHA-1 hash per line, optionally followed by ":<count>"
+- Empty lines and lines starting with # are ignored
+*/
+func LoadBreachedPasswordList(path string) (domain.BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &breachedPasswordList{ranges: map[string][]string{}}
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if !isSHA1Hex(hash) {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of %s", lineNumber, path)
+		}
+
+		list.ranges[hash[:5]] = append(list.ranges[hash[:5]], hash[5:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (l *breachedPasswordList) Range(ctx context.Context, prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
+
+func isSHA1Hex(hash string) bool {
+	if len(hash) != sha1HexLength {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+
+	return true
+}
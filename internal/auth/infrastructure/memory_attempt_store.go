@@ -0,0 +1,87 @@
+package infrastructure
+
+import (
+	"context"
+	"go-template/internal/auth/domain/throttle"
+	"sync"
+	"time"
+)
+
+// memorySweepThreshold is the number of keys above which stale entries are swept on write
+const memorySweepThreshold = 10000
+
+// memoryAttemptStore keeps the attempts in process memory, it is only consistent on a single node
+type memoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*throttle.Attempts
+}
+
+func NewMemoryAttemptStore() throttle.Store {
+	return &memoryAttemptStore{attempts: make(map[string]*throttle.Attempts)}
+}
+
+func (s *memoryAttemptStore) Get(ctx context.Context, key string) (*throttle.Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *attempts
+	return &copied, nil
+}
+
+func (s *memoryAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*throttle.Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.attempts) > memorySweepThreshold {
+		s.sweep(now, window)
+	}
+
+	attempts, ok := s.attempts[key]
+	if !ok || now.Sub(attempts.LastFailureAt) > window {
+		attempts = &throttle.Attempts{Key: key}
+		s.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailureAt = now
+
+	copied := *attempts
+	return &copied, nil
+}
+
+func (s *memoryAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempts, ok := s.attempts[key]; ok {
+		attempts.LockedUntil = &until
+	}
+
+	return nil
+}
+
+func (s *memoryAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// sweep drops the keys whose failures are outside the window and whose lockout is over
+func (s *memoryAttemptStore) sweep(now time.Time, window time.Duration) {
+	for key, attempts := range s.attempts {
+		if now.Sub(attempts.LastFailureAt) <= window {
+			continue
+		}
+		if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			continue
+		}
+		delete(s.attempts, key)
+	}
+}
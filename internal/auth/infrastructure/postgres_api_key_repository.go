@@ -0,0 +1,159 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-template/internal/auth/domain"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresApiKeyRepository struct {
+	db database.BaseDatabase
+}
+
+func NewPostgresApiKeyRepository(db database.BaseDatabase) domain.ApiKeyRepository {
+	return &postgresApiKeyRepository{db: db}
+}
+
+// lastUsedResolution is how stale the last use of an API key can be
+const lastUsedResolution = time.Minute
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, expires_at, revoked_at`
+
+func (r *postgresApiKeyRepository) Create(ctx context.Context, apiKey *domain.ApiKey) error {
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query,
+		apiKey.ID,
+		apiKey.UserID,
+		apiKey.Name,
+		apiKey.Prefix,
+		apiKey.KeyHash,
+		pq.Array(apiKey.Scopes),
+		apiKey.CreatedAt,
+		apiKey.ExpiresAt,
+	)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresApiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*domain.ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+	return r.scanApiKey(r.db.QueryRowContext(ctx, query, prefix))
+}
+
+func (r *postgresApiKeyRepository) FindByID(ctx context.Context, userID, id string) (*domain.ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	return r.scanApiKey(r.db.QueryRowContext(ctx, query, id, userID))
+}
+
+func (r *postgresApiKeyRepository) ListByUser(ctx context.Context, userID string) ([]*domain.ApiKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, database.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	apiKeys := []*domain.ApiKey{}
+	for rows.Next() {
+		apiKey, err := r.scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, database.ErrDatabaseError
+	}
+
+	return apiKeys, nil
+}
+
+func (r *postgresApiKeyRepository) Update(ctx context.Context, apiKey *domain.ApiKey) error {
+	query := `UPDATE api_keys SET name = $2, scopes = $3 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, apiKey.ID, apiKey.Name, pq.Array(apiKey.Scopes))
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresApiKeyRepository) Revoke(ctx context.Context, apiKey *domain.ApiKey) error {
+	query := `UPDATE api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, apiKey.ID, time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// TouchLastUsed is called on every authenticated request, the row is written at most once per lastUsedResolution
+func (r *postgresApiKeyRepository) TouchLastUsed(ctx context.Context, apiKey *domain.ApiKey) error {
+	now := time.Now()
+
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)`
+	result, err := r.db.ExecContext(ctx, query, apiKey.ID, now, now.Add(-lastUsedResolution))
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if affected > 0 {
+		apiKey.LastUsedAt = &now
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *postgresApiKeyRepository) scanApiKey(row rowScanner) (*domain.ApiKey, error) {
+	var apiKey domain.ApiKey
+	var lastUsedAt, expiresAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&apiKey.ID,
+		&apiKey.UserID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.CreatedAt,
+		&lastUsedAt,
+		&expiresAt,
+		&revokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrApiKeyNotFound
+		}
+		return nil, database.ErrDatabaseError
+	}
+
+	apiKey.LastUsedAt = nullTimePtr(lastUsedAt)
+	apiKey.ExpiresAt = nullTimePtr(expiresAt)
+	apiKey.RevokedAt = nullTimePtr(revokedAt)
+
+	return &apiKey, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,80 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-template/internal/auth/domain/throttle"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+)
+
+// postgresAttemptStore shares the attempts between the nodes behind the load balancer
+type postgresAttemptStore struct {
+	db database.BaseDatabase
+}
+
+func NewPostgresAttemptStore(db database.BaseDatabase) throttle.Store {
+	return &postgresAttemptStore{db: db}
+}
+
+func (s *postgresAttemptStore) Get(ctx context.Context, key string) (*throttle.Attempts, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM auth_attempts WHERE key = $1`
+
+	attempts, err := scanAttempts(s.db.QueryRowContext(ctx, query, key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, database.ErrDatabaseError
+	}
+
+	return attempts, nil
+}
+
+// RecordFailure increments the count in a single statement, concurrent failures are all counted
+func (s *postgresAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*throttle.Attempts, error) {
+	query := `INSERT INTO auth_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN auth_attempts.last_failure_at < $3 THEN 1 ELSE auth_attempts.failures + 1 END,
+			locked_until = CASE WHEN auth_attempts.last_failure_at < $3 THEN NULL ELSE auth_attempts.locked_until END,
+			last_failure_at = $2
+		RETURNING key, failures, last_failure_at, locked_until`
+
+	attempts, err := scanAttempts(s.db.QueryRowContext(ctx, query, key, now, now.Add(-window)))
+	if err != nil {
+		return nil, database.ErrDatabaseError
+	}
+
+	return attempts, nil
+}
+
+func (s *postgresAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE auth_attempts SET locked_until = $2 WHERE key = $1`
+	if _, err := s.db.ExecContext(ctx, query, key, until); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (s *postgresAttemptStore) Reset(ctx context.Context, key string) error {
+	query := `DELETE FROM auth_attempts WHERE key = $1`
+	if _, err := s.db.ExecContext(ctx, query, key); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func scanAttempts(row rowScanner) (*throttle.Attempts, error) {
+	var attempts throttle.Attempts
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+
+	attempts.LockedUntil = nullTimePtr(lockedUntil)
+
+	return &attempts, nil
+}
@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"go-template/internal/auth/domain"
+	"go-template/internal/shared/infrastructure/database"
+)
+
+func (r *postgresAuthRepository) AppendAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	query := `
+		INSERT INTO auth_events (id, user_id, email, type, detail, ip, user_agent, request_id, created_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID,
+		event.UserID,
+		event.Email,
+		event.Type,
+		event.Detail,
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresAuthRepository) ListAuthEvents(ctx context.Context, userID string, limit, offset int) ([]*domain.AuthEvent, error) {
+	query := `
+		SELECT id, user_id, email, type, detail, ip, user_agent, request_id, created_at
+		FROM auth_events WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, database.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	events := []*domain.AuthEvent{}
+	for rows.Next() {
+		var event domain.AuthEvent
+		var eventUserID sql.NullString
+		err := rows.Scan(
+			&event.ID,
+			&eventUserID,
+			&event.Email,
+			&event.Type,
+			&event.Detail,
+			&event.IP,
+			&event.UserAgent,
+			&event.RequestID,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, database.ErrDatabaseError
+		}
+		event.UserID = eventUserID.String
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.ErrDatabaseError
+	}
+
+	return events, nil
+}
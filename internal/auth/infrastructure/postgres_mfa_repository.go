@@ -0,0 +1,160 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-template/internal/auth/domain/mfa"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+)
+
+type postgresMFARepository struct {
+	db database.BaseDatabase
+}
+
+func NewPostgresMFARepository(db database.BaseDatabase) mfa.Repository {
+	return &postgresMFARepository{db: db}
+}
+
+func (r *postgresMFARepository) SaveTOTP(ctx context.Context, totp *mfa.TOTP) error {
+	// a confirmed enrollment is never overwritten
+	query := `INSERT INTO user_totp (user_id, secret_encrypted, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret_encrypted = EXCLUDED.secret_encrypted, created_at = EXCLUDED.created_at, last_used_step = NULL
+		WHERE user_totp.confirmed_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, totp.UserID, totp.SecretEncrypted, totp.CreatedAt)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if affected == 0 {
+		return mfa.ErrAlreadyEnabled
+	}
+
+	return nil
+}
+
+func (r *postgresMFARepository) FindTOTP(ctx context.Context, userID string) (*mfa.TOTP, error) {
+	query := `SELECT user_id, secret_encrypted, confirmed_at, last_used_step, created_at FROM user_totp WHERE user_id = $1`
+
+	var totp mfa.TOTP
+	var confirmedAt sql.NullTime
+	var lastUsedStep sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.SecretEncrypted,
+		&confirmedAt,
+		&lastUsedStep,
+		&totp.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, mfa.ErrNotEnrolled
+		}
+		return nil, database.ErrDatabaseError
+	}
+
+	totp.ConfirmedAt = nullTimePtr(confirmedAt)
+	if lastUsedStep.Valid {
+		totp.LastUsedStep = &lastUsedStep.Int64
+	}
+
+	return &totp, nil
+}
+
+func (r *postgresMFARepository) ConfirmTOTP(ctx context.Context, userID string, confirmedAt time.Time, codes []*mfa.RecoveryCode) error {
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		result, err := r.db.ExecContext(ctx, `UPDATE user_totp SET confirmed_at = $2 WHERE user_id = $1 AND confirmed_at IS NULL`, userID, confirmedAt)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return mfa.ErrAlreadyEnabled
+		}
+
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+
+		for _, code := range codes {
+			query := `INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`
+			if _, err := r.db.ExecContext(ctx, query, code.ID, code.UserID, code.CodeHash, code.CreatedAt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err == mfa.ErrAlreadyEnabled {
+		return err
+	}
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresMFARepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if affected == 0 {
+		return mfa.ErrCodeAlreadyUsed
+	}
+
+	return nil
+}
+
+func (r *postgresMFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	query := `UPDATE user_recovery_codes SET used_at = $3 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash, time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if affected == 0 {
+		return mfa.ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (r *postgresMFARepository) DeleteTOTP(ctx context.Context, userID string) error {
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+
+		_, err := r.db.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+		return err
+	})
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package infrastructure
+
+import (
+	"context"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// AddPasswordHistory inserts the hash unless it is already recorded and prunes the entries after the newest keep, it joins the transaction of the context
+func (r *postgresAuthRepository) AddPasswordHistory(ctx context.Context, userID, passwordHash string, keep int) error {
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO password_history (id, user_id, password_hash, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, password_hash) DO NOTHING
+		`
+		if _, err := r.db.ExecContext(ctx, query, uuidv7.New().String(), userID, passwordHash, time.Now()); err != nil {
+			return err
+		}
+
+		query = `
+			DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
+			)
+		`
+		_, err := r.db.ExecContext(ctx, query, userID, keep)
+		return err
+	})
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresAuthRepository) ListPasswordHistory(ctx context.Context, userID string, limit int) ([]string, error) {
+	query := `SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, database.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, database.ErrDatabaseError
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.ErrDatabaseError
+	}
+
+	return hashes, nil
+}
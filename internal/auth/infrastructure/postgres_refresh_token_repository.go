@@ -0,0 +1,102 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-template/internal/auth/domain"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+)
+
+func (r *postgresAuthRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, expires_at, created_at, second_factor) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.ExecContext(ctx, query, token.ID, token.FamilyID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt, token.SecondFactor)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresAuthRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	query := `SELECT id, family_id, user_id, token_hash, expires_at, created_at, rotated_at, revoked_at, second_factor FROM refresh_tokens WHERE token_hash = $1`
+
+	var token domain.RefreshToken
+	var rotatedAt, revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&rotatedAt,
+		&revokedAt,
+		&token.SecondFactor,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrRefreshTokenNotFound
+		}
+		return nil, database.ErrDatabaseError
+	}
+
+	token.RotatedAt = nullTimePtr(rotatedAt)
+	token.RevokedAt = nullTimePtr(revokedAt)
+
+	return &token, nil
+}
+
+// MarkRefreshTokenRotated marks the token as used.
+// It fails with ErrRefreshTokenNotActive if the token was rotated or revoked concurrently.
+func (r *postgresAuthRepository) MarkRefreshTokenRotated(ctx context.Context, token *domain.RefreshToken) error {
+	query := `UPDATE refresh_tokens SET rotated_at = $2 WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, token.ID, time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if affected == 0 {
+		return domain.ErrRefreshTokenNotActive
+	}
+
+	return nil
+}
+
+func (r *postgresAuthRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, familyID, time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresAuthRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// IsRefreshTokenFamilyActive reports whether the login session still has a usable refresh token
+func (r *postgresAuthRepository) IsRefreshTokenFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > $2)`
+
+	var active bool
+	if err := r.db.QueryRowContext(ctx, query, familyID, time.Now()).Scan(&active); err != nil {
+		return false, database.ErrDatabaseError
+	}
+
+	return active, nil
+}
@@ -6,10 +6,24 @@ import (
 	"errors"
 	"go-template/internal/auth/domain"
 	"go-template/internal/shared/infrastructure/database"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
 
+// userSelect loads a user with its roles and the permissions they grant, the queries add their WHERE clause
+const userSelect = `
+	SELECT id, email, first_name, last_name, password, created_at, updated_at, verify, pending_email,
+		status, status_reason, status_changed_at, activated_at, last_login_at,
+		ARRAY(SELECT role_name FROM user_roles WHERE user_id = users.id ORDER BY role_name),
+		ARRAY(
+			SELECT DISTINCT rp.permission_name FROM user_roles ur
+			JOIN role_permissions rp ON rp.role_name = ur.role_name
+			WHERE ur.user_id = users.id
+		)
+	FROM users`
+
 type postgresAuthRepository struct {
 	db database.BaseDatabase
 }
@@ -18,10 +32,17 @@ func NewPostgresAuthRepository(db database.BaseDatabase) domain.AuthRepository {
 	return &postgresAuthRepository{db: db}
 }
 
+func (r *postgresAuthRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.Transaction(ctx, fn)
+}
+
 func (r *postgresAuthRepository) Create(ctx context.Context, user *domain.AuthUser) error {
-	query := `INSERT INTO users (id, email, first_name, last_name, password, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.FirstName, user.LastName, user.PasswordHash, user.CreatedAt, user.UpdatedAt)
+	query := `INSERT INTO users (id, email, first_name, last_name, password, status, status_changed_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.FirstName, user.LastName, user.PasswordHash, user.Status, user.CreatedAt, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return domain.ErrDuplicateEntry
+		}
 		return database.ErrDatabaseError
 	}
 
@@ -29,23 +50,37 @@ func (r *postgresAuthRepository) Create(ctx context.Context, user *domain.AuthUs
 }
 
 func (r *postgresAuthRepository) FindUserByID(ctx context.Context, id string) (*domain.AuthUser, error) {
-	query := `SELECT id, email, first_name, last_name, password, created_at, updated_at, verify FROM users WHERE id = $1`
+	query := userSelect + ` WHERE id = $1 AND deleted_at IS NULL`
 	return r.findUser(ctx, query, id)
 }
 
 func (r *postgresAuthRepository) FindUserByEmail(ctx context.Context, email string) (*domain.AuthUser, error) {
-	query := `SELECT id, email, first_name, last_name, password, created_at, updated_at, verify FROM users WHERE email = $1`
+	query := userSelect + ` WHERE email = $1 AND deleted_at IS NULL`
 	return r.findUser(ctx, query, email)
 }
 
 func (r *postgresAuthRepository) FindUserByUsername(ctx context.Context, username string) (*domain.AuthUser, error) {
-	query := `SELECT id, email, first_name, last_name, password, created_at, updated_at, verify FROM users WHERE username = $1`
+	query := userSelect + ` WHERE username = $1 AND deleted_at IS NULL`
 	return r.findUser(ctx, query, username)
 }
 
 func (r *postgresAuthRepository) findUser(ctx context.Context, query string, arg interface{}) (*domain.AuthUser, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, database.ErrDatabaseError
+	}
+
+	return user, nil
+}
+
+func scanUser(row rowScanner) (*domain.AuthUser, error) {
 	var user domain.AuthUser
-	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+	var pendingEmail sql.NullString
+	var statusChangedAt, activatedAt, lastLoginAt sql.NullTime
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.FirstName,
@@ -54,12 +89,30 @@ func (r *postgresAuthRepository) findUser(ctx context.Context, query string, arg
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Verify,
+		&pendingEmail,
+		&user.Status,
+		&user.StatusReason,
+		&statusChangedAt,
+		&activatedAt,
+		&lastLoginAt,
+		pq.Array(&user.Roles),
+		pq.Array(&user.Permissions),
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, domain.ErrUserNotFound
-		}
-		return nil, database.ErrDatabaseError
+		return nil, err
+	}
+
+	if pendingEmail.Valid {
+		user.PendingEmail = &pendingEmail.String
+	}
+	if statusChangedAt.Valid {
+		user.StatusChangedAt = &statusChangedAt.Time
+	}
+	if activatedAt.Valid {
+		user.ActivatedAt = &activatedAt.Time
+	}
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
 	}
 
 	return &user, nil
@@ -92,10 +145,136 @@ func (r *postgresAuthRepository) Update(ctx context.Context, user *domain.AuthUs
 }
 
 func (r *postgresAuthRepository) VerifyAccount(ctx context.Context, user *domain.AuthUser) error {
-	query := `UPDATE users SET verify = true WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, user.ID)
+	query := `UPDATE users SET verify = true, status = $2, status_reason = $3, status_changed_at = $4, activated_at = $5 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Status, user.StatusReason, user.StatusChangedAt, user.ActivatedAt)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+	return nil
+}
+
+func (r *postgresAuthRepository) SetPendingEmail(ctx context.Context, user *domain.AuthUser, email string) error {
+	query := `UPDATE users SET pending_email = $2 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, user.ID, email)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	user.PendingEmail = &email
+	return nil
+}
+
+func (r *postgresAuthRepository) ConfirmPendingEmail(ctx context.Context, user *domain.AuthUser, email string) error {
+	query := `
+			UPDATE users
+			SET email = pending_email, pending_email = NULL, verify = true, updated_at = $3,
+			status = $4, status_reason = $5, status_changed_at = $6, activated_at = $7
+			WHERE id = $1 AND pending_email = $2
+	`
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query, user.ID, email, now, user.Status, user.StatusReason, user.StatusChangedAt, user.ActivatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return domain.ErrDuplicateEntry
+		}
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+	if affected == 0 {
+		return domain.ErrInvalidToken
+	}
+
+	user.Email = email
+	user.PendingEmail = nil
+	user.Verify = true
+	user.UpdatedAt = now
+
+	return nil
+}
+
+// SoftDelete hides the user from every lookup, the row is removed by the purge after purgeAfter
+func (r *postgresAuthRepository) SoftDelete(ctx context.Context, user *domain.AuthUser, deletedAt, purgeAfter time.Time) error {
+	query := `UPDATE users SET deleted_at = $2, purge_after = $3 WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, user.ID, deletedAt, purgeAfter)
 	if err != nil {
 		return database.ErrDatabaseError
 	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+	if affected == 0 {
+		return domain.ErrUserNotFound
+	}
+
 	return nil
 }
+
+func (r *postgresAuthRepository) ListUsers(ctx context.Context, query domain.UserQuery) ([]*domain.AuthUser, int, error) {
+	// an empty search or status matches every user
+	where := ` WHERE deleted_at IS NULL AND ($1 = '' OR email ILIKE $2 OR first_name ILIKE $2 OR last_name ILIKE $2) AND ($3 = '' OR status = $3)`
+	pattern := "%" + escapeLike(query.Search) + "%"
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, query.Search, pattern, query.Status).Scan(&total)
+	if err != nil {
+		return nil, 0, database.ErrDatabaseError
+	}
+
+	rows, err := r.db.QueryContext(ctx, userSelect+where+` ORDER BY created_at DESC, id LIMIT $4 OFFSET $5`, query.Search, pattern, query.Status, query.Limit, query.Offset)
+	if err != nil {
+		return nil, 0, database.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	users := []*domain.AuthUser{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, database.ErrDatabaseError
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, database.ErrDatabaseError
+	}
+
+	return users, total, nil
+}
+
+func (r *postgresAuthRepository) UpdateStatus(ctx context.Context, user *domain.AuthUser) error {
+	query := `UPDATE users SET status = $2, status_reason = $3, status_changed_at = $4, activated_at = $5 WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, user.ID, user.Status, user.StatusReason, user.StatusChangedAt, user.ActivatedAt)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+	if affected == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresAuthRepository) UpdateLastLogin(ctx context.Context, user *domain.AuthUser) error {
+	query := `UPDATE users SET last_login_at = $2 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.LastLoginAt)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+	return nil
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
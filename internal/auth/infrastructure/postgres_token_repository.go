@@ -0,0 +1,66 @@
+package infrastructure
+
+import (
+	"context"
+	"go-template/internal/auth/domain/token"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+)
+
+type postgresTokenRepository struct {
+	db database.BaseDatabase
+}
+
+func NewPostgresTokenRepository(db database.BaseDatabase) token.Repository {
+	return &postgresTokenRepository{db: db}
+}
+
+func (r *postgresTokenRepository) Create(ctx context.Context, record *token.Record) error {
+	query := `INSERT INTO issued_tokens (id, user_id, purpose, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.ExecContext(ctx, query, record.ID, record.UserID, string(record.Purpose), record.ExpiresAt, record.CreatedAt)
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (r *postgresTokenRepository) Consume(ctx context.Context, id string) error {
+	query := `UPDATE issued_tokens SET consumed_at = $2 WHERE id = $1 AND consumed_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if affected == 1 {
+		return nil
+	}
+
+	// nothing updated, tell a used token apart from an unknown one
+	var exists bool
+	query = `SELECT EXISTS (SELECT 1 FROM issued_tokens WHERE id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	if exists {
+		return token.ErrTokenAlreadyUsed
+	}
+
+	return token.ErrTokenNotFound
+}
+
+func (r *postgresTokenRepository) RevokeByUser(ctx context.Context, userID string, purpose token.Purpose) error {
+	query := `UPDATE issued_tokens SET consumed_at = $3 WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID, string(purpose), time.Now())
+	if err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "go-template/internal/auth/domain"
+
+type AuthEventResponse struct {
+	ID        string `json:"id"`
+	Type      string `json:"type" example:"login_succeeded"`
+	Detail    string `json:"detail,omitempty"`
+	IP        string `json:"ip" example:"203.0.113.7"`
+	UserAgent string `json:"user_agent"`
+	RequestID string `json:"request_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ActivityResponse struct {
+	Events   []*AuthEventResponse `json:"events"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+}
+
+func NewActivityResponse(events []*domain.AuthEvent, page, pageSize int) *ActivityResponse {
+	response := make([]*AuthEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, &AuthEventResponse{
+			ID:        event.ID,
+			Type:      string(event.Type),
+			Detail:    event.Detail,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			RequestID: event.RequestID,
+			CreatedAt: event.CreatedAt.Format("2006-01-02T15:04:05.000Z"),
+		})
+	}
+
+	return &ActivityResponse{Events: response, Page: page, PageSize: pageSize}
+}
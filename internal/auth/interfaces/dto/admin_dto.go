@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"go-template/internal/auth/domain"
+	"time"
+)
+
+type AdminUserResponse struct {
+	ID             string   `json:"id"`
+	Email          string   `json:"email"`
+	FirstName      string   `json:"first_name"`
+	LastName       string   `json:"last_name"`
+	Verified       bool     `json:"verified"`
+	Roles          []string `json:"roles"`
+	Status         string   `json:"status" example:"active"`
+	StatusReason   string   `json:"status_reason,omitempty"`
+	StatusChanged  *string  `json:"status_changed"`
+	LastLogin      *string  `json:"last_login"`
+	AccountCreated string   `json:"account_created"`
+	AccountUpdated string   `json:"account_updated"`
+}
+
+type AdminUserListResponse struct {
+	Users    []*AdminUserResponse `json:"users"`
+	Total    int                  `json:"total"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+}
+
+type AccountStatusInput struct {
+	Reason string `json:"reason" example:"chargeback under investigation" binding:"required,max=255"`
+}
+
+type AdminDeleteUserResponse struct {
+	PurgeAfter time.Time `json:"purge_after" example:"2024-01-31T00:00:00Z"`
+}
+
+func NewAdminUserResponse(user *domain.AuthUser) *AdminUserResponse {
+	roles := user.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+
+	return &AdminUserResponse{
+		ID:             user.ID,
+		Email:          user.Email,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Verified:       user.Verify,
+		Roles:          roles,
+		Status:         string(user.Status),
+		StatusReason:   user.StatusReason,
+		StatusChanged:  formatOptionalTime(user.StatusChangedAt),
+		LastLogin:      formatOptionalTime(user.LastLoginAt),
+		AccountCreated: user.CreatedAt.Format("2006-01-02T15:04:05.000Z"),
+		AccountUpdated: user.UpdatedAt.Format("2006-01-02T15:04:05.000Z"),
+	}
+}
+
+func NewAdminUserListResponse(users []*domain.AuthUser, total, page, pageSize int) *AdminUserListResponse {
+	response := make([]*AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, NewAdminUserResponse(user))
+	}
+
+	return &AdminUserListResponse{
+		Users:    response,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"go-template/internal/auth/domain"
+	"time"
+)
+
+type CreateApiKeyInput struct {
+	Name   string   `json:"name" example:"ci-pipeline" binding:"required,max=255"`
+	Scopes []string `json:"scopes" example:"profile:read,pic:read" binding:"required,min=1"`
+	// ExpiresAt is optional, the key never expires when omitted
+	ExpiresAt *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z"`
+}
+
+type UpdateApiKeyInput struct {
+	Name   string   `json:"name" example:"ci-pipeline" binding:"max=255"`
+	Scopes []string `json:"scopes" example:"profile:read"`
+}
+
+type ApiKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	ExpiresAt  *string  `json:"expires_at"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKeyResponse
+	// Key is only returned once, on creation
+	Key string `json:"key"`
+}
+
+func NewApiKeyResponse(apiKey *domain.ApiKey) *ApiKeyResponse {
+	return &ApiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		CreatedAt:  apiKey.CreatedAt.Format("2006-01-02T15:04:05.000Z"),
+		LastUsedAt: formatOptionalTime(apiKey.LastUsedAt),
+		ExpiresAt:  formatOptionalTime(apiKey.ExpiresAt),
+	}
+}
+
+func NewApiKeyListResponse(apiKeys []*domain.ApiKey) []*ApiKeyResponse {
+	response := make([]*ApiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, NewApiKeyResponse(apiKey))
+	}
+
+	return response
+}
+
+func NewCreateApiKeyResponse(apiKey *domain.ApiKey, key string) *CreateApiKeyResponse {
+	return &CreateApiKeyResponse{
+		ApiKeyResponse: *NewApiKeyResponse(apiKey),
+		Key:            key,
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+
+	formatted := t.Format("2006-01-02T15:04:05.000Z")
+	return &formatted
+}
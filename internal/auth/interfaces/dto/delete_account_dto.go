@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type DeleteAccountInput struct {
+	CurrentPassword string `json:"current_password" example:"secretpassword" binding:"required"`
+}
+
+type DeleteAccountResponse struct {
+	PurgeAfter time.Time `json:"purge_after" example:"2024-01-31T00:00:00Z"`
+}
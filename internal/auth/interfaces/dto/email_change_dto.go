@@ -0,0 +1,6 @@
+package dto
+
+type EmailChangeInput struct {
+	Email           string `json:"email" example:"new@example.com" binding:"required,email"`
+	CurrentPassword string `json:"current_password" example:"secretpassword" binding:"required"`
+}
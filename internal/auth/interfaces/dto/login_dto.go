@@ -1,11 +1,32 @@
 package dto
 
+import (
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/bearer"
+	"time"
+)
+
 type LoginInput struct {
 	Email    string `json:"email" example:"user@example.com" binding:"required,email"`
 	Password string `json:"password" example:"secretpassword" binding:"required"`
 }
 
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token                 string       `json:"token"`
+	TokenType             string       `json:"token_type" example:"Bearer"`
+	ExpiresIn             int64        `json:"expires_in" example:"900"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresIn int64        `json:"refresh_token_expires_in" example:"2592000"`
+	User                  UserResponse `json:"user"`
+}
+
+func NewLoginResponse(session *bearer.Session, user *domain.AuthUser) *LoginResponse {
+	return &LoginResponse{
+		Token:                 session.AccessToken.Value,
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(time.Until(session.AccessToken.ExpiresAt).Seconds()),
+		RefreshToken:          session.RefreshToken,
+		RefreshTokenExpiresIn: int64(time.Until(session.RefreshTokenExpiresAt).Seconds()),
+		User:                  *NewUserResponse(user),
+	}
 }
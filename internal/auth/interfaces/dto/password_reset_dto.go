@@ -0,0 +1,10 @@
+package dto
+
+type PasswordResetRequestInput struct {
+	Email string `json:"email" example:"user@example.com" binding:"required,email"`
+}
+
+type PasswordResetConfirmInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" example:"newsecretpassword" binding:"required"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import "go-template/internal/auth/domain/mfa"
+
+// SecondFactorChallengeResponse is returned by login instead of a session when the user has a second factor
+type SecondFactorChallengeResponse struct {
+	SecondFactorRequired bool   `json:"second_factor_required" example:"true"`
+	SecondFactorToken    string `json:"second_factor_token"`
+}
+
+type SecondFactorLoginInput struct {
+	SecondFactorToken string `json:"second_factor_token" binding:"required"`
+	// Code is a TOTP code or a recovery code
+	Code string `json:"code" example:"123456" binding:"required"`
+}
+
+type SecondFactorCodeInput struct {
+	Code string `json:"code" example:"123456" binding:"required"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Webapp:user@example.com?secret=..."`
+}
+
+func NewTOTPEnrollmentResponse(enrollment *mfa.Enrollment) *TOTPEnrollmentResponse {
+	return &TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	}
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
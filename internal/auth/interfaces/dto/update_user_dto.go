@@ -1,7 +1,17 @@
 package dto
 
+// UpdateUserInput replaces the names, Password is the new password when CurrentPassword is set and the current one otherwise
 type UpdateUserInput struct {
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Password  string `json:"password" binding:"required"`
+	FirstName       string `json:"first_name" binding:"required"`
+	LastName        string `json:"last_name" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// PatchUserInput only changes the fields that are set, CurrentPassword is required with Password
+type PatchUserInput struct {
+	FirstName       *string `json:"first_name" example:"John" binding:"omitempty,min=1"`
+	LastName        *string `json:"last_name" example:"Doe" binding:"omitempty,min=1"`
+	Password        *string `json:"password" example:"newsecretpassword" binding:"omitempty,min=1"`
+	CurrentPassword string  `json:"current_password" example:"secretpassword" binding:"required_with=Password"`
 }
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"go-template/internal/auth/application"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/interfaces/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	adminService application.AdminApplicationService
+}
+
+func NewAdminHandler(adminService application.AdminApplicationService) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+// @Summary List users
+// @Description List the users, newest first, optionally matching a search on the email and the names
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Search"
+// @Param status query string false "pending, active, suspended or deactivated"
+// @Param page query int false "Page, starts at 1"
+// @Param page_size query int false "Page size, default 20, at most 100"
+// @Success 200 {object} dto.AdminUserListResponse
+// @Router /v1/admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, pageErr := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, pageSizeErr := strconv.Atoi(c.DefaultQuery("page_size", "0"))
+	if pageErr != nil || pageSizeErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "page and page_size must be numbers"})
+		return
+	}
+
+	result, err := h.adminService.ListUsers(c.Request.Context(), c.Query("q"), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAdminUserListResponse(result.Users, result.Total, result.Page, result.PageSize))
+}
+
+// @Summary Get a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User id"
+// @Success 200 {object} dto.AdminUserResponse
+// @Router /v1/admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.adminService.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+}
+
+// @Summary Suspend a user
+// @Description The user cannot authenticate until it is activated again, its sessions and tokens are revoked
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User id"
+// @Param input body dto.AccountStatusInput true "Reason"
+// @Success 200 {object} dto.AdminUserResponse
+// @Router /v1/admin/users/{id}/suspend [post]
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	var input dto.AccountStatusInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, _ := c.Get("user")
+	user, err := h.adminService.SuspendUser(c.Request.Context(), actor.(*domain.AuthUser), c.Param("id"), input.Reason)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+}
+
+// @Summary Deactivate a user
+// @Description Close the account of the user, its sessions and tokens are revoked
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User id"
+// @Param input body dto.AccountStatusInput true "Reason"
+// @Success 200 {object} dto.AdminUserResponse
+// @Router /v1/admin/users/{id}/deactivate [post]
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	var input dto.AccountStatusInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, _ := c.Get("user")
+	user, err := h.adminService.DeactivateUser(c.Request.Context(), actor.(*domain.AuthUser), c.Param("id"), input.Reason)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+}
+
+// @Summary Activate a user
+// @Description Let a suspended or deactivated user authenticate again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User id"
+// @Success 200 {object} dto.AdminUserResponse
+// @Router /v1/admin/users/{id}/activate [post]
+func (h *AdminHandler) ActivateUser(c *gin.Context) {
+	actor, _ := c.Get("user")
+	user, err := h.adminService.ActivateUser(c.Request.Context(), actor.(*domain.AuthUser), c.Param("id"))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+}
+
+// @Summary Verify a user
+// @Description Mark the email of the user as verified without the verification link
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User id"
+// @Success 200 {object} dto.AdminUserResponse
+// @Router /v1/admin/users/{id}/verify [post]
+func (h *AdminHandler) VerifyUser(c *gin.Context) {
+	actor, _ := c.Get("user")
+	user, err := h.adminService.VerifyUser(c.Request.Context(), actor.(*domain.AuthUser), c.Param("id"))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewAdminUserResponse(user))
+}
+
+// @Summary Delete a user
+// @Description Delete the account of the user, it is purged after the grace period
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User id"
+// @Success 202 {object} dto.AdminDeleteUserResponse
+// @Router /v1/admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	actor, _ := c.Get("user")
+	purgeAfter, err := h.adminService.DeleteUser(c.Request.Context(), actor.(*domain.AuthUser), c.Param("id"))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.AdminDeleteUserResponse{PurgeAfter: purgeAfter})
+}
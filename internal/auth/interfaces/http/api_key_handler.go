@@ -0,0 +1,108 @@
+package http
+
+import (
+	"go-template/internal/auth/application"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/interfaces/dto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyHandler struct {
+	apiKeyService application.ApiKeyApplicationService
+}
+
+func NewApiKeyHandler(apiKeyService application.ApiKeyApplicationService) *ApiKeyHandler {
+	return &ApiKeyHandler{apiKeyService: apiKeyService}
+}
+
+// @Summary Create an api key
+// @Description Create a personal api key, the key is only returned once
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param input body dto.CreateApiKeyInput true "Api key details"
+// @Success 201 {object} dto.CreateApiKeyResponse
+// @Router /v1/user/self/api-keys [post]
+func (h *ApiKeyHandler) CreateApiKey(c *gin.Context) {
+	var input dto.CreateApiKeyInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	apiKey, key, err := h.apiKeyService.CreateApiKey(c.Request.Context(), user.(*domain.AuthUser), input.Name, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewCreateApiKeyResponse(apiKey, key))
+}
+
+// @Summary List api keys
+// @Description List the active api keys of the user
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} dto.ApiKeyResponse
+// @Router /v1/user/self/api-keys [get]
+func (h *ApiKeyHandler) ListApiKeys(c *gin.Context) {
+	user, _ := c.Get("user")
+	apiKeys, err := h.apiKeyService.ListApiKeys(c.Request.Context(), user.(*domain.AuthUser))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewApiKeyListResponse(apiKeys))
+}
+
+// @Summary Update an api key
+// @Description Rename an api key or change its scopes
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Api key id"
+// @Param input body dto.UpdateApiKeyInput true "Api key details"
+// @Success 200 {object} dto.ApiKeyResponse
+// @Router /v1/user/self/api-keys/{id} [patch]
+func (h *ApiKeyHandler) UpdateApiKey(c *gin.Context) {
+	var input dto.UpdateApiKeyInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	apiKey, err := h.apiKeyService.UpdateApiKey(c.Request.Context(), user.(*domain.AuthUser), c.Param("id"), input.Name, input.Scopes)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewApiKeyResponse(apiKey))
+}
+
+// @Summary Revoke an api key
+// @Tags api-keys
+// @Security ApiKeyAuth
+// @Param id path string true "Api key id"
+// @Success 204
+// @Router /v1/user/self/api-keys/{id} [delete]
+func (h *ApiKeyHandler) RevokeApiKey(c *gin.Context) {
+	user, _ := c.Get("user")
+	err := h.apiKeyService.RevokeApiKey(c.Request.Context(), user.(*domain.AuthUser), c.Param("id"))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -7,8 +7,10 @@ import (
 	"go-template/internal/auth/application"
 	"go-template/internal/auth/domain"
 	"go-template/internal/auth/interfaces/dto"
+	"go-template/pkg/apperrors"
 	"io"
 	"net/http"
+	"strconv"
 
 	_ "go-template/docs"
 
@@ -41,13 +43,145 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	user, err := h.authService.Register(c.Request.Context(), input.Email, input.FirstName, input.LastName, input.Password)
 	if err != nil {
-		c.JSON(err.Status(), gin.H{"error": err.Message})
+		c.JSON(err.Status(), errorResponse(err))
 		return
 	}
 
 	c.JSON(http.StatusCreated, dto.NewUserResponse(user))
 }
 
+// @Summary Login
+// @Description Exchange email and password for a bearer access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body dto.LoginInput true "User credentials"
+// @Success 200 {object} dto.LoginResponse
+// @Success 202 {object} dto.SecondFactorChallengeResponse
+// @Router /v1/user/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var input dto.LoginInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.authService.Login(c.Request.Context(), input.Email, input.Password, c.ClientIP())
+	if err != nil {
+		if err.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+		}
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	// the login is not complete until the second factor is
+	if result.SecondFactorToken != "" {
+		c.JSON(http.StatusAccepted, dto.SecondFactorChallengeResponse{
+			SecondFactorRequired: true,
+			SecondFactorToken:    result.SecondFactorToken,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewLoginResponse(result.Session, result.User))
+}
+
+// @Summary Complete login with a second factor
+// @Description Exchange the second factor token of the login and a TOTP or recovery code for a bearer access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body dto.SecondFactorLoginInput true "Second factor"
+// @Success 200 {object} dto.LoginResponse
+// @Router /v1/user/login/second-factor [post]
+func (h *AuthHandler) LoginSecondFactor(c *gin.Context) {
+	var input dto.SecondFactorLoginInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, user, err := h.authService.CompleteSecondFactor(c.Request.Context(), input.SecondFactorToken, input.Code, c.ClientIP())
+	if err != nil {
+		if err.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+		}
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewLoginResponse(session, user))
+}
+
+// @Summary Refresh session
+// @Description Exchange a refresh token for a new access token and a rotated refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body dto.RefreshTokenInput true "Refresh token"
+// @Success 200 {object} dto.LoginResponse
+// @Router /v1/user/token/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var input dto.RefreshTokenInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, user, err := h.authService.RefreshSession(c.Request.Context(), input.RefreshToken)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewLoginResponse(session, user))
+}
+
+// @Summary Logout
+// @Description Revoke the session the refresh token belongs to
+// @Tags auth
+// @Accept json
+// @Param input body dto.RefreshTokenInput true "Refresh token"
+// @Success 204
+// @Router /v1/user/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var input dto.RefreshTokenInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authService.Logout(c.Request.Context(), input.RefreshToken)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Logout all sessions
+// @Description Revoke every session of the authenticated user
+// @Tags auth
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /v1/user/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	user, _ := c.Get("user")
+	err := h.authService.LogoutAll(c.Request.Context(), user.(*domain.AuthUser))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // @Summary Get user profile
 // @Description Get user profile
 // @Tags auth
@@ -62,7 +196,7 @@ func (h *AuthHandler) GetUser(c *gin.Context) {
 }
 
 // @Summary Update user profile
-// @Description Update user profile
+// @Description Replace the names, password has to be the current password unless current_password is set to change it
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -79,7 +213,7 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// check if the input contains invalid data without dto.UpdateUserInput
-	if err := checkFieldsIsValid(rawBody, []string{"first_name", "last_name", "password"}); err != nil {
+	if err := checkFieldsIsValid(rawBody, []string{"first_name", "last_name", "password", "current_password"}); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -93,15 +227,58 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 	}
 
 	user, _ := c.Get("user")
-	_, err := h.authService.UpdateUser(c.Request.Context(), user.(*domain.AuthUser), input.FirstName, input.LastName, input.Password)
+	_, err := h.authService.UpdateUser(c.Request.Context(), user.(*domain.AuthUser), input.FirstName, input.LastName, input.Password, input.CurrentPassword)
 	if err != nil {
-		c.JSON(err.Status(), gin.H{"error": err.Message})
+		c.JSON(err.Status(), errorResponse(err))
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Partially update user profile
+// @Description Update any subset of the names and the password, changing the password needs the current password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param input body dto.PatchUserInput true "Fields to update"
+// @Success 200 {object} dto.UserResponse
+// @Router /v1/user/self [patch]
+func (h *AuthHandler) PatchUser(c *gin.Context) {
+	rawBody, parseErr := c.GetRawData()
+	if parseErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": parseErr.Error()})
+		return
+	}
+
+	// check if the input contains invalid data without dto.PatchUserInput
+	if err := checkFieldsIsValid(rawBody, []string{"first_name", "last_name", "password", "current_password"}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(rawBody))
+
+	var input dto.PatchUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	updatedUser, err := h.authService.PatchUser(c.Request.Context(), user.(*domain.AuthUser), input.FirstName, input.LastName, input.Password, input.CurrentPassword)
+	if err != nil {
+		if err.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+		}
+		c.JSON(err.Status(), errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewUserResponse(updatedUser))
+}
+
 func (h *AuthHandler) VerifyAccount(c *gin.Context) {
 	token := c.Query("token")
 	userId := c.Query("user_id")
@@ -131,6 +308,172 @@ func (h *AuthHandler) ResendVerification(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Request a password reset
+// @Description Send a password reset email, the response does not reveal whether the email is registered
+// @Tags auth
+// @Accept json
+// @Param input body dto.PasswordResetRequestInput true "User email"
+// @Success 202
+// @Router /v1/user/password-reset [post]
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var input dto.PasswordResetRequestInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authService.RequestPasswordReset(c.Request.Context(), input.Email)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// @Summary Confirm a password reset
+// @Description Set a new password with the token from the password reset email
+// @Tags auth
+// @Accept json
+// @Param input body dto.PasswordResetConfirmInput true "Reset token and new password"
+// @Success 204
+// @Router /v1/user/password-reset/confirm [post]
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var input dto.PasswordResetConfirmInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authService.ConfirmPasswordReset(c.Request.Context(), input.Token, input.Password)
+	if err != nil {
+		c.JSON(err.Status(), errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Request an email change
+// @Description Send a verification link to the new address, the email changes once it is confirmed
+// @Tags auth
+// @Accept json
+// @Security BearerAuth
+// @Param input body dto.EmailChangeInput true "New email and current password"
+// @Success 202
+// @Router /v1/user/self/email [post]
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	var input dto.EmailChangeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	err := h.authService.RequestEmailChange(c.Request.Context(), user.(*domain.AuthUser), input.Email, input.CurrentPassword)
+	if err != nil {
+		if err.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+		}
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// @Summary Delete the account
+// @Description Delete the account of the user, the data is kept for the grace period and purged after it
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body dto.DeleteAccountInput true "Current password"
+// @Success 202 {object} dto.DeleteAccountResponse
+// @Router /v1/user/self [delete]
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	var input dto.DeleteAccountInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	purgeAfter, err := h.authService.DeleteAccount(c.Request.Context(), user.(*domain.AuthUser), input.CurrentPassword)
+	if err != nil {
+		if err.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+		}
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.DeleteAccountResponse{PurgeAfter: purgeAfter})
+}
+
+// @Summary List own activity
+// @Description List the security events of the account, newest first
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "Page, starts at 1"
+// @Param page_size query int false "Page size, default 20, at most 100"
+// @Success 200 {object} dto.ActivityResponse
+// @Router /v1/user/self/activity [get]
+func (h *AuthHandler) ListActivity(c *gin.Context) {
+	page, pageErr := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, pageSizeErr := strconv.Atoi(c.DefaultQuery("page_size", "0"))
+	if pageErr != nil || pageSizeErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "page and page_size must be numbers"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	result, err := h.authService.ListActivity(c.Request.Context(), user.(*domain.AuthUser), page, pageSize)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewActivityResponse(result.Events, result.Page, result.PageSize))
+}
+
+// @Summary Confirm an email change
+// @Description Switch to the new address with the token from the verification email
+// @Tags auth
+// @Param token query string true "Email change token"
+// @Success 204
+// @Router /verify-email-change [get]
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	err := h.authService.ConfirmEmailChange(c.Request.Context(), token)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// errorResponse adds the details of a validation error, e.g. every rule a password failed
+func errorResponse(err *apperrors.Error) gin.H {
+	if len(err.Details) == 0 {
+		return gin.H{"error": err.Message}
+	}
+
+	return gin.H{"error": err.Message, "details": err.Details}
+}
+
 func checkFieldsIsValid(rawBody []byte, expectedFields []string) error {
 	var data map[string]interface{}
 	if err := json.Unmarshal(rawBody, &data); err != nil {
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"go-template/internal/auth/domain/token"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keyring *token.Keyring
+}
+
+func NewJWKSHandler(keyring *token.Keyring) *JWKSHandler {
+	return &JWKSHandler{keyring: keyring}
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys to verify the tokens signed with RS256 or EdDSA, selected by the kid header
+// @Tags auth
+// @Produce json
+// @Success 200 {object} token.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// the keys change only on rotation, let verifiers cache them for a while
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keyring.JWKS())
+}
@@ -11,8 +11,9 @@ func AccountVerificationMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, _ := c.Get("user")
 
-		if !user.(*domain.AuthUser).Verify {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account not verified"})
+		// a pending account becomes active once its email is verified
+		if user.(*domain.AuthUser).Status == domain.AccountStatusPending {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account pending verification"})
 			c.Abort()
 			return
 		}
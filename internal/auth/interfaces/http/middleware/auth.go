@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"go-template/internal/auth/application"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMiddleware authenticates the request with whichever scheme the Authorization header uses
+// and stores the authenticated user under the "user" key
+func AuthMiddleware(authenticator application.Authenticator) gin.HandlerFunc {
+	challenges := make([]string, 0, len(authenticator.Schemes()))
+	for _, scheme := range authenticator.Schemes() {
+		challenges = append(challenges, fmt.Sprintf(`%s realm="webapp"`, scheme))
+	}
+	wwwAuthenticate := strings.Join(challenges, ", ")
+
+	return func(c *gin.Context) {
+		user, err := authenticator.Authenticate(c.Request.Context(), c.GetHeader("Authorization"), c.ClientIP())
+		if err != nil {
+			if err.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+			} else {
+				c.Header("WWW-Authenticate", wwwAuthenticate)
+			}
+			c.JSON(err.Status(), gin.H{"error": err.Message})
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Next()
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"go-template/internal/auth/domain"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScopeMiddleware rejects requests whose credential is restricted to other scopes
+func RequireScopeMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+
+		if !user.(*domain.AuthUser).HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing scope " + scope})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermissionMiddleware rejects users whose roles do not grant every one of the permissions
+func RequirePermissionMiddleware(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+
+		for _, permission := range permissions {
+			if !user.(*domain.AuthUser).HasPermission(permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RejectApiKeyMiddleware keeps api keys away from account management routes,
+// so a leaked key cannot be used to mint new keys or end the owner's sessions
+func RejectApiKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+
+		credential := user.(*domain.AuthUser).Credential
+		if credential != nil && credential.Type == domain.CredentialApiKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Api keys cannot access this resource"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SecondFactorMiddleware rejects requests to the configured routes
+// unless the session was started with a completed second factor
+func SecondFactorMiddleware(routes []string) gin.HandlerFunc {
+	required := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		required[strings.Join(strings.Fields(route), " ")] = true
+	}
+
+	return func(c *gin.Context) {
+		if !required[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		user, _ := c.Get("user")
+
+		credential := user.(*domain.AuthUser).Credential
+		if credential == nil || !credential.SecondFactor {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Second factor required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
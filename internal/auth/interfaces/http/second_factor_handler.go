@@ -0,0 +1,88 @@
+package http
+
+import (
+	"go-template/internal/auth/application"
+	"go-template/internal/auth/domain"
+	"go-template/internal/auth/interfaces/dto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecondFactorHandler struct {
+	secondFactorService application.SecondFactorApplicationService
+}
+
+func NewSecondFactorHandler(secondFactorService application.SecondFactorApplicationService) *SecondFactorHandler {
+	return &SecondFactorHandler{secondFactorService: secondFactorService}
+}
+
+// @Summary Enroll a TOTP authenticator
+// @Description Generate a TOTP secret and its provisioning URI, the authenticator is enabled once confirmed with a first code
+// @Tags second-factor
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} dto.TOTPEnrollmentResponse
+// @Router /v1/user/self/second-factor/totp [post]
+func (h *SecondFactorHandler) EnrollTOTP(c *gin.Context) {
+	user, _ := c.Get("user")
+	enrollment, err := h.secondFactorService.EnrollTOTP(c.Request.Context(), user.(*domain.AuthUser))
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewTOTPEnrollmentResponse(enrollment))
+}
+
+// @Summary Confirm a TOTP authenticator
+// @Description Enable the enrolled authenticator with a first code, the recovery codes are only returned once
+// @Tags second-factor
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body dto.SecondFactorCodeInput true "TOTP code"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Router /v1/user/self/second-factor/totp/confirm [post]
+func (h *SecondFactorHandler) ConfirmTOTP(c *gin.Context) {
+	var input dto.SecondFactorCodeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	recoveryCodes, err := h.secondFactorService.ConfirmTOTP(c.Request.Context(), user.(*domain.AuthUser), input.Code)
+	if err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// @Summary Disable the TOTP authenticator
+// @Description Remove the authenticator and the recovery codes, a TOTP or recovery code is required
+// @Tags second-factor
+// @Accept json
+// @Security BearerAuth
+// @Param input body dto.SecondFactorCodeInput true "TOTP or recovery code"
+// @Success 204
+// @Router /v1/user/self/second-factor/totp [delete]
+func (h *SecondFactorHandler) DisableTOTP(c *gin.Context) {
+	var input dto.SecondFactorCodeInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get("user")
+	if err := h.secondFactorService.DisableTOTP(c.Request.Context(), user.(*domain.AuthUser), input.Code); err != nil {
+		c.JSON(err.Status(), gin.H{"error": err.Message})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -4,39 +4,82 @@ import (
 	"go-template/internal/auth/application"
 	"go-template/internal/auth/config"
 	"go-template/internal/auth/domain"
+	"go-template/internal/auth/domain/admin"
+	"go-template/internal/auth/domain/apikey"
 	"go-template/internal/auth/domain/basic"
+	"go-template/internal/auth/domain/bearer"
+	"go-template/internal/auth/domain/mfa"
+	"go-template/internal/auth/domain/throttle"
+	"go-template/internal/auth/domain/token"
 	"go-template/internal/auth/infrastructure"
 	"go-template/internal/auth/interfaces/http"
 	"go-template/internal/auth/interfaces/http/middleware"
-	"go-template/internal/aws/sns"
+	"go-template/internal/aws/cloudwatch"
+	appConfig "go-template/internal/config"
 	sharedConfig "go-template/internal/shared/config"
 	"go-template/internal/shared/infrastructure/database"
 	"go-template/internal/shared/infrastructure/logger"
+	"go-template/internal/shared/infrastructure/outbox"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Module struct {
-	handler      *http.AuthHandler
-	basicService *basic.BasicService
-	authConfig   *config.AuthConfig
+	handler             *http.AuthHandler
+	apiKeyHandler       *http.ApiKeyHandler
+	jwksHandler         *http.JWKSHandler
+	secondFactorHandler *http.SecondFactorHandler
+	adminHandler        *http.AdminHandler
+	authenticator       application.Authenticator
+	authConfig          *config.AuthConfig
 }
 
-func NewModule(db database.BaseDatabase, logger logger.Logger, snsModule sns.SNSModule) *Module {
+func NewModule(db database.BaseDatabase, logger logger.Logger, notificationOutbox outbox.Outbox, cloudWatchModule cloudwatch.CloudWatchModule) *Module {
 	// load auth config with viper
 	authConfig := loadConfig()
 
 	authRepo := infrastructure.NewPostgresAuthRepository(db)
-	authDomainService := domain.NewAuthService(authRepo, logger, authConfig, snsModule)
-	authAppService := application.NewAuthApplicationService(authDomainService, logger)
+
+	keyring := loadKeyring()
+	tokenService := token.NewTokenService(infrastructure.NewPostgresTokenRepository(db), keyring)
+
+	passwordHasher := loadPasswordHasher(authConfig)
+	domain.SetPasswordHasher(passwordHasher)
+
+	passwordValidator := domain.NewPasswordValidator(loadPasswordPolicy(authConfig, passwordHasher), authRepo, loadBreachedPasswords(authConfig))
+	authDomainService := domain.NewAuthService(authRepo, logger, authConfig, notificationOutbox, tokenService, passwordValidator)
+	bearerService := bearer.NewBearerService(authRepo, authConfig, tokenService)
+	guard := throttle.NewGuard(loadAttemptStore(db, authConfig), loadThrottlePolicy(authConfig))
+	loginGuard := application.NewLoginGuard(guard, logger, cloudWatchModule)
+
+	mfaService := mfa.NewMFAService(infrastructure.NewPostgresMFARepository(db), authRepo, tokenService, loadSecretCipher(authConfig), totpIssuer(authConfig))
+	basicService := basic.NewBasicService(authRepo, mfaService)
+	authAppService := application.NewAuthApplicationService(authDomainService, basicService, bearerService, mfaService, loginGuard, logger)
 	authHandler := http.NewAuthHandler(authAppService)
-	basicService := basic.NewBasicService(authRepo)
+
+	apiKeyRepo := infrastructure.NewPostgresApiKeyRepository(db)
+	apiKeyService := apikey.NewApiKeyService(authRepo, apiKeyRepo)
+	apiKeyAppService := application.NewApiKeyApplicationService(apiKeyService, logger)
+	apiKeyHandler := http.NewApiKeyHandler(apiKeyAppService)
+
+	// the strategies are selected by the scheme of the Authorization header
+	authenticator := application.NewAuthenticatorService(logger, loginGuard, authDomainService, bearerService, basicService, apiKeyService)
+
+	secondFactorAppService := application.NewSecondFactorApplicationService(mfaService, logger)
+
+	adminAppService := application.NewAdminApplicationService(admin.NewAdminService(authRepo, authDomainService), logger)
 
 	return &Module{
-		handler:      authHandler,
-		authConfig:   authConfig,
-		basicService: basicService,
+		handler:             authHandler,
+		apiKeyHandler:       apiKeyHandler,
+		jwksHandler:         http.NewJWKSHandler(keyring),
+		secondFactorHandler: http.NewSecondFactorHandler(secondFactorAppService),
+		adminHandler:        http.NewAdminHandler(adminAppService),
+		authConfig:          authConfig,
+		authenticator:       authenticator,
 	}
 }
 
@@ -50,29 +93,222 @@ func loadConfig() *config.AuthConfig {
 	return authConfig
 }
 
-func (m *Module) GetBasicService() *basic.BasicService {
-	return m.basicService
+// loadKeyring uses the keyring config, the secret keys are the fallback when it is not set
+func loadKeyring() *token.Keyring {
+	if len(appConfig.App.Keyring) == 0 {
+		return token.NewKeyring(appConfig.App.SecretKey, appConfig.App.PreviousSecretKeys...)
+	}
+
+	keyring, err := token.NewKeyringFromConfig(appConfig.App.Keyring)
+	if err != nil {
+		log.Fatalf("Failed to load keyring: %v", err)
+	}
+
+	// the links sent before the keyring was configured are signed with the secret keys
+	keyring.SetLegacySecrets(append([]string{appConfig.App.SecretKey}, appConfig.App.PreviousSecretKeys...)...)
+
+	return keyring
+}
+
+// loadSecretCipher falls back to the secret key when no dedicated TOTP encryption key is configured
+func loadSecretCipher(authConfig *config.AuthConfig) *mfa.SecretCipher {
+	encryptionKey := authConfig.Auth.TOTPEncryptionKey
+	if encryptionKey == "" {
+		encryptionKey = appConfig.App.SecretKey
+	}
+
+	cipher, err := mfa.NewSecretCipher(encryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to create TOTP cipher: %v", err)
+	}
+
+	return cipher
+}
+
+func totpIssuer(authConfig *config.AuthConfig) string {
+	if authConfig.Auth.TOTPIssuer != "" {
+		return authConfig.Auth.TOTPIssuer
+	}
+
+	return appConfig.App.Name
+}
+
+// loadAttemptStore keeps the failed attempts in memory unless they have to be shared between nodes
+func loadAttemptStore(db database.BaseDatabase, authConfig *config.AuthConfig) throttle.Store {
+	switch authConfig.Auth.ThrottleStore {
+	case "", "memory":
+		return infrastructure.NewMemoryAttemptStore()
+	case "postgres":
+		return infrastructure.NewPostgresAttemptStore(db)
+	default:
+		log.Fatalf("Unknown throttle store: %s", authConfig.Auth.ThrottleStore)
+		return nil
+	}
+}
+
+func loadThrottlePolicy(authConfig *config.AuthConfig) throttle.Policy {
+	policy := throttle.DefaultPolicy()
+	if authConfig.Auth.ThrottleMaxAccountFailures > 0 {
+		policy.MaxAccountFailures = authConfig.Auth.ThrottleMaxAccountFailures
+	}
+	if authConfig.Auth.ThrottleMaxIPFailures > 0 {
+		policy.MaxIPFailures = authConfig.Auth.ThrottleMaxIPFailures
+	}
+	if authConfig.Auth.ThrottleLockoutDuration > 0 {
+		policy.LockoutDuration = time.Duration(authConfig.Auth.ThrottleLockoutDuration) * time.Second
+	}
+
+	return policy
+}
+
+// loadPasswordHasher keeps bcrypt with the default cost unless configured otherwise
+func loadPasswordHasher(authConfig *config.AuthConfig) *domain.PasswordHasher {
+	algorithm := authConfig.Auth.PasswordHashAlgorithm
+	if algorithm == "" {
+		algorithm = domain.PasswordAlgorithmBcrypt
+	}
+
+	bcryptCost := bcrypt.DefaultCost
+	if authConfig.Auth.PasswordBcryptCost > 0 {
+		bcryptCost = authConfig.Auth.PasswordBcryptCost
+	}
+
+	argon2Params := domain.DefaultArgon2Params()
+	if authConfig.Auth.PasswordArgon2Memory > 0 {
+		argon2Params.Memory = uint32(authConfig.Auth.PasswordArgon2Memory)
+	}
+	if authConfig.Auth.PasswordArgon2Iterations > 0 {
+		argon2Params.Iterations = uint32(authConfig.Auth.PasswordArgon2Iterations)
+	}
+	if authConfig.Auth.PasswordArgon2Parallelism > 0 {
+		argon2Params.Parallelism = uint8(min(authConfig.Auth.PasswordArgon2Parallelism, 255))
+	}
+
+	hasher, err := domain.NewPasswordHasher(algorithm, bcryptCost, argon2Params)
+	if err != nil {
+		log.Fatalf("Failed to create password hasher: %v", err)
+	}
+
+	return hasher
+}
+
+func loadPasswordPolicy(authConfig *config.AuthConfig, hasher *domain.PasswordHasher) domain.PasswordPolicy {
+	policy := domain.DefaultPasswordPolicy()
+	if authConfig.Auth.PasswordMinLength > 0 {
+		policy.MinLength = authConfig.Auth.PasswordMinLength
+	}
+	if authConfig.Auth.PasswordMaxLength > 0 {
+		policy.MaxLength = authConfig.Auth.PasswordMaxLength
+	}
+	if policy.MaxLength > hasher.MaxPasswordLength() {
+		log.Fatalf("Password max length cannot exceed %d bytes with %s", hasher.MaxPasswordLength(), hasher.Algorithm())
+	}
+	if policy.MinLength > policy.MaxLength {
+		log.Fatalf("Password min length %d is greater than the max length %d", policy.MinLength, policy.MaxLength)
+	}
+
+	policy.RequireUppercase = authConfig.Auth.PasswordRequireUppercase
+	policy.RequireLowercase = authConfig.Auth.PasswordRequireLowercase
+	policy.RequireDigit = authConfig.Auth.PasswordRequireDigit
+	policy.RequireSymbol = authConfig.Auth.PasswordRequireSymbol
+
+	// a negative size turns the reuse check off
+	if authConfig.Auth.PasswordHistorySize > 0 {
+		policy.HistorySize = authConfig.Auth.PasswordHistorySize
+	} else if authConfig.Auth.PasswordHistorySize < 0 {
+		policy.HistorySize = 0
+	}
+
+	return policy
+}
+
+// loadBreachedPasswords returns nil when no list is configured, the check is skipped then
+func loadBreachedPasswords(authConfig *config.AuthConfig) domain.BreachedPasswords {
+	if authConfig.Auth.BreachedPasswordsFile == "" {
+		return nil
+	}
+
+	breached, err := infrastructure.LoadBreachedPasswordList(authConfig.Auth.BreachedPasswordsFile)
+	if err != nil {
+		log.Fatalf("Failed to load breached passwords: %v", err)
+	}
+
+	return breached
+}
+
+func (m *Module) GetAuthenticator() application.Authenticator {
+	return m.authenticator
 }
 
 func (m *Module) RegisterRoutes(router *gin.Engine) {
 
 	router.GET("/verify", m.handler.VerifyAccount)
+	router.GET("/verify-email-change", m.handler.ConfirmEmailChange)
+	router.GET("/.well-known/jwks.json", m.jwksHandler.GetJWKS)
 
 	v1User := router.Group("/v1/user")
 	{
 		v1User.POST("", m.handler.Register)
+		v1User.POST("/login", m.handler.Login)
+		v1User.POST("/login/second-factor", m.handler.LoginSecondFactor)
+		v1User.POST("/token/refresh", m.handler.RefreshToken)
+		v1User.POST("/logout", m.handler.Logout)
+		v1User.POST("/password-reset", m.handler.RequestPasswordReset)
+		v1User.POST("/password-reset/confirm", m.handler.ConfirmPasswordReset)
 
-		// the route below protected by basic auth middleware
+		// the route below protected by auth middleware
 		authenticated := v1User.Group("")
-		authenticated.Use(middleware.BasicAuthMiddleware(m.basicService))
+		authenticated.Use(middleware.AuthMiddleware(m.authenticator), middleware.SecondFactorMiddleware(m.authConfig.Auth.SecondFactorRoutes))
 		{
-			authenticated.GET("/resend-verification-email", m.handler.ResendVerification)
+			authenticated.GET("/resend-verification-email", middleware.RejectApiKeyMiddleware(), m.handler.ResendVerification)
+			authenticated.POST("/logout-all", middleware.RejectApiKeyMiddleware(), m.handler.LogoutAll)
+			// an unverified user can fix a mistyped address, the new one is verified by the confirmation
+			authenticated.POST("/self/email", middleware.RejectApiKeyMiddleware(), m.handler.RequestEmailChange)
+			// an unverified user can delete the account as well
+			authenticated.DELETE("/self", middleware.RejectApiKeyMiddleware(), m.handler.DeleteAccount)
 
 			authenticated.Use(middleware.AccountVerificationMiddleware())
 			{
-				authenticated.GET("/self", m.handler.GetUser)
-				authenticated.PUT("/self", m.handler.UpdateUser)
+				authenticated.GET("/self", middleware.RequireScopeMiddleware(domain.ScopeProfileRead), m.handler.GetUser)
+				authenticated.PUT("/self", middleware.RequireScopeMiddleware(domain.ScopeProfileWrite), m.handler.UpdateUser)
+				authenticated.PATCH("/self", middleware.RequireScopeMiddleware(domain.ScopeProfileWrite), m.handler.PatchUser)
+				authenticated.GET("/self/activity", middleware.RequireScopeMiddleware(domain.ScopeProfileRead), m.handler.ListActivity)
+
+				apiKeys := authenticated.Group("/self/api-keys")
+				apiKeys.Use(middleware.RejectApiKeyMiddleware())
+				{
+					apiKeys.POST("", m.apiKeyHandler.CreateApiKey)
+					apiKeys.GET("", m.apiKeyHandler.ListApiKeys)
+					apiKeys.PATCH("/:id", m.apiKeyHandler.UpdateApiKey)
+					apiKeys.DELETE("/:id", m.apiKeyHandler.RevokeApiKey)
+				}
+
+				secondFactor := authenticated.Group("/self/second-factor")
+				secondFactor.Use(middleware.RejectApiKeyMiddleware())
+				{
+					secondFactor.POST("/totp", m.secondFactorHandler.EnrollTOTP)
+					secondFactor.POST("/totp/confirm", m.secondFactorHandler.ConfirmTOTP)
+					secondFactor.DELETE("/totp", m.secondFactorHandler.DisableTOTP)
+				}
 			}
 		}
 	}
+
+	// the admin API needs a session of a verified user whose roles grant the permissions
+	adminUsers := router.Group("/v1/admin/users")
+	adminUsers.Use(
+		middleware.AuthMiddleware(m.authenticator),
+		middleware.SecondFactorMiddleware(m.authConfig.Auth.SecondFactorRoutes),
+		middleware.RejectApiKeyMiddleware(),
+		middleware.AccountVerificationMiddleware(),
+	)
+	{
+		adminUsers.GET("", middleware.RequirePermissionMiddleware(domain.PermissionUsersRead), m.adminHandler.ListUsers)
+		adminUsers.GET("/:id", middleware.RequirePermissionMiddleware(domain.PermissionUsersRead), m.adminHandler.GetUser)
+		adminUsers.POST("/:id/suspend", middleware.RequirePermissionMiddleware(domain.PermissionUsersWrite), m.adminHandler.SuspendUser)
+		adminUsers.POST("/:id/deactivate", middleware.RequirePermissionMiddleware(domain.PermissionUsersWrite), m.adminHandler.DeactivateUser)
+		adminUsers.POST("/:id/activate", middleware.RequirePermissionMiddleware(domain.PermissionUsersWrite), m.adminHandler.ActivateUser)
+		adminUsers.POST("/:id/verify", middleware.RequirePermissionMiddleware(domain.PermissionUsersWrite), m.adminHandler.VerifyUser)
+		adminUsers.DELETE("/:id", middleware.RequirePermissionMiddleware(domain.PermissionUsersDelete), m.adminHandler.DeleteUser)
+	}
 }
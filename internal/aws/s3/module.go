@@ -1,32 +1,39 @@
 package s3
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"go-template/internal/aws/cloudwatch"
 	appConfig "go-template/internal/config"
 	"go-template/internal/shared/config"
 	"go-template/internal/shared/infrastructure/logger"
+	"go-template/internal/shared/infrastructure/storage"
+	"io"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// S3Module is the S3 driver of the object storage, the objects are stored in the configured bucket
 type S3Module interface {
-	GetFile(key string) ([]byte, error)
-	UploadFile(key string, file []byte) (*manager.UploadOutput, error)
-	DeleteFile(key string) error
+	storage.ObjectStorage
 	GetBucketName() string
 }
 
 type module struct {
 	s3Config         *S3Config
 	client           *s3.Client
+	uploader         *manager.Uploader
+	presignClient    *s3.PresignClient
 	cloudWatchModule cloudwatch.CloudWatchModule
 }
 
@@ -46,6 +53,8 @@ func NewModule(logger logger.Logger, cloudWatchModule cloudwatch.CloudWatchModul
 
 	return &module{
 		client:           client,
+		uploader:         manager.NewUploader(client),
+		presignClient:    s3.NewPresignClient(client),
 		s3Config:         s3Config,
 		cloudWatchModule: cloudWatchModule,
 	}
@@ -61,57 +70,160 @@ func loadConfig() *S3Config {
 	return s3Config
 }
 
-func (m *module) GetFile(key string) ([]byte, error) {
-	startTime := time.Now()
+func (m *module) UploadFile(ctx context.Context, key string, body io.Reader, opts *storage.UploadOptions) (*storage.UploadResult, error) {
+	defer m.logLatency("upload_file", time.Now())
 
-	downloader := manager.NewDownloader(m.client)
-	buffer := manager.NewWriteAtBuffer([]byte{})
-
-	numBytes, err := downloader.Download(context.TODO(), buffer, &s3.GetObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(m.s3Config.AWS.S3.BucketName),
 		Key:    aws.String(key),
-	})
-
-	defer m.logLatencyMetric("get_file", float64(time.Since(startTime).Milliseconds()))
+		Body:   body,
+	}
+	if opts != nil {
+		input.ContentType = optionalString(opts.ContentType)
+		input.CacheControl = optionalString(opts.CacheControl)
+		input.Metadata = opts.Metadata
+		input.ServerSideEncryption = s3Types.ServerSideEncryption(opts.ServerSideEncryption)
+		input.SSEKMSKeyId = optionalString(opts.SSEKMSKeyID)
+	}
 
+	output, err := m.uploader.Upload(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 
-	return buffer.Bytes()[:numBytes], nil
+	return &storage.UploadResult{
+		Key:                  aws.ToString(output.Key),
+		Location:             output.Location,
+		ETag:                 aws.ToString(output.ETag),
+		VersionID:            aws.ToString(output.VersionID),
+		ServerSideEncryption: string(output.ServerSideEncryption),
+		SSEKMSKeyID:          aws.ToString(output.SSEKMSKeyId),
+	}, nil
 }
 
-func (m *module) UploadFile(key string, file []byte) (*manager.UploadOutput, error) {
-	startTime := time.Now()
+// GetFile measures the latency until the response headers, the body is read by the caller
+func (m *module) GetFile(ctx context.Context, key string, opts *storage.GetOptions) (*storage.Object, error) {
+	defer m.logLatency("get_file", time.Now())
 
-	uploader := manager.NewUploader(m.client)
-	result, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(m.s3Config.AWS.S3.BucketName),
 		Key:    aws.String(key),
-		Body:   bytes.NewReader(file),
-	})
+	}
+	if opts != nil {
+		input.Range = optionalString(opts.Range)
+	}
 
-	defer m.logLatencyMetric("upload_file", float64(time.Since(startTime).Milliseconds()))
+	output, err := m.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, mapError(err)
+	}
 
-	return result, err
+	return &storage.Object{
+		ObjectInfo: storage.ObjectInfo{
+			Key:                  key,
+			ContentLength:        aws.ToInt64(output.ContentLength),
+			ContentType:          aws.ToString(output.ContentType),
+			CacheControl:         aws.ToString(output.CacheControl),
+			ETag:                 aws.ToString(output.ETag),
+			LastModified:         aws.ToTime(output.LastModified),
+			Metadata:             output.Metadata,
+			ServerSideEncryption: string(output.ServerSideEncryption),
+		},
+		Body:         output.Body,
+		ContentRange: aws.ToString(output.ContentRange),
+	}, nil
 }
 
-func (m *module) DeleteFile(key string) error {
-	startTime := time.Now()
+func (m *module) DeleteFile(ctx context.Context, key string) error {
+	defer m.logLatency("delete_file", time.Now())
 
-	_, err := m.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	_, err := m.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(m.s3Config.AWS.S3.BucketName),
 		Key:    aws.String(key),
 	})
 
-	defer m.logLatencyMetric("delete_file", float64(time.Since(startTime).Milliseconds()))
 	return err
 }
 
+func (m *module) HeadFile(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	defer m.logLatency("head_file", time.Now())
+
+	output, err := m.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(m.s3Config.AWS.S3.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return &storage.ObjectInfo{
+		Key:                  key,
+		ContentLength:        aws.ToInt64(output.ContentLength),
+		ContentType:          aws.ToString(output.ContentType),
+		CacheControl:         aws.ToString(output.CacheControl),
+		ETag:                 aws.ToString(output.ETag),
+		LastModified:         aws.ToTime(output.LastModified),
+		Metadata:             output.Metadata,
+		ServerSideEncryption: string(output.ServerSideEncryption),
+	}, nil
+}
+
+func (m *module) PresignPutFile(ctx context.Context, key, contentType string, contentLength int64, expires time.Duration) (*storage.PresignedRequest, error) {
+	request, err := m.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(m.s3Config.AWS.S3.BucketName),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(contentLength),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, err
+	}
+
+	return newPresignedRequest(request, expires), nil
+}
+
+func (m *module) PresignGetFile(ctx context.Context, key string, expires time.Duration) (*storage.PresignedRequest, error) {
+	request, err := m.presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(m.s3Config.AWS.S3.BucketName),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, err
+	}
+
+	return newPresignedRequest(request, expires), nil
+}
+
+// newPresignedRequest drops the host header, the clients derive it from the URL
+func newPresignedRequest(request *v4.PresignedHTTPRequest, expires time.Duration) *storage.PresignedRequest {
+	header := request.SignedHeader.Clone()
+	header.Del("Host")
+
+	return &storage.PresignedRequest{
+		URL:       request.URL,
+		Method:    request.Method,
+		Header:    header,
+		ExpiresAt: time.Now().Add(expires),
+	}
+}
+
+// optionalString leaves the empty options unset so that S3 applies its defaults
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return aws.String(value)
+}
+
 func (m *module) GetBucketName() string {
 	return m.s3Config.AWS.S3.BucketName
 }
 
+// logLatency is deferred with the start time, the latency is measured when the call returns
+func (m *module) logLatency(action string, startTime time.Time) {
+	m.logLatencyMetric(action, float64(time.Since(startTime).Milliseconds()))
+}
+
 func (m *module) logLatencyMetric(action string, latency float64) {
 	m.cloudWatchModule.PublishMetric(
 		appConfig.App.Name+"/S3",
@@ -120,3 +232,18 @@ func (m *module) logLatencyMetric(action string, latency float64) {
 		types.StandardUnitMilliseconds,
 	)
 }
+
+// mapError turns the status of a failed request into the storage errors, HEAD responses have no error code to match on
+func mapError(err error) error {
+	var responseError *awsHttp.ResponseError
+	if errors.As(err, &responseError) {
+		switch responseError.HTTPStatusCode() {
+		case http.StatusNotFound:
+			return storage.ErrObjectNotFound
+		case http.StatusRequestedRangeNotSatisfiable:
+			return storage.ErrInvalidRange
+		}
+	}
+
+	return err
+}
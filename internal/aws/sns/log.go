@@ -0,0 +1,19 @@
+package sns
+
+import (
+	"go-template/internal/shared/infrastructure/logger"
+)
+
+// logTransport writes the messages to the log instead of publishing them, for the local runs
+type logTransport struct {
+	logger logger.Logger
+}
+
+func NewLogTransport(logger logger.Logger) SNSModule {
+	return &logTransport{logger: logger}
+}
+
+func (t *logTransport) PublishMessage(topicArn string, message string) error {
+	t.logger.Info("Notification to topic ", topicArn, ": ", message)
+	return nil
+}
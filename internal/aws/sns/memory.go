@@ -0,0 +1,37 @@
+package sns
+
+import (
+	"sync"
+)
+
+// Message is a message published to the memory transport
+type Message struct {
+	TopicArn string
+	Message  string
+}
+
+// MemoryTransport keeps the published messages in the process so the tests can inspect them
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) PublishMessage(topicArn string, message string) error {
+	t.mu.Lock()
+	t.messages = append(t.messages, Message{TopicArn: topicArn, Message: message})
+	t.mu.Unlock()
+
+	return nil
+}
+
+// Messages returns the published messages in the order they were published
+func (t *MemoryTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Message(nil), t.messages...)
+}
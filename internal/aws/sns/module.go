@@ -11,6 +11,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 )
 
+// The transports a notification can be published with, selected by notification.transport
+const (
+	TransportSNS    = "sns"
+	TransportLog    = "log"
+	TransportSMTP   = "smtp"
+	TransportMemory = "memory"
+)
+
+// SNSModule publishes a message to a topic, NewModule returns the SNS transport, the others publish without AWS credentials
 type SNSModule interface {
 	PublishMessage(topicArn string, message string) error
 }
@@ -0,0 +1,57 @@
+package sns
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var errMissingRecipient = errors.New("message has no to_addr")
+
+/*
+smtpTransport mails the messages to a local catcher such as MailHog or Mailpit
+- The recipient is the to_addr of the JSON message, the body is the message itself
+- The catcher accepts unauthenticated plain text mail, there is no TLS or auth
+*/
+type smtpTransport struct {
+	address string
+	from    string
+}
+
+func NewSMTPTransport(address, from string) SNSModule {
+	return &smtpTransport{address: address, from: from}
+}
+
+func (t *smtpTransport) PublishMessage(topicArn string, message string) error {
+	var payload struct {
+		Type   string `json:"type"`
+		ToAddr string `json:"to_addr"`
+	}
+	if err := json.Unmarshal([]byte(message), &payload); err != nil {
+		return err
+	}
+	if payload.ToAddr == "" || strings.ContainsAny(payload.ToAddr, "\r\n") {
+		return errMissingRecipient
+	}
+
+	subject := payload.Type
+	if subject == "" {
+		subject = "notification"
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", t.from)
+	fmt.Fprintf(&body, "To: %s\r\n", payload.ToAddr)
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "X-Topic-Arn: %s\r\n", topicArn)
+	body.WriteString("Content-Type: application/json; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	return smtp.SendMail(t.address, nil, t.from, []string{payload.ToAddr}, body.Bytes())
+}
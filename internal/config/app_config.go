@@ -5,12 +5,53 @@ type AppConfig struct {
 	Environment string
 	Server      ServerConfig
 	Database    DatabaseConfig
+	Storage     StorageConfig
+	// Notification is read by the outbox dispatcher that publishes the notifications
+	Notification NotificationConfig
 
 	SecretKey string `mapstructure:"secret_key"`
+	// PreviousSecretKeys are still accepted to verify tokens after the secret key was rotated
+	PreviousSecretKeys []string `mapstructure:"previous_secret_keys"`
+	// Keyring takes precedence over the secret keys when it is set
+	Keyring []SigningKeyConfig `mapstructure:"keyring"`
+}
+
+// SigningKeyConfig describes a key of the keyring, exactly one key is active and signs new tokens
+type SigningKeyConfig struct {
+	ID        string `mapstructure:"kid"`
+	Algorithm string `mapstructure:"algorithm"` // HS256, RS256 or EdDSA
+	Active    bool   `mapstructure:"active"`
+
+	// HS256
+	Secret string `mapstructure:"secret"`
+
+	// RS256 and EdDSA, PEM encoded inline or read from a file
+	// a key without the private key is only used for verification
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKey      string `mapstructure:"public_key"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// StorageConfig selects where the objects are stored, S3 unless configured otherwise
+type StorageConfig struct {
+	Driver    string `mapstructure:"driver"`     // s3, local or memory
+	LocalPath string `mapstructure:"local_path"` // directory of the local driver
+}
+
+// NotificationConfig selects how the notifications of the outbox are published, with SNS unless configured otherwise
+type NotificationConfig struct {
+	Transport        string `mapstructure:"transport"`         // sns, log, smtp or memory
+	SMTPAddress      string `mapstructure:"smtp_address"`      // host:port of the catcher of the smtp transport
+	SMTPFrom         string `mapstructure:"smtp_from"`         // sender of the smtp transport
+	DispatchInterval int    `mapstructure:"dispatch_interval"` // how often the outbox is dispatched (in seconds)
+	MaxAttempts      int    `mapstructure:"max_attempts"`      // attempts before a notification is given up
 }
 
 type ServerConfig struct {
 	Port int
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For header is trusted, the client IP is the peer address when empty
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
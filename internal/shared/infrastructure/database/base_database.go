@@ -12,6 +12,9 @@ type BaseDatabase interface {
 	Close()
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	// Transaction runs fn in a transaction, the queries made with the context given to fn join it
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 	AutoMigrate() error
 }
 
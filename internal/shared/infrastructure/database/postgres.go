@@ -86,29 +86,68 @@ func (db *PostgresDatabase) CheckDBConnection() error {
 	}, 3, 1000*time.Millisecond)
 }
 
+// ExecContext runs in the transaction of the context when there is one, a failed statement aborts it so it is not retried
 func (db *PostgresDatabase) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	var result sql.Result
-	start := time.Now()
+	defer db.logLatency(ctx, query, time.Now())
+
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
 
+	var result sql.Result
 	err := retry(ctx, func() error {
 		var err error
 		result, err = db.conn.ExecContext(ctx, query, args...)
 		return err
 	}, 3, 1000*time.Millisecond)
 
-	defer db.logLatencyMetric(ctx, query, float64(time.Since(start).Milliseconds()))
-
 	return result, err
 }
 
 func (db *PostgresDatabase) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	start := time.Now()
+	defer db.logLatency(ctx, query, time.Now())
+
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+
+	return db.conn.QueryRowContext(ctx, query, args...)
+}
+
+func (db *PostgresDatabase) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.logLatency(ctx, query, time.Now())
+
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+
+	return db.conn.QueryContext(ctx, query, args...)
+}
 
-	row := db.conn.QueryRowContext(ctx, query, args...)
+// Transaction commits when fn returns nil and rolls back otherwise, a nested call joins the transaction of its caller
+func (db *PostgresDatabase) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
 
-	defer db.logLatencyMetric(ctx, query, float64(time.Since(start).Milliseconds()))
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	return row
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type txContextKey struct{}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txContextKey{}).(*sql.Tx)
+	return tx
 }
 
 func (db *PostgresDatabase) AutoMigrate() error {
@@ -134,6 +173,11 @@ func (db *PostgresDatabase) AutoMigrate() error {
 	return nil
 }
 
+// logLatency is deferred with the start time, the latency is measured when the call returns
+func (db *PostgresDatabase) logLatency(ctx context.Context, query string, startTime time.Time) {
+	db.logLatencyMetric(ctx, query, float64(time.Since(startTime).Milliseconds()))
+}
+
 func (db *PostgresDatabase) logLatencyMetric(ctx context.Context, query string, latency float64) {
 	db.cloudWatchModule.PublishMetric(
 		config.App.Name+"/Postgres",
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"go-template/internal/aws/sns"
+	"go-template/internal/shared/infrastructure/logger"
+	"time"
+)
+
+// DefaultDispatchInterval is used when notification.dispatch_interval is not configured
+const DefaultDispatchInterval = 5 * time.Second
+
+// DefaultMaxAttempts is used when notification.max_attempts is not configured
+const DefaultMaxAttempts = 10
+
+const (
+	// dispatchBatchSize is the number of messages claimed at each tick
+	dispatchBatchSize = 100
+	// dispatchLease is how long a claimed message is hidden from the other dispatchers, it is retried after it when the dispatcher dies
+	dispatchLease = time.Minute
+	// the delay before the retry doubles with each failed attempt, from baseBackoff up to maxBackoff
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+/*
+Dispatcher publishes the messages of the outbox with the transport
+- A message is deleted once it is published, it is delivered at least once
+- A failed message is retried with an exponential backoff, it is marked as failed after the last attempt
+- Several dispatchers can share an outbox, a message is claimed by one of them at a time
+*/
+type Dispatcher struct {
+	store        Store
+	transport    sns.SNSModule
+	logger       logger.Logger
+	interval     time.Duration
+	maxAttempts  int
+	shutdownChan chan struct{}
+	doneChan     chan struct{}
+}
+
+func NewDispatcher(store Store, transport sns.SNSModule, logger logger.Logger, interval time.Duration, maxAttempts int) *Dispatcher {
+	if interval <= 0 {
+		interval = DefaultDispatchInterval
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	return &Dispatcher{
+		store:        store,
+		transport:    transport,
+		logger:       logger,
+		interval:     interval,
+		maxAttempts:  maxAttempts,
+		shutdownChan: make(chan struct{}),
+		doneChan:     make(chan struct{}),
+	}
+}
+
+// Start dispatches the outbox at each interval until the dispatcher is shut down
+func (d *Dispatcher) Start() {
+	go func() {
+		defer close(d.doneChan)
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				d.Dispatch(context.Background(), time.Now())
+			case <-d.shutdownChan:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown waits for the batch in flight, its messages are not published twice
+func (d *Dispatcher) Shutdown() {
+	close(d.shutdownChan)
+	<-d.doneChan
+}
+
+// Dispatch publishes the messages that are due at now and returns how many were published
+func (d *Dispatcher) Dispatch(ctx context.Context, now time.Time) int {
+	messages, err := d.store.Claim(ctx, now, dispatchBatchSize, dispatchLease)
+	if err != nil {
+		d.logger.Error("Failed to claim the outbox messages: ", err)
+		return 0
+	}
+
+	published := 0
+	for _, message := range messages {
+		if err := d.transport.PublishMessage(message.Topic, message.Payload); err != nil {
+			d.fail(ctx, message, now, err)
+			continue
+		}
+
+		if err := d.store.Delete(ctx, message.ID); err != nil {
+			// the message is published again once its lease expires
+			d.logger.Error("Failed to delete the published outbox message "+message.ID+": ", err)
+		}
+		published++
+	}
+
+	return published
+}
+
+func (d *Dispatcher) fail(ctx context.Context, message *Message, now time.Time, publishErr error) {
+	if message.Attempts >= d.maxAttempts {
+		d.logger.Error("Giving up on the outbox message "+message.ID+" to "+message.Topic+": ", publishErr)
+		if err := d.store.MarkFailed(ctx, message.ID, publishErr.Error()); err != nil {
+			d.logger.Error("Failed to mark the outbox message "+message.ID+" as failed: ", err)
+		}
+		return
+	}
+
+	d.logger.Warn("Failed to publish the outbox message "+message.ID+", retrying: ", publishErr)
+	if err := d.store.Reschedule(ctx, message.ID, now.Add(backoff(message.Attempts)), publishErr.Error()); err != nil {
+		d.logger.Error("Failed to reschedule the outbox message "+message.ID+": ", err)
+	}
+}
+
+// backoff is the delay before the next attempt of a message that failed attempts times
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
@@ -0,0 +1,122 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"go-template/internal/aws/sns"
+	"testing"
+	"time"
+)
+
+type fakeLogger struct{}
+
+func (l *fakeLogger) Info(args ...interface{})  {}
+func (l *fakeLogger) Warn(args ...interface{})  {}
+func (l *fakeLogger) Error(args ...interface{}) {}
+func (l *fakeLogger) Debug(args ...interface{}) {}
+
+// failingTransport fails the first failures publications and publishes the next ones to the memory transport
+type failingTransport struct {
+	*sns.MemoryTransport
+	failures int
+}
+
+func (t *failingTransport) PublishMessage(topicArn string, message string) error {
+	if t.failures > 0 {
+		t.failures--
+		return errors.New("topic unavailable")
+	}
+	return t.MemoryTransport.PublishMessage(topicArn, message)
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	transport := sns.NewMemoryTransport()
+	dispatcher := NewDispatcher(store, transport, &fakeLogger{}, time.Second, 3)
+
+	store.Enqueue(ctx, "topic", "first")
+	store.Enqueue(ctx, "topic", "second")
+
+	if published := dispatcher.Dispatch(ctx, time.Now()); published != 2 {
+		t.Fatalf("Published should be 2, got %d", published)
+	}
+
+	messages := transport.Messages()
+	if len(messages) != 2 || messages[0].TopicArn != "topic" {
+		t.Errorf("Messages should be published to their topic, got %v", messages)
+	}
+
+	// the published messages are removed from the outbox
+	if published := dispatcher.Dispatch(ctx, time.Now().Add(time.Hour)); published != 0 {
+		t.Errorf("Published should be 0, got %d", published)
+	}
+}
+
+func TestDispatchRetry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	transport := &failingTransport{MemoryTransport: sns.NewMemoryTransport(), failures: 2}
+	dispatcher := NewDispatcher(store, transport, &fakeLogger{}, time.Second, 3)
+
+	store.Enqueue(ctx, "topic", "message")
+	now := time.Now()
+
+	if published := dispatcher.Dispatch(ctx, now); published != 0 {
+		t.Fatalf("Published should be 0, got %d", published)
+	}
+
+	t.Run("Test backoff", func(t *testing.T) {
+		if published := dispatcher.Dispatch(ctx, now.Add(baseBackoff-time.Second)); published != 0 || transport.failures != 1 {
+			t.Errorf("Message should not be retried before its backoff, got %d published", published)
+		}
+
+		now = now.Add(baseBackoff)
+		dispatcher.Dispatch(ctx, now)
+		if transport.failures != 0 {
+			t.Fatalf("Message should be retried after its backoff")
+		}
+
+		// the delay doubles after the second failure
+		if published := dispatcher.Dispatch(ctx, now.Add(baseBackoff)); published != 0 {
+			t.Errorf("Message should not be retried before its backoff, got %d published", published)
+		}
+		if published := dispatcher.Dispatch(ctx, now.Add(2*baseBackoff)); published != 1 {
+			t.Errorf("Message should be published once the topic is available, got %d published", published)
+		}
+	})
+
+	t.Run("Test max attempts", func(t *testing.T) {
+		transport.failures = 3
+		store.Enqueue(ctx, "topic", "undeliverable")
+
+		now = time.Now()
+		for attempt := 0; attempt < 3; attempt++ {
+			now = now.Add(maxBackoff)
+			dispatcher.Dispatch(ctx, now)
+		}
+		if transport.failures != 0 {
+			t.Fatalf("Message should be attempted 3 times, %d failures left", transport.failures)
+		}
+
+		// the message is given up after its last attempt
+		if published := dispatcher.Dispatch(ctx, now.Add(maxBackoff)); published != 0 {
+			t.Errorf("Message should not be retried after the max attempts, got %d published", published)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  baseBackoff,
+		2:  2 * baseBackoff,
+		3:  4 * baseBackoff,
+		20: maxBackoff,
+	}
+
+	for attempts, want := range tests {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) should be %v, got %v", attempts, want, got)
+		}
+	}
+}
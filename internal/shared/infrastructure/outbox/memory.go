@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+type memoryMessage struct {
+	Message
+	failed bool
+}
+
+// memoryStore keeps the outbox in the process for the tests, it does not take part in database transactions
+type memoryStore struct {
+	mu       sync.Mutex
+	messages map[string]*memoryMessage
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{messages: make(map[string]*memoryMessage)}
+}
+
+func (s *memoryStore) Enqueue(ctx context.Context, topic, payload string) error {
+	now := time.Now()
+	message := &memoryMessage{Message: Message{
+		ID:            uuidv7.New().String(),
+		Topic:         topic,
+		Payload:       payload,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}}
+
+	s.mu.Lock()
+	s.messages[message.ID] = message
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *memoryStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*memoryMessage
+	for _, message := range s.messages {
+		if !message.failed && !message.NextAttemptAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	claimed := make([]*Message, 0, min(limit, len(due)))
+	for _, message := range due[:min(limit, len(due))] {
+		message.Attempts++
+		message.NextAttemptAt = now.Add(lease)
+		copied := message.Message
+		claimed = append(claimed, &copied)
+	}
+
+	return claimed, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.messages, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *memoryStore) Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if message, ok := s.messages[id]; ok {
+		message.NextAttemptAt = nextAttemptAt
+		message.LastError = lastError
+	}
+
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, id string, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if message, ok := s.messages[id]; ok {
+		message.failed = true
+		message.LastError = lastError
+	}
+
+	return nil
+}
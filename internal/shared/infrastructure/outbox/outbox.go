@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a notification waiting in the outbox to be published to its topic
+type Message struct {
+	ID            string
+	Topic         string
+	Payload       string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// Outbox stores the notifications, Enqueue joins the transaction of the context so a message is only published once its transaction commits
+type Outbox interface {
+	Enqueue(ctx context.Context, topic, payload string) error
+}
+
+// Store is the outbox read by the dispatcher
+type Store interface {
+	Outbox
+	// Claim leases up to limit messages that are due at now until now+lease and counts an attempt for each of them
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error)
+	// Delete removes a published message
+	Delete(ctx context.Context, id string) error
+	// Reschedule records a failed attempt, the message is retried at nextAttemptAt
+	Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+	// MarkFailed records the last failed attempt, the message is not retried
+	MarkFailed(ctx context.Context, id string, lastError string) error
+}
@@ -0,0 +1,87 @@
+package outbox
+
+import (
+	"context"
+	"go-template/internal/shared/infrastructure/database"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+type postgresStore struct {
+	db database.BaseDatabase
+}
+
+func NewPostgresStore(db database.BaseDatabase) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Enqueue(ctx context.Context, topic, payload string) error {
+	now := time.Now()
+	query := `INSERT INTO notification_outbox (id, topic, payload, next_attempt_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := s.db.ExecContext(ctx, query, uuidv7.New().String(), topic, payload, now, now); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// Claim skips the rows locked by another dispatcher, the lease keeps them from being claimed twice once the statement commits
+func (s *postgresStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error) {
+	query := `
+		UPDATE notification_outbox SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE failed_at IS NULL AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, payload, attempts, next_attempt_at, created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, database.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		message := &Message{}
+		if err := rows.Scan(&message.ID, &message.Topic, &message.Payload, &message.Attempts, &message.NextAttemptAt, &message.CreatedAt); err != nil {
+			return nil, database.ErrDatabaseError
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.ErrDatabaseError
+	}
+
+	return messages, nil
+}
+
+func (s *postgresStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM notification_outbox WHERE id = $1`, id); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (s *postgresStore) Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	query := `UPDATE notification_outbox SET next_attempt_at = $2, last_error = $3 WHERE id = $1`
+	if _, err := s.db.ExecContext(ctx, query, id, nextAttemptAt, lastError); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
+
+func (s *postgresStore) MarkFailed(ctx context.Context, id string, lastError string) error {
+	query := `UPDATE notification_outbox SET failed_at = $2, last_error = $3 WHERE id = $1`
+	if _, err := s.db.ExecContext(ctx, query, id, time.Now(), lastError); err != nil {
+		return database.ErrDatabaseError
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+var errInvalidKey = errors.New("invalid object key")
+
+// localMetadata is stored next to the content of an object
+type localMetadata struct {
+	ContentType  string            `json:"content_type"`
+	CacheControl string            `json:"cache_control,omitempty"`
+	ETag         string            `json:"etag"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+/*
+localStorage keeps the objects on disk for the local runs
+- The content of an object is stored under objects/ with its key as path, its metadata under meta/
+- The files are written to a temporary file first, a reader never sees a partial object
+*/
+type localStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) ObjectStorage {
+	return &localStorage{root: root}
+}
+
+func (s *localStorage) UploadFile(ctx context.Context, key string, body io.Reader, opts *UploadOptions) (*UploadResult, error) {
+	contentPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := md5.New()
+	err = writeFileAtomically(contentPath, func(file *os.File) error {
+		_, err := io.Copy(io.MultiWriter(file, hash), &contextReader{ctx: ctx, reader: body})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := newObjectInfo(key, opts, `"`+hex.EncodeToString(hash.Sum(nil))+`"`, time.Now())
+	metadata, err := json.Marshal(&localMetadata{
+		ContentType:  info.ContentType,
+		CacheControl: info.CacheControl,
+		ETag:         info.ETag,
+		Metadata:     info.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeFileAtomically(metaPath, func(file *os.File) error {
+		_, err := file.Write(metadata)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{Key: key, Location: "file://" + contentPath, ETag: info.ETag}, nil
+}
+
+func (s *localStorage) GetFile(ctx context.Context, key string, opts *GetOptions) (*Object, error) {
+	info, err := s.HeadFile(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	contentPath, _, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(contentPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	result := &Object{ObjectInfo: *info}
+	var reader io.Reader = file
+
+	if opts != nil {
+		byteRange, err := parseRange(opts.Range, info.ContentLength)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if byteRange != nil {
+			if _, err := file.Seek(byteRange.start, io.SeekStart); err != nil {
+				file.Close()
+				return nil, err
+			}
+			reader = io.LimitReader(file, byteRange.length())
+			result.ContentLength = byteRange.length()
+			result.ContentRange = byteRange.contentRange(info.ContentLength)
+		}
+	}
+
+	result.Body = &readCloser{Reader: &contextReader{ctx: ctx, reader: reader}, Closer: file}
+	return result, nil
+}
+
+func (s *localStorage) DeleteFile(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	contentPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range []string{contentPath, metaPath} {
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *localStorage) HeadFile(ctx context.Context, key string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	contentPath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(contentPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	// an object copied into the directory by hand has no metadata
+	metadata := localMetadata{ContentType: "binary/octet-stream"}
+	if content, err := os.ReadFile(metaPath); err == nil {
+		if err := json.Unmarshal(content, &metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ObjectInfo{
+		Key:           key,
+		ContentLength: stat.Size(),
+		ContentType:   metadata.ContentType,
+		CacheControl:  metadata.CacheControl,
+		ETag:          metadata.ETag,
+		LastModified:  stat.ModTime(),
+		Metadata:      metadata.Metadata,
+	}, nil
+}
+
+func (s *localStorage) PresignPutFile(ctx context.Context, key, contentType string, contentLength int64, expires time.Duration) (*PresignedRequest, error) {
+	return nil, ErrPresignNotSupported
+}
+
+func (s *localStorage) PresignGetFile(ctx context.Context, key string, expires time.Duration) (*PresignedRequest, error) {
+	return nil, ErrPresignNotSupported
+}
+
+// paths returns where the content and the metadata of the object are stored, the key cannot point outside of the root
+func (s *localStorage) paths(key string) (string, string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" {
+		return "", "", errInvalidKey
+	}
+
+	relative := filepath.FromSlash(cleaned)
+	return filepath.Join(s.root, "objects", relative), filepath.Join(s.root, "meta", relative+".json"), nil
+}
+
+// writeFileAtomically writes a temporary file in the directory of filePath and renames it once it is complete
+func writeFileAtomically(filePath string, write func(file *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	info    ObjectInfo
+	content []byte
+}
+
+// memoryStorage keeps the objects in the process, they are lost on restart
+type memoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+}
+
+// NewMemoryStorage returns an empty storage for the tests and the local runs that need no files
+func NewMemoryStorage() ObjectStorage {
+	return &memoryStorage{objects: make(map[string]*memoryObject)}
+}
+
+func (s *memoryStorage) UploadFile(ctx context.Context, key string, body io.Reader, opts *UploadOptions) (*UploadResult, error) {
+	content, err := io.ReadAll(&contextReader{ctx: ctx, reader: body})
+	if err != nil {
+		return nil, err
+	}
+
+	object := &memoryObject{
+		info:    newObjectInfo(key, opts, md5ETag(content), time.Now()),
+		content: content,
+	}
+	object.info.ContentLength = int64(len(content))
+
+	s.mu.Lock()
+	s.objects[key] = object
+	s.mu.Unlock()
+
+	return &UploadResult{Key: key, Location: "memory://" + key, ETag: object.info.ETag}, nil
+}
+
+func (s *memoryStorage) GetFile(ctx context.Context, key string, opts *GetOptions) (*Object, error) {
+	object, err := s.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Object{ObjectInfo: object.info}
+	content := object.content
+
+	if opts != nil {
+		size := int64(len(content))
+		byteRange, err := parseRange(opts.Range, size)
+		if err != nil {
+			return nil, err
+		}
+		if byteRange != nil {
+			content = content[byteRange.start : byteRange.end+1]
+			result.ContentLength = byteRange.length()
+			result.ContentRange = byteRange.contentRange(size)
+		}
+	}
+
+	result.Body = io.NopCloser(bytes.NewReader(content))
+	return result, nil
+}
+
+func (s *memoryStorage) DeleteFile(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.objects, key)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *memoryStorage) HeadFile(ctx context.Context, key string) (*ObjectInfo, error) {
+	object, err := s.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	info := object.info
+	return &info, nil
+}
+
+func (s *memoryStorage) PresignPutFile(ctx context.Context, key, contentType string, contentLength int64, expires time.Duration) (*PresignedRequest, error) {
+	return nil, ErrPresignNotSupported
+}
+
+func (s *memoryStorage) PresignGetFile(ctx context.Context, key string, expires time.Duration) (*PresignedRequest, error) {
+	return nil, ErrPresignNotSupported
+}
+
+func (s *memoryStorage) get(ctx context.Context, key string) (*memoryObject, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	object, ok := s.objects[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	return object, nil
+}
+
+// newObjectInfo keeps the options of an upload, the content length is set by the driver
+func newObjectInfo(key string, opts *UploadOptions, etag string, lastModified time.Time) ObjectInfo {
+	info := ObjectInfo{
+		Key:          key,
+		ContentType:  "binary/octet-stream",
+		ETag:         etag,
+		LastModified: lastModified,
+	}
+	if opts != nil {
+		if opts.ContentType != "" {
+			info.ContentType = opts.ContentType
+		}
+		info.CacheControl = opts.CacheControl
+		info.Metadata = maps.Clone(opts.Metadata)
+	}
+
+	return info
+}
+
+// md5ETag returns the ETag S3 gives an object uploaded in a single part
+func md5ETag(content []byte) string {
+	sum := md5.Sum(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// byteRange is an inclusive range of an object
+type byteRange struct {
+	start, end int64
+}
+
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return "bytes " + strconv.FormatInt(r.start, 10) + "-" + strconv.FormatInt(r.end, 10) + "/" + strconv.FormatInt(size, 10)
+}
+
+/*
+parseRange reads a Range header the way S3 does for the local drivers
+- A header that is not a single byte range is ignored, the whole object is read
+- A range starting past the end of the object fails with ErrInvalidRange, an end past it is cut to the last byte
+*/
+func parseRange(header string, size int64) (*byteRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+
+	// suffix range, the last bytes of the object
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return nil, nil
+		}
+		if n == 0 || size == 0 {
+			return nil, ErrInvalidRange
+		}
+		return &byteRange{start: max(size-n, 0), end: size - 1}, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return nil, nil
+		}
+	}
+
+	if start >= size {
+		return nil, ErrInvalidRange
+	}
+	return &byteRange{start: start, end: min(end, size-1)}, nil
+}
+
+// contextReader stops reading once the context is cancelled
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// the drivers selected by storage.driver
+const (
+	DriverS3     = "s3"
+	DriverLocal  = "local"
+	DriverMemory = "memory"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrInvalidRange is returned when the requested range starts past the end of the object
+	ErrInvalidRange = errors.New("range not satisfiable")
+	// ErrPresignNotSupported is returned by the drivers the clients cannot reach directly
+	ErrPresignNotSupported = errors.New("presigned requests are not supported by the storage driver")
+)
+
+// ObjectStorage streams the objects of a bucket, every call stops when its context is cancelled
+type ObjectStorage interface {
+	// UploadFile streams body to the object, an existing object is replaced
+	UploadFile(ctx context.Context, key string, body io.Reader, opts *UploadOptions) (*UploadResult, error)
+	// GetFile streams the object, it fails with ErrObjectNotFound when there is none and ErrInvalidRange when the range cannot be served
+	GetFile(ctx context.Context, key string, opts *GetOptions) (*Object, error)
+	// DeleteFile removes the object, removing a missing object is not an error
+	DeleteFile(ctx context.Context, key string) error
+	// HeadFile reads the metadata of the object, it fails with ErrObjectNotFound when there is none
+	HeadFile(ctx context.Context, key string) (*ObjectInfo, error)
+	// PresignPutFile returns a URL the client uploads the object to, the content type and length are part of the signature
+	PresignPutFile(ctx context.Context, key, contentType string, contentLength int64, expires time.Duration) (*PresignedRequest, error)
+	// PresignGetFile returns a URL the client downloads the object from
+	PresignGetFile(ctx context.Context, key string, expires time.Duration) (*PresignedRequest, error)
+}
+
+// UploadOptions are stored with the object, the zero value keeps the defaults of the driver
+type UploadOptions struct {
+	ContentType  string
+	CacheControl string
+	// Metadata is stored with the object, S3 lowercases the keys
+	Metadata map[string]string
+	// ServerSideEncryption is AES256 or aws:kms, SSEKMSKeyID selects the key of aws:kms (the AWS managed key when empty)
+	// only S3 encrypts the objects, the other drivers ignore both
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+}
+
+// GetOptions select what is read from the object
+type GetOptions struct {
+	// Range is the value of an HTTP Range header, e.g. bytes=0-1023, the whole object is read when empty
+	Range string
+}
+
+// UploadResult describes the stored object
+type UploadResult struct {
+	Key                  string
+	Location             string
+	ETag                 string
+	VersionID            string
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+}
+
+// ObjectInfo is the metadata of an object
+type ObjectInfo struct {
+	Key string
+	// ContentLength is the size of the object, or of the range when one was requested
+	ContentLength        int64
+	ContentType          string
+	CacheControl         string
+	ETag                 string
+	LastModified         time.Time
+	Metadata             map[string]string
+	ServerSideEncryption string
+}
+
+// Object is a streamed object, the caller must close Body
+type Object struct {
+	ObjectInfo
+	Body io.ReadCloser
+	// ContentRange is the Content-Range of a range read, e.g. bytes 0-1023/4096, empty otherwise
+	ContentRange string
+}
+
+// PresignedRequest is the request a client sends to the storage without credentials, Header holds the signed headers to send as is
+type PresignedRequest struct {
+	URL       string
+	Method    string
+	Header    http.Header
+	ExpiresAt time.Time
+}
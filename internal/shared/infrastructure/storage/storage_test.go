@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header string
+		want   *byteRange
+		err    error
+	}{
+		{"", nil, nil},
+		{"bytes=0-3", &byteRange{0, 3}, nil},
+		{"bytes=4-", &byteRange{4, 9}, nil},
+		{"bytes=-3", &byteRange{7, 9}, nil},
+		{"bytes=-30", &byteRange{0, 9}, nil},
+		{"bytes=5-100", &byteRange{5, 9}, nil},
+		{"bytes=10-", nil, ErrInvalidRange},
+		{"bytes=-0", nil, ErrInvalidRange},
+		{"bytes=0-1,4-5", nil, nil},
+		{"bytes=5-2", nil, nil},
+		{"items=0-3", nil, nil},
+	}
+
+	for _, test := range tests {
+		got, err := parseRange(test.header, 10)
+		if err != test.err {
+			t.Errorf("parseRange(%q) error should be %v, got %v", test.header, test.err, err)
+			continue
+		}
+		if (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("parseRange(%q) should be %v, got %v", test.header, test.want, got)
+		}
+	}
+}
+
+func TestDrivers(t *testing.T) {
+	drivers := map[string]func(t *testing.T) ObjectStorage{
+		DriverMemory: func(t *testing.T) ObjectStorage { return NewMemoryStorage() },
+		DriverLocal:  func(t *testing.T) ObjectStorage { return NewLocalStorage(t.TempDir()) },
+	}
+
+	for name, newStorage := range drivers {
+		t.Run("Test "+name, func(t *testing.T) {
+			testObjectStorage(t, newStorage(t))
+		})
+	}
+}
+
+// testObjectStorage checks the behaviour shared by every driver
+func testObjectStorage(t *testing.T, storage ObjectStorage) {
+	ctx := context.Background()
+	opts := &UploadOptions{ContentType: "image/png", CacheControl: "private", Metadata: map[string]string{"user-id": "1"}}
+
+	result, err := storage.UploadFile(ctx, "user/id/me.png", strings.NewReader("0123456789"), opts)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if result.ETag != `"781e5e245d69b566979b86e28d23f2c7"` {
+		t.Errorf("ETag should be the MD5 of the content, got %s", result.ETag)
+	}
+
+	info, err := storage.HeadFile(ctx, "user/id/me.png")
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if info.ContentLength != 10 || info.ContentType != "image/png" || info.CacheControl != "private" || info.Metadata["user-id"] != "1" {
+		t.Errorf("Metadata should be kept, got %+v", info)
+	}
+
+	object, err := storage.GetFile(ctx, "user/id/me.png", &GetOptions{Range: "bytes=2-4"})
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	content, _ := io.ReadAll(object.Body)
+	object.Body.Close()
+	if string(content) != "234" || object.ContentLength != 3 || object.ContentRange != "bytes 2-4/10" {
+		t.Errorf("Range should be served, got %q with %d and %s", content, object.ContentLength, object.ContentRange)
+	}
+
+	if _, err := storage.GetFile(ctx, "user/id/me.png", &GetOptions{Range: "bytes=20-"}); err != ErrInvalidRange {
+		t.Errorf("Error should be ErrInvalidRange, got %v", err)
+	}
+
+	if err := storage.DeleteFile(ctx, "user/id/me.png"); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if _, err := storage.GetFile(ctx, "user/id/me.png", nil); err != ErrObjectNotFound {
+		t.Errorf("Error should be ErrObjectNotFound, got %v", err)
+	}
+	if err := storage.DeleteFile(ctx, "user/id/me.png"); err != nil {
+		t.Errorf("Deleting a missing object should not fail, got %v", err)
+	}
+
+	if _, err := storage.PresignGetFile(ctx, "user/id/me.png", 0); err != ErrPresignNotSupported {
+		t.Errorf("Error should be ErrPresignNotSupported, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := storage.UploadFile(cancelled, "user/id/other.png", strings.NewReader("content"), nil); err != context.Canceled {
+		t.Errorf("Error should be context.Canceled, got %v", err)
+	}
+}
+
+func TestLocalStorageKeys(t *testing.T) {
+	root := t.TempDir()
+	storage := NewLocalStorage(filepath.Join(root, "bucket"))
+
+	// the key cannot leave the root of the storage
+	if _, err := storage.UploadFile(context.Background(), "../../escaped.png", strings.NewReader("content"), nil); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "escaped.png")); !os.IsNotExist(err) {
+		t.Errorf("Object should be stored under the root, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "bucket", "objects", "escaped.png")); err != nil {
+		t.Errorf("Object should be stored under the root, got %v", err)
+	}
+}
@@ -42,6 +42,16 @@ func (m *MockDatabase) QueryRowContext(ctx context.Context, query string, args .
 	return arguments.Get(0).(*sql.Row)
 }
 
+func (m *MockDatabase) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	arguments := m.Called(ctx, query, args)
+	return arguments.Get(0).(*sql.Rows), arguments.Error(1)
+}
+
+func (m *MockDatabase) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Called(ctx)
+	return fn(ctx)
+}
+
 func (m *MockDatabase) AutoMigrate() error {
 	args := m.Called()
 	return args.Error(0)
@@ -1,6 +1,9 @@
 package http
 
 import (
+	"go-template/internal/config"
+	"log"
+
 	"github.com/gin-gonic/gin"
 
 	swaggerFiles "github.com/swaggo/files"
@@ -17,8 +20,17 @@ type Server struct {
 }
 
 func NewServer() *Server {
+	router := gin.New()
+	// the handlers pass the gin context down, it is cancelled with the request only with the fallback
+	router.ContextWithFallback = true
+
+	// the client IP is throttled and audited, a forwarded address is only taken from the configured proxies
+	if err := router.SetTrustedProxies(config.App.Server.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set the trusted proxies: %v", err)
+	}
+
 	return &Server{
-		router:  gin.New(),
+		router:  router,
 		modules: make([]Module, 0),
 	}
 }
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"go-template/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clientIP := func(server *Server) string {
+		var ip string
+		server.GetRouter().GET("/ip", func(c *gin.Context) { ip = c.ClientIP() })
+
+		req, _ := http.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		server.GetRouter().ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	// a client cannot pick its address by forwarding a header
+	if ip := clientIP(NewServer()); ip != "10.0.0.1" {
+		t.Errorf("Client IP should be the peer address, got %s", ip)
+	}
+
+	config.App.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	defer func() { config.App.Server.TrustedProxies = nil }()
+
+	if ip := clientIP(NewServer()); ip != "203.0.113.7" {
+		t.Errorf("Client IP should be forwarded by the trusted proxy, got %s", ip)
+	}
+}
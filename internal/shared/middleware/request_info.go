@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"go-template/internal/shared/requestinfo"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samborkent/uuidv7"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern keeps the request ids of the clients short and printable
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestInfo stores the request id, the client IP and the user agent in the context of the request,
+// the request id of the client is kept when it is valid, a new one is generated otherwise
+func RequestInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuidv7.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		info := requestinfo.Info{
+			RequestID: requestID,
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		c.Request = c.Request.WithContext(requestinfo.NewContext(c.Request.Context(), info))
+
+		c.Next()
+	}
+}
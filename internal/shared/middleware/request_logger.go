@@ -5,6 +5,7 @@ import (
 	"go-template/internal/aws/cloudwatch"
 	"go-template/internal/config"
 	"go-template/internal/shared/infrastructure/logger"
+	"go-template/internal/shared/requestinfo"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
@@ -24,7 +25,7 @@ func NewRequestLoggerMiddleware(logger logger.Logger, cloudWatchModule cloudwatc
 }
 
 // Handler logs the request
-// It logs request method, request path, request ip, latency, and response status code
+// It logs request id, request method, request path, request ip, latency, and response status code
 func (m *RequestLoggerMiddleware) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -36,7 +37,8 @@ func (m *RequestLoggerMiddleware) Handler() gin.HandlerFunc {
 
 		m.logger.Info(
 			fmt.Sprintf(
-				`{"method": "%s", "path": "%s", "client_ip": "%s", "latency": "%s", "status": %d, "errors": "%s"}`,
+				`{"request_id": "%s", "method": "%s", "path": "%s", "client_ip": "%s", "latency": "%s", "status": %d, "errors": "%s"}`,
+				requestinfo.FromContext(c.Request.Context()).RequestID,
 				c.Request.Method,
 				c.Request.URL.Path,
 				c.ClientIP(),
@@ -0,0 +1,21 @@
+package requestinfo
+
+import "context"
+
+// Info describes the HTTP request a context belongs to, it is empty outside of a request
+type Info struct {
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
@@ -0,0 +1,76 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"go-template/internal/user/domain"
+	"go-template/pkg/apperrors"
+)
+
+// GetProfilePicVersion returns a version of the profile pic, the current one when version is 0
+func (s *userApplicationService) GetProfilePicVersion(ctx context.Context, user *domain.User, version int) (*domain.ProfilePic, *apperrors.Error) {
+	if version == 0 {
+		return s.GetProfilePic(ctx, user)
+	}
+
+	profilePic, err := s.userRepository.GetProfilePicVersion(ctx, user, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.logger.Debug("profile pic version not found", err)
+			return nil, apperrors.NewNotFound("profile pic version not found")
+		}
+
+		s.logger.Error("Failed to get profile pic version from database", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return profilePic, nil
+}
+
+// GetAvatar returns the current profile pic of another user, the accounts that are deleted or not active have none
+func (s *userApplicationService) GetAvatar(ctx context.Context, userID string) (*domain.ProfilePic, *apperrors.Error) {
+	user := domain.NewUser(userID)
+
+	profile, err := s.userRepository.GetProfile(ctx, user)
+	if err != nil && err != sql.ErrNoRows {
+		s.logger.Error("Failed to get profile from database", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	// the same error as a user without a profile pic, the response does not tell whether the account exists
+	if err == sql.ErrNoRows || !profile.IsActive() {
+		s.logger.Debug("avatar not found", err)
+		return nil, apperrors.NewNotFound("avatar not found")
+	}
+
+	profilePic, apperr := s.GetProfilePic(ctx, user)
+	if apperr != nil {
+		if apperr.Type == apperrors.NotFound {
+			return nil, apperrors.NewNotFound("avatar not found")
+		}
+		return nil, apperr
+	}
+
+	return profilePic, nil
+}
+
+// OpenProfilePicContent streams the file of the profile pic, or of its variant when size is set
+func (s *userApplicationService) OpenProfilePicContent(ctx context.Context, profilePic *domain.ProfilePic, size int, byteRange string) (*domain.ProfilePicContent, *apperrors.Error) {
+	key, ok := profilePic.FileKey(size)
+	if !ok {
+		return nil, apperrors.NewNotFound("profile pic variant not found")
+	}
+
+	content, err := s.userService.OpenProfilePic(ctx, key, byteRange)
+	if err != nil {
+		if err == domain.ErrProfilePicRangeNotSatisfiable {
+			s.logger.Debug("Invalid profile pic range", err)
+			return nil, apperrors.NewRangeNotSatisfiable(err.Error())
+		}
+
+		s.logger.Error("Failed to get profile pic from S3", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return content, nil
+}
@@ -0,0 +1,159 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-template/internal/user/domain"
+	"go-template/pkg/apperrors"
+	"time"
+)
+
+// CreateProfilePicUpload returns a slot and the presigned URL the client uploads the file to
+func (s *userApplicationService) CreateProfilePicUpload(ctx context.Context, user *domain.User, filename, contentType string, size int64) (*domain.PicUpload, *domain.PresignedURL, *apperrors.Error) {
+	upload, presignedURL, err := s.userService.CreatePicUpload(ctx, user.ID, filename, contentType, size)
+	if err != nil {
+		var tooLarge *domain.ProfilePicTooLargeError
+		if errors.As(err, &tooLarge) {
+			s.logger.Debug("Profile pic is too large", err)
+			return nil, nil, apperrors.NewPayloadTooLarge(tooLarge.MaxSize, tooLarge.Size)
+		}
+
+		if err == domain.ErrUnsupportedProfilePicFormat || err == domain.ErrProfilePicExtensionMismatch || err == domain.ErrDirectTransferUnavailable {
+			s.logger.Debug("Invalid profile pic upload", err)
+			return nil, nil, apperrors.NewUnprocessableEntity(err.Error())
+		}
+
+		s.logger.Error("Failed to presign profile pic upload", err)
+		return nil, nil, apperrors.NewInternal()
+	}
+
+	if err := s.userRepository.CreatePicUpload(ctx, upload); err != nil {
+		s.logger.Error("Failed to save profile pic upload to database", err)
+		return nil, nil, apperrors.NewInternal()
+	}
+
+	return upload, presignedURL, nil
+}
+
+/*
+ConfirmProfilePicUpload turns the uploaded file into the current profile pic
+- The object is checked with a HEAD request, then validated and processed like a proxied upload
+- The slot and the staging object are removed once the profile pic is saved, a refused file is removed as well
+*/
+func (s *userApplicationService) ConfirmProfilePicUpload(ctx context.Context, user *domain.User, uploadID string) (*domain.ProfilePic, *apperrors.Error) {
+	upload, err := s.userRepository.GetPicUpload(ctx, user, uploadID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.logger.Debug("profile pic upload not found", err)
+			return nil, apperrors.NewNotFound("profile pic upload not found")
+		}
+
+		s.logger.Error("Failed to get profile pic upload from database", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if upload.IsExpired() {
+		s.logger.Debug("profile pic upload expired", nil)
+		return nil, apperrors.NewUnprocessableEntity(domain.ErrPicUploadExpired.Error())
+	}
+
+	content, err := s.userService.OpenPicUpload(ctx, upload)
+	if err != nil {
+		var tooLarge *domain.ProfilePicTooLargeError
+		switch {
+		case errors.As(err, &tooLarge):
+			s.logger.Debug("Profile pic is too large", err)
+			s.discardPicUpload(ctx, upload)
+			return nil, apperrors.NewPayloadTooLarge(tooLarge.MaxSize, tooLarge.Size)
+		case err == domain.ErrPicUploadMismatch:
+			s.logger.Debug("Invalid profile pic upload", err)
+			s.discardPicUpload(ctx, upload)
+			return nil, apperrors.NewUnprocessableEntity(err.Error())
+		case err == domain.ErrPicUploadMissing:
+			// the client can still upload until the slot expires
+			s.logger.Debug("Invalid profile pic upload", err)
+			return nil, apperrors.NewUnprocessableEntity(err.Error())
+		}
+
+		s.logger.Error("Failed to open profile pic upload from S3", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	profilePic, apperr := s.storeProfilePic(ctx, user, upload.Filename, content)
+	content.Close()
+	if apperr != nil {
+		// a refused file cannot become valid, the client has to request a new slot
+		if apperr.Type != apperrors.Internal {
+			s.discardPicUpload(ctx, upload)
+		}
+		return nil, apperr
+	}
+
+	s.discardPicUpload(ctx, upload)
+
+	return profilePic, nil
+}
+
+// GetProfilePicURL returns a presigned URL of the current profile pic, or of its variant when size is set
+func (s *userApplicationService) GetProfilePicURL(ctx context.Context, user *domain.User, size int) (*domain.PresignedURL, *apperrors.Error) {
+	profilePic, apperr := s.GetProfilePic(ctx, user)
+	if apperr != nil {
+		return nil, apperr
+	}
+
+	key, ok := profilePic.FileKey(size)
+	if !ok {
+		return nil, apperrors.NewNotFound("profile pic variant not found")
+	}
+
+	presignedURL, err := s.userService.PresignProfilePic(ctx, key)
+	if err != nil {
+		if err == domain.ErrDirectTransferUnavailable {
+			s.logger.Debug("Profile pic download cannot be presigned", err)
+			return nil, apperrors.NewUnprocessableEntity(err.Error())
+		}
+
+		s.logger.Error("Failed to presign profile pic download", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return presignedURL, nil
+}
+
+// PurgeExpiredPicUploads removes the slots that were never confirmed and their staging objects, it returns how many were purged
+func (s *userApplicationService) PurgeExpiredPicUploads(ctx context.Context) int {
+	uploads, err := s.userRepository.ListExpiredPicUploads(ctx, time.Now(), purgeBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to list the expired profile pic uploads", err)
+		return 0
+	}
+
+	purged := 0
+	for _, upload := range uploads {
+		if err := s.userService.DeleteProfilePic(ctx, upload.S3Key); err != nil {
+			s.logger.Error("Failed to delete profile pic upload "+upload.ID+" from S3", err)
+			continue
+		}
+
+		if err := s.userRepository.DeletePicUpload(ctx, upload); err != nil {
+			s.logger.Error("Failed to delete profile pic upload "+upload.ID+" from database", err)
+			continue
+		}
+		purged++
+	}
+
+	return purged
+}
+
+// discardPicUpload removes the staging object and the slot, a failure is only logged since the purge retries expired slots
+func (s *userApplicationService) discardPicUpload(ctx context.Context, upload *domain.PicUpload) {
+	if err := s.userService.DeleteProfilePic(ctx, upload.S3Key); err != nil {
+		s.logger.Error("Failed to delete profile pic upload "+upload.ID+" from S3", err)
+		return
+	}
+
+	if err := s.userRepository.DeletePicUpload(ctx, upload); err != nil {
+		s.logger.Error("Failed to delete profile pic upload "+upload.ID+" from database", err)
+	}
+}
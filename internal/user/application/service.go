@@ -3,48 +3,100 @@ package application
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"go-template/internal/shared/infrastructure/logger"
 	"go-template/internal/user/domain"
 	"go-template/pkg/apperrors"
+	"io"
 	"mime/multipart"
+	"path"
 	"strings"
+	"time"
 )
 
 type UserApplicationService interface {
 	UploadProfilePic(ctx context.Context, user *domain.User, profilePicFile *multipart.FileHeader) (*domain.ProfilePic, *apperrors.Error)
+	CreateProfilePicUpload(ctx context.Context, user *domain.User, filename, contentType string, size int64) (*domain.PicUpload, *domain.PresignedURL, *apperrors.Error)
+	ConfirmProfilePicUpload(ctx context.Context, user *domain.User, uploadID string) (*domain.ProfilePic, *apperrors.Error)
+	GetProfilePicURL(ctx context.Context, user *domain.User, size int) (*domain.PresignedURL, *apperrors.Error)
 	DeleteProfilePic(ctx context.Context, user *domain.User) *apperrors.Error
 	GetProfilePic(ctx context.Context, user *domain.User) (*domain.ProfilePic, *apperrors.Error)
+	GetProfilePicVersion(ctx context.Context, user *domain.User, version int) (*domain.ProfilePic, *apperrors.Error)
+	GetAvatar(ctx context.Context, userID string) (*domain.ProfilePic, *apperrors.Error)
+	OpenProfilePicContent(ctx context.Context, profilePic *domain.ProfilePic, size int, byteRange string) (*domain.ProfilePicContent, *apperrors.Error)
+	ListProfilePicVersions(ctx context.Context, user *domain.User) ([]*domain.ProfilePic, *apperrors.Error)
+	RestoreProfilePicVersion(ctx context.Context, user *domain.User, version int) (*domain.ProfilePic, *apperrors.Error)
 	ValidateProfilePicExtension(filename string) bool
+	ExportData(ctx context.Context, user *domain.User) (*domain.DataExport, *apperrors.Error)
+	PurgeDeletedUsers(ctx context.Context) int
+	PurgeProfilePicVersions(ctx context.Context) int
+	PurgeExpiredPicUploads(ctx context.Context) int
 }
 
+// purgeBatchSize bounds the users and the profile pic versions purged by a single run, the rest is left to the next run
+const purgeBatchSize = 100
+
 type userApplicationService struct {
 	logger         logger.Logger
 	userService    domain.UserService
 	userRepository domain.UserRepository
+	// picVersionRetention is how long a replaced profile pic is kept
+	picVersionRetention time.Duration
 }
 
-func NewUserApplicationService(logger logger.Logger, userService domain.UserService, userRepository domain.UserRepository) UserApplicationService {
+func NewUserApplicationService(
+	logger logger.Logger,
+	userService domain.UserService,
+	userRepository domain.UserRepository,
+	picVersionRetention time.Duration,
+) UserApplicationService {
 	return &userApplicationService{
-		logger:         logger,
-		userService:    userService,
-		userRepository: userRepository,
+		logger:              logger,
+		userService:         userService,
+		userRepository:      userRepository,
+		picVersionRetention: picVersionRetention,
 	}
 }
 
 func (s *userApplicationService) UploadProfilePic(ctx context.Context, user *domain.User, profilePicFile *multipart.FileHeader) (*domain.ProfilePic, *apperrors.Error) {
 
-	fileBytes, err := s.userService.ParseProfilePic(profilePicFile)
+	file, err := s.userService.OpenProfilePicFile(profilePicFile)
 	if err != nil {
-		s.logger.Debug("Failed to parse profile pic", err)
-		return nil, apperrors.NewBadRequest("invalid profile pic content")
+		var tooLarge *domain.ProfilePicTooLargeError
+		if errors.As(err, &tooLarge) {
+			s.logger.Debug("Profile pic is too large", err)
+			return nil, apperrors.NewPayloadTooLarge(tooLarge.MaxSize, tooLarge.Size)
+		}
+
+		s.logger.Debug("Failed to open profile pic", err)
+		return nil, apperrors.NewUnprocessableEntity(domain.ErrInvalidProfilePicContent.Error())
 	}
+	defer file.Close()
 
-	profilePic, err := s.userService.UploadProfilePic(user.ID, profilePicFile.Filename, fileBytes)
+	return s.storeProfilePic(ctx, user, profilePicFile.Filename, file)
+}
+
+// storeProfilePic validates and processes the content while it is read, then saves it as the current version
+func (s *userApplicationService) storeProfilePic(ctx context.Context, user *domain.User, filename string, content io.Reader) (*domain.ProfilePic, *apperrors.Error) {
+	// the content decides the format, the stored filename is normalized to match it
+	profilePic, err := s.userService.UploadProfilePic(ctx, user.ID, filename, content)
 	if err != nil {
+		var tooLarge *domain.ProfilePicTooLargeError
+		if errors.As(err, &tooLarge) {
+			s.logger.Debug("Profile pic is too large", err)
+			return nil, apperrors.NewPayloadTooLarge(tooLarge.MaxSize, tooLarge.Size)
+		}
+
+		if domain.IsInvalidProfilePic(err) {
+			s.logger.Debug("Invalid profile pic", err)
+			return nil, apperrors.NewUnprocessableEntity(err.Error())
+		}
+
 		s.logger.Error("Failed to upload profile pic to S3", err)
 		return nil, apperrors.NewInternal()
 	}
 
+	// the previous profile pic is kept as a version
 	err = s.userRepository.SaveProfilePic(ctx, user, profilePic)
 	if err != nil {
 		s.logger.Error("Failed to save profile pic to database", err)
@@ -54,21 +106,22 @@ func (s *userApplicationService) UploadProfilePic(ctx context.Context, user *dom
 	return profilePic, nil
 }
 
+// DeleteProfilePic removes the profile pic with every previous version
 func (s *userApplicationService) DeleteProfilePic(ctx context.Context, user *domain.User) *apperrors.Error {
 	// Get the profile pic
-	profilePic, err := s.userRepository.GetProfilePic(ctx, user)
+	_, err := s.userRepository.GetProfilePic(ctx, user)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			s.logger.Debug("profile pic not found", err)
 			return apperrors.NewNotFound("profile pic not found")
 		}
 
-		s.logger.Error("Failed to get profile pic from s3", err)
+		s.logger.Error("Failed to get profile pic from database", err)
 		return apperrors.NewInternal()
 	}
 
-	// Delete the profile pic from S3
-	err = s.userService.DeleteProfilePic(user.ID + "/" + profilePic.Filename)
+	// Delete the versions from S3
+	err = s.deleteProfilePicFiles(ctx, user)
 	if err != nil {
 		s.logger.Error("Failed to delete profile pic from S3", err)
 		return apperrors.NewInternal()
@@ -84,10 +137,12 @@ func (s *userApplicationService) DeleteProfilePic(ctx context.Context, user *dom
 	return nil
 }
 
+// ValidateProfilePicExtension is a cheap check of the filename, the content is checked by UploadProfilePic
 func (s *userApplicationService) ValidateProfilePicExtension(filename string) bool {
-	allowedExtensions := []string{"jpg", "jpeg", "png"}
+	allowedExtensions := []string{".jpg", ".jpeg", ".png"}
 
-	extension := strings.ToLower(filename[strings.LastIndex(filename, ".")+1:])
+	// a name without a dot has no extension
+	extension := strings.ToLower(path.Ext(filename))
 	for _, allowedExtension := range allowedExtensions {
 		if extension == allowedExtension {
 			return true
@@ -112,3 +167,157 @@ func (s *userApplicationService) GetProfilePic(ctx context.Context, user *domain
 
 	return profilePic, nil
 }
+
+func (s *userApplicationService) ListProfilePicVersions(ctx context.Context, user *domain.User) ([]*domain.ProfilePic, *apperrors.Error) {
+	profilePics, err := s.userRepository.ListProfilePicVersions(ctx, user)
+	if err != nil {
+		s.logger.Error("Failed to list profile pic versions from database", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return profilePics, nil
+}
+
+// RestoreProfilePicVersion rolls the profile pic back to a previous version, the replaced one becomes a version
+func (s *userApplicationService) RestoreProfilePicVersion(ctx context.Context, user *domain.User, version int) (*domain.ProfilePic, *apperrors.Error) {
+	profilePic, err := s.userRepository.RestoreProfilePicVersion(ctx, user, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.logger.Debug("profile pic version not found", err)
+			return nil, apperrors.NewNotFound("profile pic version not found")
+		}
+
+		s.logger.Error("Failed to restore profile pic version", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return profilePic, nil
+}
+
+func (s *userApplicationService) ExportData(ctx context.Context, user *domain.User) (*domain.DataExport, *apperrors.Error) {
+	profile, err := s.userRepository.GetProfile(ctx, user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			s.logger.Debug("user not found", err)
+			return nil, apperrors.NewNotFound("user not found")
+		}
+
+		s.logger.Error("Failed to get profile from database", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	export := &domain.DataExport{
+		Profile:    profile,
+		ExportedAt: time.Now(),
+	}
+
+	profilePic, err := s.userRepository.GetProfilePic(ctx, user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return export, nil
+		}
+
+		s.logger.Error("Failed to get profile pic from database", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	content, err := s.userService.GetProfilePic(ctx, profilePic.S3Key)
+	if err != nil {
+		s.logger.Error("Failed to get profile pic from S3", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	export.ProfilePic = profilePic
+	export.ProfilePicContent = content
+
+	return export, nil
+}
+
+/*
+PurgeDeletedUsers removes the deleted users whose grace period ended and returns how many were purged
+- The profile pic is removed from S3 first, the cascades of the users table remove the rows
+- A user that fails is logged and kept, the next run retries it
+*/
+func (s *userApplicationService) PurgeDeletedUsers(ctx context.Context) int {
+	users, err := s.userRepository.ListPurgeableUsers(ctx, time.Now(), purgeBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to list the users to purge", err)
+		return 0
+	}
+
+	purged := 0
+	for _, user := range users {
+		if err := s.purgeUser(ctx, user); err != nil {
+			s.logger.Error("Failed to purge user "+user.ID, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged
+}
+
+func (s *userApplicationService) purgeUser(ctx context.Context, user *domain.User) error {
+	if err := s.deleteProfilePicFiles(ctx, user); err != nil {
+		return err
+	}
+
+	return s.userRepository.PurgeUser(ctx, user)
+}
+
+/*
+PurgeProfilePicVersions removes the profile pic versions replaced for longer than the retention and returns how many were purged
+- The row is deleted first, a version restored in the meantime is not deleted and keeps its files
+- A failure to remove the files of a deleted version leaves them orphaned in S3, it is logged with the version
+*/
+func (s *userApplicationService) PurgeProfilePicVersions(ctx context.Context) int {
+	profilePics, err := s.userRepository.ListExpiredProfilePicVersions(ctx, time.Now().Add(-s.picVersionRetention), purgeBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to list the profile pic versions to purge", err)
+		return 0
+	}
+
+	purged := 0
+	for _, profilePic := range profilePics {
+		if err := s.userRepository.DeleteProfilePicVersion(ctx, profilePic); err != nil {
+			if err != sql.ErrNoRows {
+				s.logger.Error("Failed to delete profile pic version "+profilePic.ID+" from database", err)
+			}
+			continue
+		}
+		purged++
+
+		if err := s.deleteFiles(ctx, profilePic); err != nil {
+			s.logger.Error("Failed to delete profile pic version "+profilePic.ID+" from S3", err)
+		}
+	}
+
+	return purged
+}
+
+// deleteProfilePicFiles removes the files of every profile pic version of the user and their variants
+func (s *userApplicationService) deleteProfilePicFiles(ctx context.Context, user *domain.User) error {
+	profilePics, err := s.userRepository.ListProfilePicVersions(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	for _, profilePic := range profilePics {
+		if err := s.deleteFiles(ctx, profilePic); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteFiles removes the file of the profile pic version and the files of its variants
+func (s *userApplicationService) deleteFiles(ctx context.Context, profilePic *domain.ProfilePic) error {
+	for _, key := range profilePic.Keys() {
+		if err := s.userService.DeleteProfilePic(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
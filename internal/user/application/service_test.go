@@ -0,0 +1,506 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"go-template/internal/user/domain"
+	"io"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Info(args ...interface{})  {}
+func (m *MockLogger) Error(args ...interface{}) {}
+func (m *MockLogger) Debug(args ...interface{}) {}
+func (m *MockLogger) Warn(args ...interface{})  {}
+
+// fakeUserRepository keeps the profiles, the profile pic versions and the uploads in memory, every profile has been deleted
+type fakeUserRepository struct {
+	domain.UserRepository
+	profiles    map[string]*domain.Profile
+	profilePics map[string][]*domain.ProfilePic
+	uploads     map[string]*domain.PicUpload
+}
+
+func (r *fakeUserRepository) GetProfile(ctx context.Context, user *domain.User) (*domain.Profile, error) {
+	profile, ok := r.profiles[user.ID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return profile, nil
+}
+
+func (r *fakeUserRepository) GetProfilePic(ctx context.Context, user *domain.User) (*domain.ProfilePic, error) {
+	for _, profilePic := range r.profilePics[user.ID] {
+		if profilePic.Current {
+			return profilePic, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeUserRepository) ListProfilePicVersions(ctx context.Context, user *domain.User) ([]*domain.ProfilePic, error) {
+	return r.profilePics[user.ID], nil
+}
+
+func (r *fakeUserRepository) DeleteProfilePic(ctx context.Context, user *domain.User) error {
+	delete(r.profilePics, user.ID)
+	return nil
+}
+
+func (r *fakeUserRepository) ListExpiredProfilePicVersions(ctx context.Context, replacedBefore time.Time, limit int) ([]*domain.ProfilePic, error) {
+	var expired []*domain.ProfilePic
+	for _, profilePics := range r.profilePics {
+		for _, profilePic := range profilePics {
+			if !profilePic.Current && profilePic.ReplacedAt.Before(replacedBefore) {
+				expired = append(expired, profilePic)
+			}
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeUserRepository) DeleteProfilePicVersion(ctx context.Context, profilePic *domain.ProfilePic) error {
+	for userID, profilePics := range r.profilePics {
+		for i, version := range profilePics {
+			if version.ID == profilePic.ID && !version.Current {
+				r.profilePics[userID] = append(profilePics[:i], profilePics[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// restoringUserRepository restores every version right before it is deleted, like a concurrent restore
+type restoringUserRepository struct {
+	*fakeUserRepository
+}
+
+func (r *restoringUserRepository) DeleteProfilePicVersion(ctx context.Context, profilePic *domain.ProfilePic) error {
+	profilePic.Current = true
+	return r.fakeUserRepository.DeleteProfilePicVersion(ctx, profilePic)
+}
+
+func (r *fakeUserRepository) ListPurgeableUsers(ctx context.Context, now time.Time, limit int) ([]*domain.User, error) {
+	var users []*domain.User
+	for id := range r.profiles {
+		users = append(users, domain.NewUser(id))
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepository) PurgeUser(ctx context.Context, user *domain.User) error {
+	delete(r.profiles, user.ID)
+	delete(r.profilePics, user.ID)
+	return nil
+}
+
+func (r *fakeUserRepository) GetPicUpload(ctx context.Context, user *domain.User, id string) (*domain.PicUpload, error) {
+	upload, ok := r.uploads[id]
+	if !ok || upload.UserID != user.ID {
+		return nil, sql.ErrNoRows
+	}
+	return upload, nil
+}
+
+func (r *fakeUserRepository) DeletePicUpload(ctx context.Context, upload *domain.PicUpload) error {
+	delete(r.uploads, upload.ID)
+	return nil
+}
+
+func (r *fakeUserRepository) ListExpiredPicUploads(ctx context.Context, now time.Time, limit int) ([]*domain.PicUpload, error) {
+	var expired []*domain.PicUpload
+	for _, upload := range r.uploads {
+		if upload.ExpiresAt.Before(now) {
+			expired = append(expired, upload)
+		}
+	}
+	return expired, nil
+}
+
+// fakeUserService stores the files by key, an upload matches its slot unless it is listed in mismatched
+type fakeUserService struct {
+	domain.UserService
+	files      map[string][]byte
+	mismatched map[string]bool
+	failDelete bool
+}
+
+func (s *fakeUserService) OpenProfilePic(ctx context.Context, key, byteRange string) (*domain.ProfilePicContent, error) {
+	content, ok := s.files[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	if byteRange == "bytes=1000-" {
+		return nil, domain.ErrProfilePicRangeNotSatisfiable
+	}
+	return &domain.ProfilePicContent{Body: io.NopCloser(bytes.NewReader(content)), ContentLength: int64(len(content))}, nil
+}
+
+func (s *fakeUserService) OpenPicUpload(ctx context.Context, upload *domain.PicUpload) (io.ReadCloser, error) {
+	content, ok := s.files[upload.S3Key]
+	if !ok {
+		return nil, domain.ErrPicUploadMissing
+	}
+	if s.mismatched[upload.S3Key] {
+		return nil, domain.ErrPicUploadMismatch
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *fakeUserService) GetProfilePic(ctx context.Context, key string) ([]byte, error) {
+	return s.files[key], nil
+}
+
+func (s *fakeUserService) DeleteProfilePic(ctx context.Context, key string) error {
+	if s.failDelete {
+		return errors.New("s3 unavailable")
+	}
+	delete(s.files, key)
+	return nil
+}
+
+func newTestFixtures() (*fakeUserRepository, *fakeUserService) {
+	replacedAt := time.Now().Add(-48 * time.Hour)
+	repository := &fakeUserRepository{
+		profiles: map[string]*domain.Profile{
+			"with-pic":    {ID: "with-pic", Email: "with-pic@example.com"},
+			"without-pic": {ID: "without-pic", Email: "without-pic@example.com"},
+		},
+		profilePics: map[string][]*domain.ProfilePic{
+			"with-pic": {
+				{ID: "v2", Version: 2, Filename: "me.png", S3Key: "with-pic/v2/me.png", VariantSizes: []int{64}, Current: true},
+				{ID: "v1", Version: 1, Filename: "old.png", S3Key: "with-pic/v1/old.png", ReplacedAt: &replacedAt},
+			},
+		},
+		uploads: map[string]*domain.PicUpload{
+			"pending":    {ID: "pending", UserID: "with-pic", S3Key: "uploads/with-pic/pending/new.png", ExpiresAt: time.Now().Add(time.Hour)},
+			"mismatched": {ID: "mismatched", UserID: "with-pic", S3Key: "uploads/with-pic/mismatched/new.png", ExpiresAt: time.Now().Add(time.Hour)},
+			"expired":    {ID: "expired", UserID: "with-pic", S3Key: "uploads/with-pic/expired/new.png", ExpiresAt: replacedAt},
+		},
+	}
+	userService := &fakeUserService{files: map[string][]byte{
+		"with-pic/v2/me.png":    []byte("image"),
+		"with-pic/v2/64/me.png": []byte("variant"),
+		"with-pic/v1/old.png":   []byte("old image"),
+	}}
+
+	return repository, userService
+}
+
+// newUploadTestFixtures adds the files uploaded to the mismatched and expired slots, nothing was uploaded to the pending one
+func newUploadTestFixtures() (*fakeUserRepository, *fakeUserService) {
+	repository, userService := newTestFixtures()
+	userService.files["uploads/with-pic/mismatched/new.png"] = []byte("other image")
+	userService.files["uploads/with-pic/expired/new.png"] = []byte("abandoned image")
+	userService.mismatched = map[string]bool{"uploads/with-pic/mismatched/new.png": true}
+
+	return repository, userService
+}
+
+func TestExportData(t *testing.T) {
+	repository, userService := newTestFixtures()
+	service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+	ctx := context.Background()
+
+	export, err := service.ExportData(ctx, domain.NewUser("with-pic"))
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if export.Profile.Email != "with-pic@example.com" || export.ProfilePic == nil || string(export.ProfilePicContent) != "image" {
+		t.Errorf("Export should contain the profile and the profile pic, got %+v", export)
+	}
+
+	export, err = service.ExportData(ctx, domain.NewUser("without-pic"))
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if export.ProfilePic != nil {
+		t.Errorf("Export should not contain a profile pic, got %+v", export.ProfilePic)
+	}
+
+	if _, err := service.ExportData(ctx, domain.NewUser("unknown")); err == nil || err.Status() != 404 {
+		t.Errorf("Error should be not found, got %v", err)
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Test purge", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if purged := service.PurgeDeletedUsers(ctx); purged != 2 {
+			t.Errorf("Purged should be 2, got %d", purged)
+		}
+
+		if len(repository.profiles) != 0 || len(userService.files) != 0 {
+			t.Errorf("Users and files should be removed, got %v and %v", repository.profiles, userService.files)
+		}
+	})
+
+	t.Run("Test S3 failure keeps the user", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		userService.failDelete = true
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if purged := service.PurgeDeletedUsers(ctx); purged != 1 {
+			t.Errorf("Purged should be 1, got %d", purged)
+		}
+
+		// the row is kept so the file is not orphaned, the next run retries it
+		if _, ok := repository.profiles["with-pic"]; !ok {
+			t.Errorf("User with a profile pic should be kept")
+		}
+	})
+}
+
+func TestDeleteProfilePic(t *testing.T) {
+	repository, userService := newTestFixtures()
+	service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+	ctx := context.Background()
+
+	if err := service.DeleteProfilePic(ctx, domain.NewUser("with-pic")); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	// the previous versions are removed as well
+	if len(repository.profilePics["with-pic"]) != 0 || len(userService.files) != 0 {
+		t.Errorf("Versions and files should be removed, got %v and %v", repository.profilePics, userService.files)
+	}
+
+	if err := service.DeleteProfilePic(ctx, domain.NewUser("without-pic")); err == nil || err.Status() != 404 {
+		t.Errorf("Error should be not found, got %v", err)
+	}
+}
+
+func TestPurgeProfilePicVersions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Test purge past the retention", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if purged := service.PurgeProfilePicVersions(ctx); purged != 1 {
+			t.Errorf("Purged should be 1, got %d", purged)
+		}
+
+		// the current version is kept
+		if len(repository.profilePics["with-pic"]) != 1 || userService.files["with-pic/v2/me.png"] == nil {
+			t.Errorf("Current version should be kept, got %v", repository.profilePics["with-pic"])
+		}
+
+		if _, ok := userService.files["with-pic/v1/old.png"]; ok {
+			t.Errorf("File of the expired version should be removed")
+		}
+	})
+
+	t.Run("Test restored versions keep their files", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, &restoringUserRepository{repository}, 24*time.Hour)
+
+		if purged := service.PurgeProfilePicVersions(ctx); purged != 0 {
+			t.Errorf("Purged should be 0, got %d", purged)
+		}
+
+		if _, ok := userService.files["with-pic/v1/old.png"]; !ok {
+			t.Errorf("File of the restored version should be kept")
+		}
+	})
+
+	t.Run("Test versions within the retention are kept", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 72*time.Hour)
+
+		if purged := service.PurgeProfilePicVersions(ctx); purged != 0 {
+			t.Errorf("Purged should be 0, got %d", purged)
+		}
+
+		if len(repository.profilePics["with-pic"]) != 2 {
+			t.Errorf("Versions should be kept, got %v", repository.profilePics["with-pic"])
+		}
+	})
+}
+
+func TestConfirmProfilePicUpload(t *testing.T) {
+	ctx := context.Background()
+	user := domain.NewUser("with-pic")
+
+	t.Run("Test upload of another user", func(t *testing.T) {
+		repository, userService := newUploadTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if _, err := service.ConfirmProfilePicUpload(ctx, domain.NewUser("without-pic"), "pending"); err == nil || err.Status() != 404 {
+			t.Errorf("Error should be not found, got %v", err)
+		}
+	})
+
+	t.Run("Test expired upload", func(t *testing.T) {
+		repository, userService := newUploadTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if _, err := service.ConfirmProfilePicUpload(ctx, user, "expired"); err == nil || err.Status() != 422 {
+			t.Errorf("Error should be unprocessable entity, got %v", err)
+		}
+	})
+
+	t.Run("Test file not uploaded yet", func(t *testing.T) {
+		repository, userService := newUploadTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if _, err := service.ConfirmProfilePicUpload(ctx, user, "pending"); err == nil || err.Status() != 422 {
+			t.Errorf("Error should be unprocessable entity, got %v", err)
+		}
+
+		// the client can still upload the file
+		if _, ok := repository.uploads["pending"]; !ok {
+			t.Errorf("Upload should be kept")
+		}
+	})
+
+	t.Run("Test file not matching the upload", func(t *testing.T) {
+		repository, userService := newUploadTestFixtures()
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if _, err := service.ConfirmProfilePicUpload(ctx, user, "mismatched"); err == nil || err.Status() != 422 {
+			t.Errorf("Error should be unprocessable entity, got %v", err)
+		}
+
+		if _, ok := repository.uploads["mismatched"]; ok {
+			t.Errorf("Upload should be removed")
+		}
+		if _, ok := userService.files["uploads/with-pic/mismatched/new.png"]; ok {
+			t.Errorf("Uploaded file should be removed")
+		}
+
+		// the current profile pic is untouched
+		if len(repository.profilePics["with-pic"]) != 2 {
+			t.Errorf("Versions should be kept, got %v", repository.profilePics["with-pic"])
+		}
+	})
+}
+
+func TestPurgeExpiredPicUploads(t *testing.T) {
+	repository, userService := newUploadTestFixtures()
+	service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+	if purged := service.PurgeExpiredPicUploads(context.Background()); purged != 1 {
+		t.Errorf("Purged should be 1, got %d", purged)
+	}
+
+	if _, ok := repository.uploads["expired"]; ok {
+		t.Errorf("Expired upload should be removed")
+	}
+	if _, ok := userService.files["uploads/with-pic/expired/new.png"]; ok {
+		t.Errorf("File of the expired upload should be removed")
+	}
+	if len(repository.uploads) != 2 {
+		t.Errorf("Pending uploads should be kept, got %v", repository.uploads)
+	}
+}
+
+func TestGetAvatar(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Test active user", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		repository.profiles["with-pic"].Status = "active"
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		profilePic, err := service.GetAvatar(ctx, "with-pic")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if !profilePic.Current {
+			t.Errorf("Avatar should be the current version, got %d", profilePic.Version)
+		}
+	})
+
+	t.Run("Test user that is not active", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		repository.profiles["with-pic"].Status = "suspended"
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		if _, err := service.GetAvatar(ctx, "with-pic"); err == nil || err.Status() != 404 {
+			t.Errorf("Error should be not found, got %v", err)
+		}
+	})
+
+	t.Run("Test unknown user and user without profile pic", func(t *testing.T) {
+		repository, userService := newTestFixtures()
+		repository.profiles["without-pic"].Status = "active"
+		service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+
+		unknown, unknownErr := service.GetAvatar(ctx, "unknown")
+		withoutPic, withoutPicErr := service.GetAvatar(ctx, "without-pic")
+		if unknown != nil || withoutPic != nil || unknownErr == nil || withoutPicErr == nil {
+			t.Fatalf("Errors should be set, got %v and %v", unknownErr, withoutPicErr)
+		}
+
+		// both are answered the same way
+		if unknownErr.Status() != 404 || unknownErr.Message != withoutPicErr.Message {
+			t.Errorf("Errors should be the same not found, got %v and %v", unknownErr, withoutPicErr)
+		}
+	})
+}
+
+func TestOpenProfilePicContent(t *testing.T) {
+	ctx := context.Background()
+	repository, userService := newTestFixtures()
+	service := NewUserApplicationService(&MockLogger{}, userService, repository, 24*time.Hour)
+	profilePic := repository.profilePics["with-pic"][0]
+
+	t.Run("Test variant", func(t *testing.T) {
+		content, err := service.OpenProfilePicContent(ctx, profilePic, 64, "")
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		defer content.Body.Close()
+
+		body, _ := io.ReadAll(content.Body)
+		if string(body) != "variant" {
+			t.Errorf("Content should be the variant, got %s", body)
+		}
+	})
+
+	t.Run("Test unknown variant", func(t *testing.T) {
+		if _, err := service.OpenProfilePicContent(ctx, profilePic, 128, ""); err == nil || err.Status() != 404 {
+			t.Errorf("Error should be not found, got %v", err)
+		}
+	})
+
+	t.Run("Test range past the end", func(t *testing.T) {
+		if _, err := service.OpenProfilePicContent(ctx, profilePic, 0, "bytes=1000-"); err == nil || err.Status() != 416 {
+			t.Errorf("Error should be range not satisfiable, got %v", err)
+		}
+	})
+}
+
+func TestValidateProfilePicExtension(t *testing.T) {
+	service := NewUserApplicationService(&MockLogger{}, &fakeUserService{}, &fakeUserRepository{}, 24*time.Hour)
+
+	cases := map[string]bool{
+		"me.png":    true,
+		"me.JPEG":   true,
+		"me.jpg.sh": false,
+		// a name without a dot used to be its own extension
+		"png": false,
+		"":    false,
+	}
+
+	for filename, expected := range cases {
+		if valid := service.ValidateProfilePicExtension(filename); valid != expected {
+			t.Errorf("Extension of %q should be valid %v, got %v", filename, expected, valid)
+		}
+	}
+}
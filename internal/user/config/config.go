@@ -0,0 +1,16 @@
+package config
+
+type UserConfig struct {
+	User struct {
+		// PurgeInterval is how often the deleted accounts past their grace period are purged (in seconds)
+		PurgeInterval int `mapstructure:"purge_interval"`
+		// PicVersionRetention is how long a replaced profile pic can be restored before it is purged (in seconds)
+		PicVersionRetention int `mapstructure:"pic_version_retention"`
+		// PicMaxSize is the maximum size of an uploaded profile pic (in bytes)
+		PicMaxSize int64 `mapstructure:"pic_max_size"`
+		// PicMaxDimension is the maximum width and height of an uploaded profile pic (in pixels)
+		PicMaxDimension int `mapstructure:"pic_max_dimension"`
+		// PicPresignExpiration is how long a presigned upload or download URL of a profile pic is valid (in seconds)
+		PicPresignExpiration int `mapstructure:"pic_presign_expiration"`
+	} `mapstructure:"user"`
+}
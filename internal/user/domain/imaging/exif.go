@@ -0,0 +1,93 @@
+package imaging
+
+import "encoding/binary"
+
+const (
+	markerSOI  = 0xd8
+	markerAPP1 = 0xe1
+	markerSOS  = 0xda
+
+	tagOrientation = 0x0112
+	typeShort      = 3
+)
+
+// jpegOrientation returns the EXIF orientation of a JPEG, 1 (upright) when it has none or it cannot be read
+func jpegOrientation(content []byte) int {
+	if len(content) < 4 || content[0] != 0xff || content[1] != markerSOI {
+		return 1
+	}
+
+	// walk the segments until the image data starts
+	for i := 2; i+4 <= len(content); {
+		if content[i] != 0xff {
+			return 1
+		}
+		marker := content[i+1]
+		if marker == markerSOS {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(content[i+2 : i+4]))
+		if length < 2 || i+2+length > len(content) {
+			return 1
+		}
+
+		segment := content[i+4 : i+2+length]
+		if marker == markerAPP1 && len(segment) >= 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of the TIFF structure of the EXIF segment
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:entry+2]) != tagOrientation {
+			continue
+		}
+		if order.Uint16(tiff[entry+2:entry+4]) != typeShort {
+			return 1
+		}
+
+		orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+		if orientation < 1 || orientation > 8 {
+			return 1
+		}
+		return orientation
+	}
+
+	return 1
+}
@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// jpegQuality is used to re-encode the JPEG images
+const jpegQuality = 85
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrInvalidImage      = errors.New("invalid image")
+)
+
+/*
+Decode decodes the pixels of the image and reads the stream to its end
+- The pixels are only decoded once the header was read by Open, its dimensions can be checked first
+- Data after the end marker is refused, it is how a file is made valid as an image and as another format at once
+- The EXIF orientation of a JPEG is applied to the pixels, the image is upright
+- An error of the underlying reader, e.g. a size limit, is returned as is
+*/
+func (s *Source) Decode() (*image.RGBA, error) {
+	var decoded image.Image
+	var err error
+	if s.Format == FormatPNG {
+		decoded, err = png.Decode(s.reader)
+	} else {
+		decoded, err = jpeg.Decode(s.reader)
+	}
+	if err != nil {
+		return nil, s.tail.errOr(ErrInvalidImage)
+	}
+
+	// the decoder stops at the end marker, what follows is read to find trailing data
+	if _, err := io.Copy(io.Discard, s.reader); err != nil {
+		return nil, err
+	}
+	if s.tail.hasTrailingData(s.Format) {
+		return nil, ErrTrailingData
+	}
+
+	img := toRGBA(decoded)
+	if s.Format == FormatJPEG {
+		img = applyOrientation(img, s.orientation)
+	}
+
+	return img, nil
+}
+
+// Encode writes the image in the format, the encoders do not write any metadata (EXIF, GPS, comments)
+func Encode(w io.Writer, img image.Image, format string) error {
+	if format == FormatPNG {
+		return png.Encode(w, img)
+	}
+
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
+
+// Variant crops the image to its centered square and scales it to size x size
+func Variant(img *image.RGBA, size int) *image.RGBA {
+	return resize(cropSquare(img), size, size)
+}
+
+// toRGBA copies the image into an RGBA image whose bounds start at 0, 0
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
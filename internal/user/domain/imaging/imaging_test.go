@@ -0,0 +1,254 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// twoColorImage is red on the left half and blue on the right half
+func twoColorImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+// withExif inserts an EXIF segment with the orientation and a GPS like payload after the SOI marker
+func withExif(content []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, uint16(tagOrientation))
+	binary.Write(&tiff, binary.BigEndian, uint16(typeShort))
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, orientation)
+	binary.Write(&tiff, binary.BigEndian, uint16(0))
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	tiff.WriteString("GPS 48.8584N 2.2945E")
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(content[:2])
+	out.Write([]byte{0xff, markerAPP1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(content[2:])
+	return out.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	return buf.Bytes()
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r>>8 > 200 && g>>8 < 60 && b>>8 < 60
+}
+
+func TestJpegOrientation(t *testing.T) {
+	content := encodeJPEG(t, twoColorImage(8, 4))
+
+	if orientation := jpegOrientation(content); orientation != 1 {
+		t.Errorf("Orientation should be 1 without EXIF, got %d", orientation)
+	}
+
+	if orientation := jpegOrientation(withExif(content, 6)); orientation != 6 {
+		t.Errorf("Orientation should be 6, got %d", orientation)
+	}
+
+	// a truncated segment is ignored
+	if orientation := jpegOrientation(withExif(content, 6)[:20]); orientation != 1 {
+		t.Errorf("Orientation should be 1 for a truncated segment, got %d", orientation)
+	}
+}
+
+// decode opens and decodes the content
+func decode(content []byte) (*Source, *image.RGBA, error) {
+	source, err := Open(bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img, err := source.Decode()
+	return source, img, err
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("Test JPEG is upright and stripped", func(t *testing.T) {
+		content := withExif(encodeJPEG(t, twoColorImage(40, 20)), 6)
+
+		source, img, err := decode(content)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if source.Format != FormatJPEG {
+			t.Errorf("Format should be jpeg, got %s", source.Format)
+		}
+
+		var encoded bytes.Buffer
+		if err := Encode(&encoded, img, source.Format); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if bytes.Contains(encoded.Bytes(), []byte("Exif")) || bytes.Contains(encoded.Bytes(), []byte("GPS")) {
+			t.Errorf("Metadata should be stripped")
+		}
+
+		original, err := jpeg.Decode(&encoded)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		// rotated 90 clockwise, the left half is now on top
+		if original.Bounds().Dx() != 20 || original.Bounds().Dy() != 40 {
+			t.Errorf("Size should be 20x40, got %v", original.Bounds())
+		}
+		if !isRed(original.At(10, 5)) || isRed(original.At(10, 35)) {
+			t.Errorf("Top should be red and bottom blue")
+		}
+
+		for _, size := range []int{8, 16} {
+			variant := Variant(img, size)
+			if variant.Bounds().Dx() != size || variant.Bounds().Dy() != size {
+				t.Errorf("Variant should be %dx%d, got %v", size, size, variant.Bounds())
+			}
+		}
+	})
+
+	t.Run("Test PNG keeps its format", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, twoColorImage(30, 10)); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		source, img, err := decode(buf.Bytes())
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+
+		if source.Format != FormatPNG {
+			t.Errorf("Format should be png, got %s", source.Format)
+		}
+
+		// small images are scaled up to the variant size
+		var encoded bytes.Buffer
+		if err := Encode(&encoded, Variant(img, 64), source.Format); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		variant, err := png.Decode(&encoded)
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if variant.Bounds().Dx() != 64 || variant.Bounds().Dy() != 64 {
+			t.Errorf("Variant should be 64x64, got %v", variant.Bounds())
+		}
+	})
+
+	t.Run("Test invalid content", func(t *testing.T) {
+		if _, _, err := decode([]byte("GIF89a not really")); err != ErrUnsupportedFormat {
+			t.Errorf("Error should be %v, got %v", ErrUnsupportedFormat, err)
+		}
+
+		content := encodeJPEG(t, twoColorImage(40, 20))
+		if _, _, err := decode(content[:len(content)/2]); err != ErrInvalidImage {
+			t.Errorf("Error should be %v, got %v", ErrInvalidImage, err)
+		}
+	})
+
+	t.Run("Test trailing data", func(t *testing.T) {
+		content := encodeJPEG(t, twoColorImage(40, 20))
+
+		// zero padding after the end marker is allowed
+		if _, _, err := decode(append(append([]byte{}, content...), 0, 0, 0)); err != nil {
+			t.Errorf("Error should be nil for a padded JPEG, got %v", err)
+		}
+
+		// a zip appended to the image makes a polyglot
+		polyglot := append(append([]byte{}, content...), []byte("PK\x03\x04payload")...)
+		if _, _, err := decode(polyglot); err != ErrTrailingData {
+			t.Errorf("Error should be %v, got %v", ErrTrailingData, err)
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, twoColorImage(30, 10)); err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if _, _, err := decode(append(buf.Bytes(), []byte("<script>alert(1)</script>")...)); err != ErrTrailingData {
+			t.Errorf("Error should be %v, got %v", ErrTrailingData, err)
+		}
+	})
+
+	t.Run("Test reader error", func(t *testing.T) {
+		content := encodeJPEG(t, twoColorImage(40, 20))
+		readErr := errors.New("too large")
+
+		source, err := Open(io.MultiReader(bytes.NewReader(content[:len(content)-10]), iotest.ErrReader(readErr)))
+		if err != nil {
+			t.Fatalf("Error should be nil, got %v", err)
+		}
+		if _, err := source.Decode(); err != readErr {
+			t.Errorf("Error should be %v, got %v", readErr, err)
+		}
+	})
+}
+
+func TestApplyOrientation(t *testing.T) {
+	img := twoColorImage(4, 2)
+
+	for orientation, size := range map[int]image.Point{1: {4, 2}, 3: {4, 2}, 6: {2, 4}, 8: {2, 4}} {
+		rotated := applyOrientation(img, orientation)
+		if rotated.Bounds().Size() != size {
+			t.Errorf("Size for orientation %d should be %v, got %v", orientation, size, rotated.Bounds().Size())
+		}
+	}
+
+	// rotated 90 counterclockwise, the left half is now at the bottom
+	if rotated := applyOrientation(img, 8); isRed(rotated.At(0, 0)) || !isRed(rotated.At(0, 3)) {
+		t.Errorf("Bottom should be red for orientation 8")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	content := withExif(encodeJPEG(t, twoColorImage(40, 20)), 6)
+
+	// the pixels are not read, a truncated image still has a header
+	source, err := Open(bytes.NewReader(content[:len(content)-10]))
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if source.Format != FormatJPEG || source.Width != 40 || source.Height != 20 || source.orientation != 6 {
+		t.Errorf("Info should be jpeg 40x20 rotated, got %+v", source.Info)
+	}
+
+	// the signature decides the format, whatever follows
+	if _, err := Open(strings.NewReader("<html><body>not an image</body></html>")); err != ErrUnsupportedFormat {
+		t.Errorf("Error should be %v, got %v", ErrUnsupportedFormat, err)
+	}
+	if _, err := Open(bytes.NewReader(content[:12])); err != ErrInvalidImage {
+		t.Errorf("Error should be %v, got %v", ErrInvalidImage, err)
+	}
+}
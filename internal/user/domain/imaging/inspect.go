@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+var ErrTrailingData = errors.New("unexpected data after the end of the image")
+
+var (
+	jpegSignature = []byte{0xff, markerSOI, 0xff}
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+	// pngEnd is the IEND chunk, it has no data so its CRC is fixed
+	pngEnd  = []byte("\x00\x00\x00\x00IEND\xae\x42\x60\x82")
+	jpegEnd = []byte{0xff, 0xd9}
+)
+
+// Info is read from the signature and the header of the image without decoding its pixels
+type Info struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// Source is an image read from a stream, Open reads its header and Decode the rest
+type Source struct {
+	Info
+	orientation int
+	// reader replays the header read by Open, then reads the rest of the stream
+	reader io.Reader
+	tail   *tailReader
+}
+
+/*
+Open identifies the image by its signature and reads its dimensions from the header
+- The format comes from the magic bytes, never from the filename
+- Only the header is read, the pixels of a too large image are never decoded
+- An error of the underlying reader, e.g. a size limit, is returned as is
+*/
+func Open(r io.Reader) (*Source, error) {
+	tail := &tailReader{r: r}
+	var header bytes.Buffer
+	headerReader := io.TeeReader(tail, &header)
+
+	signature := make([]byte, len(pngSignature))
+	n, _ := io.ReadFull(headerReader, signature)
+	signature = signature[:n]
+
+	var config image.Config
+	var format string
+	var err error
+
+	// the decoders read the signature again, followed by the rest of the header
+	configReader := io.MultiReader(bytes.NewReader(signature), headerReader)
+	switch {
+	case bytes.HasPrefix(signature, jpegSignature):
+		format = FormatJPEG
+		config, err = jpeg.DecodeConfig(configReader)
+	case bytes.HasPrefix(signature, pngSignature):
+		format = FormatPNG
+		config, err = png.DecodeConfig(configReader)
+	default:
+		return nil, tail.errOr(ErrUnsupportedFormat)
+	}
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, tail.errOr(ErrInvalidImage)
+	}
+
+	source := &Source{
+		Info:        Info{Format: format, Width: config.Width, Height: config.Height},
+		orientation: 1,
+		reader:      io.MultiReader(bytes.NewReader(header.Bytes()), tail),
+		tail:        tail,
+	}
+
+	// the EXIF segment comes before the frame header, it is part of what was read
+	if format == FormatJPEG {
+		source.orientation = jpegOrientation(header.Bytes())
+	}
+
+	return source, nil
+}
+
+// tailReader passes the stream through and remembers how it ends, the end marker is checked without keeping the content
+type tailReader struct {
+	r   io.Reader
+	err error
+	// last holds the last bytes read, n of them are set
+	last [12]byte
+	n    int
+	// previous is the last byte read, lastMarker the two bytes ending at the last non-zero byte
+	previous   byte
+	lastMarker [2]byte
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	for _, b := range p[:n] {
+		copy(t.last[:], t.last[1:])
+		t.last[len(t.last)-1] = b
+		t.n = min(t.n+1, len(t.last))
+
+		if b != 0 {
+			t.lastMarker = [2]byte{t.previous, b}
+		}
+		t.previous = b
+	}
+	if err != nil && err != io.EOF && t.err == nil {
+		t.err = err
+	}
+
+	return n, err
+}
+
+// errOr returns the error of the underlying reader, or err when it did not fail
+func (t *tailReader) errOr(err error) error {
+	if t.err != nil {
+		return t.err
+	}
+	return err
+}
+
+// hasTrailingData reports whether the stream did not end with the end marker of its format,
+// the zero padding some encoders add after a JPEG is allowed
+func (t *tailReader) hasTrailingData(format string) bool {
+	if format == FormatPNG {
+		return t.n < len(pngEnd) || !bytes.Equal(t.last[len(t.last)-len(pngEnd):], pngEnd)
+	}
+
+	return !bytes.Equal(t.lastMarker[:], jpegEnd)
+}
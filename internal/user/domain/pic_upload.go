@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"go-template/internal/user/domain/imaging"
+	"net/http"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+var (
+	ErrPicUploadExpired  = errors.New("profile pic upload expired")
+	ErrPicUploadMissing  = errors.New("profile pic was not uploaded")
+	ErrPicUploadMismatch = errors.New("uploaded profile pic does not match the upload request")
+	// ErrDirectTransferUnavailable is returned when the storage driver cannot be reached by the clients, e.g. the local one
+	ErrDirectTransferUnavailable = errors.New("direct uploads and downloads are not supported by the storage")
+)
+
+// picUploadPrefix keeps the staging objects apart, a lifecycle rule on it removes the abandoned ones as well
+const picUploadPrefix = "uploads/"
+
+// profilePicContentTypes maps the content types accepted for a direct upload to their format
+var profilePicContentTypes = map[string]string{
+	"image/jpeg": imaging.FormatJPEG,
+	"image/png":  imaging.FormatPNG,
+}
+
+// PicUpload is a slot the client uploads a profile pic to directly, the profile pic is created when it is confirmed
+type PicUpload struct {
+	ID          string
+	UserID      string
+	S3Key       string
+	Filename    string
+	ContentType string
+	Size        int64
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+func NewPicUpload(userID, filename, contentType string, size int64, expires time.Duration) *PicUpload {
+	id := uuidv7.New().String()
+	now := time.Now()
+
+	return &PicUpload{
+		ID:          id,
+		UserID:      userID,
+		S3Key:       picUploadPrefix + userID + "/" + id + "/" + filename,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		ExpiresAt:   now.Add(expires),
+		CreatedAt:   now,
+	}
+}
+
+func (u *PicUpload) IsExpired() bool {
+	return time.Now().After(u.ExpiresAt)
+}
+
+// PresignedURL is a request the client sends to S3 directly, Header holds the headers it has to send as is
+type PresignedURL struct {
+	URL       string
+	Method    string
+	Header    http.Header
+	ExpiresAt time.Time
+}
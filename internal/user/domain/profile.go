@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Profile is the account data kept by the auth module, read for the data export
+type Profile struct {
+	ID        string
+	Email     string
+	FirstName string
+	LastName  string
+	Verified  bool
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// profileStatusActive is the status of an account that can sign in, see the account statuses of the auth module
+const profileStatusActive = "active"
+
+// IsActive reports whether the account can sign in, only the active accounts are shown to other users
+func (p *Profile) IsActive() bool {
+	return p.Status == profileStatusActive
+}
+
+// DataExport holds everything stored about a user, the profile pic is nil when the user has none
+type DataExport struct {
+	Profile           *Profile
+	ProfilePic        *ProfilePic
+	ProfilePicContent []byte
+	ExportedAt        time.Time
+}
@@ -1,12 +1,16 @@
 package domain
 
 import (
+	"path"
+	"strconv"
 	"time"
-
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// ProfilePic is a version of the profile pic of a user, a replaced version is kept until the retention purges it
 type ProfilePic struct {
+	ID string
+	// Version is assigned when the profile pic is saved, it starts at 1 for every user
+	Version       int
 	Filename      string
 	UploadedAt    time.Time
 	Url           string
@@ -14,20 +18,42 @@ type ProfilePic struct {
 	ETag          string
 	Encryption    string
 	EncryptionKey string
+	// VariantSizes are the sizes of the scaled copies stored under the keys derived by VariantKey
+	VariantSizes []int
+	// Current is set on the version shown as the profile pic
+	Current    bool
+	ReplacedAt *time.Time
 }
 
+// ProfilePicVariantSizes are the squares generated for every uploaded profile pic (in pixels)
+var ProfilePicVariantSizes = []int{64, 256, 1024}
+
 func NewProfilePic(
-	filename, url, s3Key, eTag string,
-	encryption types.ServerSideEncryption,
-	encryptionKey string,
+	id, filename, url, s3Key, eTag, encryption, encryptionKey string,
 ) *ProfilePic {
 	return &ProfilePic{
+		ID:            id,
 		Filename:      filename,
 		UploadedAt:    time.Now(),
 		Url:           url,
 		S3Key:         s3Key,
 		ETag:          eTag,
-		Encryption:    string(encryption),
+		Encryption:    encryption,
 		EncryptionKey: encryptionKey,
+		Current:       true,
+	}
+}
+
+// VariantKey derives the S3 key of a variant from the key of the profile pic, e.g. user/id/256/me.png
+func (p *ProfilePic) VariantKey(size int) string {
+	return path.Join(path.Dir(p.S3Key), strconv.Itoa(size), path.Base(p.S3Key))
+}
+
+// Keys returns the S3 keys of the profile pic and its variants
+func (p *ProfilePic) Keys() []string {
+	keys := []string{p.S3Key}
+	for _, size := range p.VariantSizes {
+		keys = append(keys, p.VariantKey(size))
 	}
+	return keys
 }
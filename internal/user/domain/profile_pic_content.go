@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrProfilePicRangeNotSatisfiable = errors.New("requested range is not satisfiable")
+
+// ProfilePicContent streams a file of a profile pic, the caller must close Body
+type ProfilePicContent struct {
+	Body        io.ReadCloser
+	ContentType string
+	// ContentLength is the size of the file, or of the range when one was served
+	ContentLength int64
+	// ContentRange is the Content-Range of a served range, e.g. bytes 0-1023/4096, empty otherwise
+	ContentRange string
+}
+
+/*
+ContentETag returns the entity tag of the file of the given size, the original when size is 0
+- The original uses the ETag stored when it was uploaded
+- A variant has no stored ETag, it is derived from the one of the original since both never change once stored
+*/
+func (p *ProfilePic) ContentETag(size int) string {
+	if size == 0 {
+		return p.ETag
+	}
+
+	return `"` + strings.Trim(p.ETag, `"`) + "-" + strconv.Itoa(size) + `"`
+}
+
+// FileKey returns the S3 key of the file of the given size, the original when size is 0
+func (p *ProfilePic) FileKey(size int) (string, bool) {
+	if size == 0 {
+		return p.S3Key, true
+	}
+
+	for _, variantSize := range p.VariantSizes {
+		if variantSize == size {
+			return p.VariantKey(size), true
+		}
+	}
+	return "", false
+}
+
+// contentTypeOf keeps the content type stored with an image, the files stored without one are typed from their extension
+func contentTypeOf(storedContentType, key string) string {
+	if strings.HasPrefix(storedContentType, "image/") {
+		return storedContentType
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
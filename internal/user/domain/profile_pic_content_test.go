@@ -0,0 +1,30 @@
+package domain
+
+import "testing"
+
+func TestContentETag(t *testing.T) {
+	profilePic := &ProfilePic{ETag: `"abc"`, S3Key: "user/id/me.png", VariantSizes: []int{64, 256}}
+
+	if etag := profilePic.ContentETag(0); etag != `"abc"` {
+		t.Errorf("ETag of the original should be the stored one, got %s", etag)
+	}
+	if etag := profilePic.ContentETag(64); etag != `"abc-64"` {
+		t.Errorf("ETag of the variant should be derived, got %s", etag)
+	}
+
+	if key, ok := profilePic.FileKey(256); !ok || key != "user/id/256/me.png" {
+		t.Errorf("Key of the variant should be user/id/256/me.png, got %s", key)
+	}
+	if _, ok := profilePic.FileKey(128); ok {
+		t.Errorf("Key of a missing variant should not be found")
+	}
+}
+
+func TestContentTypeOf(t *testing.T) {
+	if contentType := contentTypeOf("image/png", "user/id/me.png"); contentType != "image/png" {
+		t.Errorf("Stored content type should be kept, got %s", contentType)
+	}
+	if contentType := contentTypeOf("binary/octet-stream", "user/id/me.jpg"); contentType != "image/jpeg" {
+		t.Errorf("Content type should come from the extension, got %s", contentType)
+	}
+}
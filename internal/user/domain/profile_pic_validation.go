@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"go-template/internal/user/domain/imaging"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// the limits used when user.pic_max_size and user.pic_max_dimension are not configured
+const (
+	DefaultProfilePicMaxSize      = 5 * 1024 * 1024
+	DefaultProfilePicMaxDimension = 4096
+)
+
+// maxFilenameLength bounds the stored filename without its extension (in characters)
+const maxFilenameLength = 100
+
+var (
+	ErrUnsupportedProfilePicFormat = errors.New("profile pic must be a jpeg or png image")
+	ErrProfilePicExtensionMismatch = errors.New("profile pic extension does not match its content")
+	ErrProfilePicDimensions        = errors.New("profile pic dimensions exceed the maximum")
+)
+
+// unsafeFilenameChars are replaced in the stored filename, it ends up in S3 keys and URLs
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ProfilePicLimits bound the uploaded profile pics
+type ProfilePicLimits struct {
+	// MaxSize is the maximum file size (in bytes)
+	MaxSize int64
+	// MaxDimension is the maximum width and height (in pixels)
+	MaxDimension int
+}
+
+// ProfilePicTooLargeError is returned when the upload exceeds the maximum file size
+type ProfilePicTooLargeError struct {
+	MaxSize int64
+	Size    int64
+}
+
+func (e *ProfilePicTooLargeError) Error() string {
+	return fmt.Sprintf("profile pic of %d bytes exceeds the maximum of %d bytes", e.Size, e.MaxSize)
+}
+
+// profilePicExtensions are the extensions accepted for each format, the first one is used for the stored filename
+var profilePicExtensions = map[string][]string{
+	imaging.FormatJPEG: {".jpg", ".jpeg"},
+	imaging.FormatPNG:  {".png"},
+}
+
+/*
+validateProfilePic checks the header of an upload and returns the filename to store it under
+- The format is sniffed from the content and has to match the extension of the filename
+- The dimensions are read from the header, the pixels of a too large image are never decoded
+*/
+func validateProfilePic(filename string, info *imaging.Info, limits ProfilePicLimits) (string, error) {
+	extensions := profilePicExtensions[info.Format]
+	if !contains(extensions, strings.ToLower(path.Ext(baseName(filename)))) {
+		return "", ErrProfilePicExtensionMismatch
+	}
+
+	if info.Width > limits.MaxDimension || info.Height > limits.MaxDimension {
+		return "", fmt.Errorf("%w: %dx%d is larger than %dx%d", ErrProfilePicDimensions, info.Width, info.Height, limits.MaxDimension, limits.MaxDimension)
+	}
+
+	return NormalizeProfilePicFilename(filename, extensions[0]), nil
+}
+
+// profilePicError maps the errors of the imaging package, a corrupt file or a file with data after the end of the image is refused,
+// the errors of the reader, e.g. its size limit, are returned as is
+func profilePicError(err error) error {
+	switch err {
+	case imaging.ErrUnsupportedFormat:
+		return ErrUnsupportedProfilePicFormat
+	case imaging.ErrInvalidImage, imaging.ErrTrailingData:
+		return ErrInvalidProfilePicContent
+	}
+	return err
+}
+
+// IsInvalidProfilePic reports whether the upload was refused for its content, the other errors are failures to read or store it
+func IsInvalidProfilePic(err error) bool {
+	return errors.Is(err, ErrInvalidProfilePicContent) ||
+		errors.Is(err, ErrUnsupportedProfilePicFormat) ||
+		errors.Is(err, ErrProfilePicExtensionMismatch) ||
+		errors.Is(err, ErrProfilePicDimensions) ||
+		errors.Is(err, ErrPicUploadMismatch)
+}
+
+// sizeLimitReader fails with err once more than limit bytes are read, or when exact and the stream ends before limit bytes
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	exact bool
+	err   error
+	read  int64
+}
+
+func newSizeLimitReader(r io.Reader, limit int64, exact bool, err error) *sizeLimitReader {
+	return &sizeLimitReader{r: r, limit: limit, exact: exact, err: err}
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit || (err == io.EOF && l.exact && l.read < l.limit) {
+		return n, l.err
+	}
+	return n, err
+}
+
+// readCloser reads through a wrapping reader and closes the file or the object it wraps
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+/*
+NormalizeProfilePicFilename makes the uploaded filename safe to use in S3 keys and URLs
+- The directories of the client path are dropped
+- The characters other than letters, digits, dots, dashes and underscores are replaced with a dash
+- The extension is replaced with the given one, the name falls back to profile-pic when nothing is left
+*/
+func NormalizeProfilePicFilename(filename, extension string) string {
+	name := baseName(filename)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	name = unsafeFilenameChars.ReplaceAllString(name, "-")
+	name = strings.Trim(name, ".-")
+
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = strings.TrimRight(string(runes[:maxFilenameLength]), ".-")
+	}
+	if name == "" {
+		name = "profile-pic"
+	}
+
+	return name + extension
+}
+
+// baseName drops the directories of a path sent by a client, with slashes or backslashes
+func baseName(filename string) string {
+	return path.Base(strings.ReplaceAll(filename, `\`, "/"))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"go-template/internal/user/domain/imaging"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newFileHeader parses a multipart form holding the content as profilePic
+func newFileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("profilePic", filename)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1024)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	return form.File["profilePic"][0]
+}
+
+func TestNormalizeProfilePicFilename(t *testing.T) {
+	cases := []struct {
+		filename  string
+		extension string
+		expected  string
+	}{
+		{"me.PNG", ".png", "me.png"},
+		{"../../etc/passwd.png", ".png", "passwd.png"},
+		{`C:\Users\me\holiday.jpeg`, ".jpg", "holiday.jpg"},
+		{"my photo (1).jpg", ".jpg", "my-photo-1.jpg"},
+		{".png", ".png", "profile-pic.png"},
+		{"noextension", ".png", "noextension.png"},
+	}
+
+	for _, tc := range cases {
+		if normalized := NormalizeProfilePicFilename(tc.filename, tc.extension); normalized != tc.expected {
+			t.Errorf("Filename %q should be normalized to %q, got %q", tc.filename, tc.expected, normalized)
+		}
+	}
+}
+
+func TestValidateProfilePic(t *testing.T) {
+	limits := ProfilePicLimits{MaxSize: 1024 * 1024, MaxDimension: 100}
+	info := &imaging.Info{Format: imaging.FormatPNG, Width: 50, Height: 40}
+
+	filename, err := validateProfilePic("My Pic.PNG", info, limits)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if filename != "My-Pic.png" {
+		t.Errorf("Filename should be My-Pic.png, got %s", filename)
+	}
+
+	if _, err := validateProfilePic("me.jpg", info, limits); err != ErrProfilePicExtensionMismatch {
+		t.Errorf("Error should be %v, got %v", ErrProfilePicExtensionMismatch, err)
+	}
+
+	large := &imaging.Info{Format: imaging.FormatPNG, Width: 200, Height: 40}
+	if _, err := validateProfilePic("me.png", large, limits); !errors.Is(err, ErrProfilePicDimensions) {
+		t.Errorf("Error should be %v, got %v", ErrProfilePicDimensions, err)
+	}
+}
+
+func TestOpenProfilePicFile(t *testing.T) {
+	// larger than the in-memory part of the form, the file is read from disk
+	content := bytes.Repeat([]byte("a"), 4096)
+	service := NewUserService(nil, ProfilePicLimits{MaxSize: 8192, MaxDimension: 100}, 0)
+
+	file, err := service.OpenProfilePicFile(newFileHeader(t, "me.png", content))
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	defer file.Close()
+
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if !bytes.Equal(fileBytes, content) {
+		t.Errorf("Content should be read completely, got %d bytes", len(fileBytes))
+	}
+
+	service = NewUserService(nil, ProfilePicLimits{MaxSize: 1024, MaxDimension: 100}, 0)
+
+	var tooLarge *ProfilePicTooLargeError
+	if _, err := service.OpenProfilePicFile(newFileHeader(t, "me.png", content)); !errors.As(err, &tooLarge) {
+		t.Errorf("Error should be a ProfilePicTooLargeError, got %v", err)
+	}
+}
+
+func TestSizeLimitReader(t *testing.T) {
+	errLimit := errors.New("limit")
+
+	t.Run("Test content past the limit", func(t *testing.T) {
+		reader := newSizeLimitReader(strings.NewReader("image"), 4, false, errLimit)
+		if _, err := io.ReadAll(reader); err != errLimit {
+			t.Errorf("Error should be %v, got %v", errLimit, err)
+		}
+	})
+
+	t.Run("Test content shorter than the exact size", func(t *testing.T) {
+		reader := newSizeLimitReader(strings.NewReader("image"), 6, true, errLimit)
+		if _, err := io.ReadAll(reader); err != errLimit {
+			t.Errorf("Error should be %v, got %v", errLimit, err)
+		}
+
+		reader = newSizeLimitReader(strings.NewReader("image"), 6, false, errLimit)
+		if _, err := io.ReadAll(reader); err != nil {
+			t.Errorf("Error should be nil, got %v", err)
+		}
+	})
+
+	t.Run("Test content of the exact size", func(t *testing.T) {
+		reader := newSizeLimitReader(strings.NewReader("image"), 5, true, errLimit)
+		content, err := io.ReadAll(reader)
+		if err != nil || string(content) != "image" {
+			t.Errorf("Content should be read, got %q and %v", content, err)
+		}
+	})
+}
@@ -1,9 +1,38 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type UserRepository interface {
+	// SaveProfilePic stores the profile pic as the next version and makes it the current one
 	SaveProfilePic(ctx context.Context, user *User, profilePic *ProfilePic) error
+	// GetProfilePic returns the current version
 	GetProfilePic(ctx context.Context, user *User) (*ProfilePic, error)
+	// GetProfilePicVersion returns a version, current or not
+	GetProfilePicVersion(ctx context.Context, user *User, version int) (*ProfilePic, error)
+	// ListProfilePicVersions returns every version of the user, the newest first
+	ListProfilePicVersions(ctx context.Context, user *User) ([]*ProfilePic, error)
+	// RestoreProfilePicVersion makes the version the current one again
+	RestoreProfilePicVersion(ctx context.Context, user *User, version int) (*ProfilePic, error)
+	// DeleteProfilePic removes every version of the user
 	DeleteProfilePic(ctx context.Context, user *User) error
+	// ListExpiredProfilePicVersions returns the versions replaced before the given time
+	ListExpiredProfilePicVersions(ctx context.Context, replacedBefore time.Time, limit int) ([]*ProfilePic, error)
+	// DeleteProfilePicVersion removes a version that is not the current one, sql.ErrNoRows when it is current again
+	DeleteProfilePicVersion(ctx context.Context, profilePic *ProfilePic) error
+
+	// direct uploads
+	CreatePicUpload(ctx context.Context, upload *PicUpload) error
+	// GetPicUpload returns the upload slot of the user
+	GetPicUpload(ctx context.Context, user *User, id string) (*PicUpload, error)
+	DeletePicUpload(ctx context.Context, upload *PicUpload) error
+	// ListExpiredPicUploads returns the upload slots that expired before now
+	ListExpiredPicUploads(ctx context.Context, now time.Time, limit int) ([]*PicUpload, error)
+	GetProfile(ctx context.Context, user *User) (*Profile, error)
+	// ListPurgeableUsers returns the deleted users whose grace period ended before now
+	ListPurgeableUsers(ctx context.Context, now time.Time, limit int) ([]*User, error)
+	// PurgeUser removes a deleted user, the rows referencing it are removed by the cascades
+	PurgeUser(ctx context.Context, user *User) error
 }
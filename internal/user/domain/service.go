@@ -1,67 +1,271 @@
 package domain
 
 import (
+	"context"
 	"errors"
-	"go-template/internal/aws/s3"
+	"go-template/internal/shared/infrastructure/storage"
+	"go-template/internal/user/domain/imaging"
+	"image"
+	"io"
 	"mime/multipart"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/samborkent/uuidv7"
 )
 
 var ErrInvalidProfilePicContent = errors.New("invalid profile pic content")
 
+// profilePicCacheControl lets the clients keep a file for good, every version and variant is stored under its own key
+const profilePicCacheControl = "private, max-age=31536000, immutable"
+
 type UserService interface {
-	ParseProfilePic(profilePic *multipart.FileHeader) ([]byte, error)
-	UploadProfilePic(userId, filename string, fileBytes []byte) (*ProfilePic, error)
-	DeleteProfilePic(key string) error
-	GetProfilePic(key string) ([]byte, error)
+	// OpenProfilePicFile opens a proxied upload, reading past the maximum size fails with a ProfilePicTooLargeError
+	OpenProfilePicFile(profilePic *multipart.FileHeader) (io.ReadCloser, error)
+	// UploadProfilePic checks, processes and stores the image read from content, IsInvalidProfilePic reports the refused content
+	UploadProfilePic(ctx context.Context, userId, filename string, content io.Reader) (*ProfilePic, error)
+	DeleteProfilePic(ctx context.Context, key string) error
+	GetProfilePic(ctx context.Context, key string) ([]byte, error)
+	// OpenProfilePic streams a file, or the part of it selected by byteRange (an HTTP Range header value) when set
+	OpenProfilePic(ctx context.Context, key, byteRange string) (*ProfilePicContent, error)
+	// direct uploads
+	CreatePicUpload(ctx context.Context, userID, filename, contentType string, size int64) (*PicUpload, *PresignedURL, error)
+	OpenPicUpload(ctx context.Context, upload *PicUpload) (io.ReadCloser, error)
+	PresignProfilePic(ctx context.Context, key string) (*PresignedURL, error)
 }
 
 type userService struct {
-	s3Module   s3.S3Module
-	repository UserRepository
+	storage storage.ObjectStorage
+	limits  ProfilePicLimits
+	// presignExpiration is how long the presigned upload and download URLs are valid
+	presignExpiration time.Duration
 }
 
-func NewUserService(s3Module s3.S3Module) UserService {
+func NewUserService(objectStorage storage.ObjectStorage, limits ProfilePicLimits, presignExpiration time.Duration) UserService {
 	return &userService{
-		s3Module: s3Module,
+		storage:           objectStorage,
+		limits:            limits,
+		presignExpiration: presignExpiration,
 	}
 }
 
-func (s *userService) ParseProfilePic(profilePic *multipart.FileHeader) ([]byte, error) {
+// OpenProfilePicFile opens the upload, the size of the header is not trusted, the reader fails past the maximum size
+func (s *userService) OpenProfilePicFile(profilePic *multipart.FileHeader) (io.ReadCloser, error) {
+	tooLarge := &ProfilePicTooLargeError{MaxSize: s.limits.MaxSize, Size: profilePic.Size}
+	if profilePic.Size > s.limits.MaxSize {
+		return nil, tooLarge
+	}
+
 	file, err := profilePic.Open()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	fileBytes := make([]byte, profilePic.Size)
-	if _, err = file.Read(fileBytes); err != nil {
-		return nil, ErrInvalidProfilePicContent
+	return &readCloser{Reader: newSizeLimitReader(file, s.limits.MaxSize, false, tooLarge), Closer: file}, nil
+}
+
+/*
+UploadProfilePic processes the image read from content and stores it with its variants
+- The header is checked against the limits before the pixels are decoded, the stored filename is normalized to match the format
+- The upload is re-encoded without its metadata and turned upright, the variants are stored under derived keys
+- The content is streamed from the reader and the encoded files into the storage, only the decoded pixels are held in memory
+- Every version is stored under its own key, a replaced version stays readable until it is purged
+- The files already uploaded are removed when a variant fails
+*/
+func (s *userService) UploadProfilePic(ctx context.Context, userId, filename string, content io.Reader) (*ProfilePic, error) {
+	source, err := imaging.Open(content)
+	if err != nil {
+		return nil, profilePicError(err)
 	}
 
-	return fileBytes, nil
-}
+	filename, err = validateProfilePic(filename, &source.Info, s.limits)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *userService) UploadProfilePic(userId, filename string, fileBytes []byte) (*ProfilePic, error) {
-	uniqueKey := userId + "/" + filename
-	uploadResult, err := s.s3Module.UploadFile(uniqueKey, fileBytes)
+	img, err := source.Decode()
+	if err != nil {
+		return nil, profilePicError(err)
+	}
+
+	id := uuidv7.New().String()
+	uniqueKey := userId + "/" + id + "/" + filename
+	opts := &storage.UploadOptions{
+		ContentType:  profilePicContentType(source.Format),
+		CacheControl: profilePicCacheControl,
+		Metadata:     map[string]string{"user-id": userId},
+	}
+
+	uploadResult, err := s.uploadImage(ctx, uniqueKey, img, source.Format, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewProfilePic(
+	profilePic := NewProfilePic(
+		id,
 		filename,
 		uploadResult.Location,
-		*uploadResult.Key,
-		*uploadResult.ETag,
+		uploadResult.Key,
+		uploadResult.ETag,
 		uploadResult.ServerSideEncryption,
-		*uploadResult.SSEKMSKeyId,
-	), nil
+		uploadResult.SSEKMSKeyID,
+	)
+
+	for _, size := range ProfilePicVariantSizes {
+		if _, err := s.uploadImage(ctx, profilePic.VariantKey(size), imaging.Variant(img, size), source.Format, opts); err != nil {
+			s.deleteFiles(profilePic.Keys())
+			return nil, err
+		}
+		profilePic.VariantSizes = append(profilePic.VariantSizes, size)
+	}
+
+	return profilePic, nil
+}
+
+// uploadImage encodes the image into the upload through a pipe, the encoder stops when the upload fails
+func (s *userService) uploadImage(ctx context.Context, key string, img image.Image, format string, opts *storage.UploadOptions) (*storage.UploadResult, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(imaging.Encode(writer, img, format))
+	}()
+	defer reader.Close()
+
+	return s.storage.UploadFile(ctx, key, reader, opts)
+}
+
+// deleteFiles removes the files on a best effort basis, the upload already failed
+// it does not use the context of the request since a cancelled request is one of the reasons of the failure
+func (s *userService) deleteFiles(keys []string) {
+	for _, key := range keys {
+		_ = s.storage.DeleteFile(context.Background(), key)
+	}
+}
+
+func (s *userService) DeleteProfilePic(ctx context.Context, key string) error {
+	return s.storage.DeleteFile(ctx, key)
+}
+
+func (s *userService) GetProfilePic(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.storage.GetFile(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	return io.ReadAll(object.Body)
+}
+
+func (s *userService) OpenProfilePic(ctx context.Context, key, byteRange string) (*ProfilePicContent, error) {
+	object, err := s.storage.GetFile(ctx, key, &storage.GetOptions{Range: byteRange})
+	if err != nil {
+		if err == storage.ErrInvalidRange {
+			return nil, ErrProfilePicRangeNotSatisfiable
+		}
+		return nil, err
+	}
+
+	return &ProfilePicContent{
+		Body:          object.Body,
+		ContentType:   contentTypeOf(object.ContentType, key),
+		ContentLength: object.ContentLength,
+		ContentRange:  object.ContentRange,
+	}, nil
+}
+
+/*
+CreatePicUpload checks what the client announces and returns a slot with the URL to upload the file to
+- The content type, the extension and the size are checked before anything is uploaded
+- The content type and the size are signed, S3 refuses an upload that does not match them
+*/
+func (s *userService) CreatePicUpload(ctx context.Context, userID, filename, contentType string, size int64) (*PicUpload, *PresignedURL, error) {
+	format, ok := profilePicContentTypes[contentType]
+	if !ok {
+		return nil, nil, ErrUnsupportedProfilePicFormat
+	}
+
+	extensions := profilePicExtensions[format]
+	if !contains(extensions, strings.ToLower(path.Ext(baseName(filename)))) {
+		return nil, nil, ErrProfilePicExtensionMismatch
+	}
+
+	if size > s.limits.MaxSize {
+		return nil, nil, &ProfilePicTooLargeError{MaxSize: s.limits.MaxSize, Size: size}
+	}
+
+	upload := NewPicUpload(userID, NormalizeProfilePicFilename(filename, extensions[0]), contentType, size, s.presignExpiration)
+
+	request, err := s.storage.PresignPutFile(ctx, upload.S3Key, upload.ContentType, upload.Size, s.presignExpiration)
+	if err != nil {
+		if err == storage.ErrPresignNotSupported {
+			return nil, nil, ErrDirectTransferUnavailable
+		}
+		return nil, nil, err
+	}
+
+	return upload, newPresignedURL(request), nil
+}
+
+/*
+OpenPicUpload checks the uploaded object with a HEAD request before its content is read
+- The object must have the size and the content type of the upload request
+- The content is streamed from S3 to be validated and processed like a proxied upload, it never passes through the proxy in front of the API
+- The object can be overwritten between both requests, the reader fails with ErrPicUploadMismatch when its size is not the announced one
+*/
+func (s *userService) OpenPicUpload(ctx context.Context, upload *PicUpload) (io.ReadCloser, error) {
+	info, err := s.storage.HeadFile(ctx, upload.S3Key)
+	if err != nil {
+		if err == storage.ErrObjectNotFound {
+			return nil, ErrPicUploadMissing
+		}
+		return nil, err
+	}
+
+	if info.ContentLength > s.limits.MaxSize {
+		return nil, &ProfilePicTooLargeError{MaxSize: s.limits.MaxSize, Size: info.ContentLength}
+	}
+	if info.ContentLength != upload.Size || info.ContentType != upload.ContentType {
+		return nil, ErrPicUploadMismatch
+	}
+
+	object, err := s.storage.GetFile(ctx, upload.S3Key, nil)
+	if err != nil {
+		if err == storage.ErrObjectNotFound {
+			return nil, ErrPicUploadMissing
+		}
+		return nil, err
+	}
+
+	return &readCloser{Reader: newSizeLimitReader(object.Body, upload.Size, true, ErrPicUploadMismatch), Closer: object.Body}, nil
 }
 
-func (s *userService) DeleteProfilePic(key string) error {
-	return s.s3Module.DeleteFile(key)
+func (s *userService) PresignProfilePic(ctx context.Context, key string) (*PresignedURL, error) {
+	request, err := s.storage.PresignGetFile(ctx, key, s.presignExpiration)
+	if err != nil {
+		if err == storage.ErrPresignNotSupported {
+			return nil, ErrDirectTransferUnavailable
+		}
+		return nil, err
+	}
+
+	return newPresignedURL(request), nil
 }
 
-func (s *userService) GetProfilePic(key string) ([]byte, error) {
-	return s.s3Module.GetFile(key)
+func newPresignedURL(request *storage.PresignedRequest) *PresignedURL {
+	return &PresignedURL{
+		URL:       request.URL,
+		Method:    request.Method,
+		Header:    request.Header,
+		ExpiresAt: request.ExpiresAt,
+	}
+}
+
+// profilePicContentType returns the content type of an image format
+func profilePicContentType(format string) string {
+	for contentType, f := range profilePicContentTypes {
+		if f == format {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
 }
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-template/internal/shared/infrastructure/storage"
+	"io"
+	"testing"
+	"time"
+)
+
+// countingStorage counts the uploaded files
+type countingStorage struct {
+	storage.ObjectStorage
+	uploads int
+}
+
+func (s *countingStorage) UploadFile(ctx context.Context, key string, body io.Reader, opts *storage.UploadOptions) (*storage.UploadResult, error) {
+	s.uploads++
+	return s.ObjectStorage.UploadFile(ctx, key, body, opts)
+}
+
+func TestUploadProfilePic(t *testing.T) {
+	ctx := context.Background()
+	objectStorage := &countingStorage{ObjectStorage: storage.NewMemoryStorage()}
+	service := NewUserService(objectStorage, ProfilePicLimits{MaxSize: 1 << 20, MaxDimension: 2048}, time.Minute)
+
+	profilePic, err := service.UploadProfilePic(ctx, "user", "me.png", bytes.NewReader(encodePNG(t, 300, 200)))
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+
+	if len(profilePic.VariantSizes) != len(ProfilePicVariantSizes) {
+		t.Errorf("Variant sizes should be %v, got %v", ProfilePicVariantSizes, profilePic.VariantSizes)
+	}
+
+	for _, key := range profilePic.Keys() {
+		info, err := objectStorage.HeadFile(ctx, key)
+		if err != nil {
+			t.Fatalf("File %s should be stored, got %v", key, err)
+		}
+		if info.ContentType != "image/png" || info.CacheControl != profilePicCacheControl {
+			t.Errorf("File %s should be stored with its content type and cache control, got %+v", key, info)
+		}
+	}
+
+	if profilePic.ETag == "" || profilePic.S3Key != "user/"+profilePic.ID+"/me.png" {
+		t.Errorf("Profile pic should describe the stored file, got %+v", profilePic)
+	}
+
+	t.Run("Test refused content", func(t *testing.T) {
+		content := encodePNG(t, 50, 40)
+		cases := []struct {
+			filename string
+			content  []byte
+			expected error
+		}{
+			{"me.png", []byte("GIF89a"), ErrUnsupportedProfilePicFormat},
+			{"me.jpg", content, ErrProfilePicExtensionMismatch},
+			{"me.png", encodePNG(t, 3000, 10), ErrProfilePicDimensions},
+			{"me.png", append(content, "PK\x03\x04"...), ErrInvalidProfilePicContent},
+		}
+
+		for _, tc := range cases {
+			_, err := service.UploadProfilePic(ctx, "refused", tc.filename, bytes.NewReader(tc.content))
+			if !errors.Is(err, tc.expected) || !IsInvalidProfilePic(err) {
+				t.Errorf("Error should be %v, got %v", tc.expected, err)
+			}
+		}
+
+		if objectStorage.uploads != 1+len(ProfilePicVariantSizes) {
+			t.Errorf("Refused content should not be stored, got %d uploads", objectStorage.uploads)
+		}
+	})
+
+	t.Run("Test reader error", func(t *testing.T) {
+		tooLarge := &ProfilePicTooLargeError{MaxSize: 10, Size: 10}
+		content := newSizeLimitReader(bytes.NewReader(encodePNG(t, 50, 40)), 100, false, tooLarge)
+
+		if _, err := service.UploadProfilePic(ctx, "user", "me.png", content); err != tooLarge {
+			t.Errorf("Error should be %v, got %v", tooLarge, err)
+		}
+	})
+}
+
+func TestOpenPicUpload(t *testing.T) {
+	ctx := context.Background()
+	objectStorage := storage.NewMemoryStorage()
+	service := NewUserService(objectStorage, ProfilePicLimits{MaxSize: 1024, MaxDimension: 100}, time.Minute)
+
+	upload := NewPicUpload("user", "me.png", "image/png", 5, time.Minute)
+
+	if _, err := service.OpenPicUpload(ctx, upload); err != ErrPicUploadMissing {
+		t.Errorf("Error should be %v, got %v", ErrPicUploadMissing, err)
+	}
+
+	objectStorage.UploadFile(ctx, upload.S3Key, bytes.NewReader([]byte("image")), &storage.UploadOptions{ContentType: "image/jpeg"})
+	if _, err := service.OpenPicUpload(ctx, upload); err != ErrPicUploadMismatch {
+		t.Errorf("Error should be %v, got %v", ErrPicUploadMismatch, err)
+	}
+
+	objectStorage.UploadFile(ctx, upload.S3Key, bytes.NewReader([]byte("image")), &storage.UploadOptions{ContentType: "image/png"})
+	file, err := service.OpenPicUpload(ctx, upload)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("Error should be nil, got %v", err)
+	}
+	if string(content) != "image" {
+		t.Errorf("Content should be read, got %s", content)
+	}
+}
+
+func TestPresignWithoutSupport(t *testing.T) {
+	service := NewUserService(storage.NewMemoryStorage(), ProfilePicLimits{MaxSize: 1024, MaxDimension: 100}, time.Minute)
+
+	if _, _, err := service.CreatePicUpload(context.Background(), "user", "me.png", "image/png", 5); err != ErrDirectTransferUnavailable {
+		t.Errorf("Error should be %v, got %v", ErrDirectTransferUnavailable, err)
+	}
+}
@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"context"
+	"go-template/internal/user/domain"
+	"time"
+)
+
+const picUploadSelect = `SELECT id, user_id, s3_key, filename, content_type, size, expires_at, created_at FROM user_pic_uploads`
+
+func scanPicUpload(row rowScanner) (*domain.PicUpload, error) {
+	upload := domain.PicUpload{}
+	err := row.Scan(&upload.ID, &upload.UserID, &upload.S3Key, &upload.Filename, &upload.ContentType, &upload.Size, &upload.ExpiresAt, &upload.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+func (r *postgresUserRepository) CreatePicUpload(ctx context.Context, upload *domain.PicUpload) error {
+	query := `INSERT INTO user_pic_uploads(id, user_id, s3_key, filename, content_type, size, expires_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query, upload.ID, upload.UserID, upload.S3Key, upload.Filename, upload.ContentType, upload.Size, upload.ExpiresAt, upload.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) GetPicUpload(ctx context.Context, user *domain.User, id string) (*domain.PicUpload, error) {
+	query := picUploadSelect + ` WHERE id = $1 AND user_id = $2`
+	return scanPicUpload(r.db.QueryRowContext(ctx, query, id, user.ID))
+}
+
+func (r *postgresUserRepository) DeletePicUpload(ctx context.Context, upload *domain.PicUpload) error {
+	query := `DELETE FROM user_pic_uploads WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) ListExpiredPicUploads(ctx context.Context, now time.Time, limit int) ([]*domain.PicUpload, error) {
+	query := picUploadSelect + ` WHERE expires_at <= $1 ORDER BY expires_at LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := []*domain.PicUpload{}
+	for rows.Next() {
+		upload, err := scanPicUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
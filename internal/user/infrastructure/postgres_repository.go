@@ -2,8 +2,12 @@ package infrastructure
 
 import (
 	"context"
+	"database/sql"
 	"go-template/internal/shared/infrastructure/database"
 	"go-template/internal/user/domain"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type postgresUserRepository struct {
@@ -16,26 +20,138 @@ func NewPostgresUserRepository(db database.BaseDatabase) domain.UserRepository {
 	}
 }
 
+// profilePicSelect lists the columns scanned by scanProfilePic, the queries add their WHERE clause
+const profilePicSelect = `
+	SELECT id, version, filename, uploaded_at, url, s3_key, etag, encryption, encryption_key, variant_sizes, is_current, replaced_at
+	FROM user_pic`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProfilePic(row rowScanner) (*domain.ProfilePic, error) {
+	profilePic := domain.ProfilePic{}
+	var replacedAt sql.NullTime
+	var variantSizes []int64
+	err := row.Scan(
+		&profilePic.ID,
+		&profilePic.Version,
+		&profilePic.Filename,
+		&profilePic.UploadedAt,
+		&profilePic.Url,
+		&profilePic.S3Key,
+		&profilePic.ETag,
+		&profilePic.Encryption,
+		&profilePic.EncryptionKey,
+		pq.Array(&variantSizes),
+		&profilePic.Current,
+		&replacedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if replacedAt.Valid {
+		profilePic.ReplacedAt = &replacedAt.Time
+	}
+	for _, size := range variantSizes {
+		profilePic.VariantSizes = append(profilePic.VariantSizes, int(size))
+	}
+
+	return &profilePic, nil
+}
+
+// SaveProfilePic locks the user so that concurrent uploads get consecutive versions
 func (r *postgresUserRepository) SaveProfilePic(ctx context.Context, user *domain.User, profilePic *domain.ProfilePic) error {
-	query := `INSERT INTO user_pic(user_id, filename, uploaded_at, url, s3_key, etag, encryption, encryption_key) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := r.db.ExecContext(ctx, query, user.ID, profilePic.Filename, profilePic.UploadedAt, profilePic.Url, profilePic.S3Key, profilePic.ETag, profilePic.Encryption, profilePic.EncryptionKey)
+	var version int
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, user.ID); err != nil {
+			return err
+		}
+
+		query := `SELECT COALESCE(MAX(version), 0) + 1 FROM user_pic WHERE user_id = $1`
+		if err := r.db.QueryRowContext(ctx, query, user.ID).Scan(&version); err != nil {
+			return err
+		}
+
+		query = `UPDATE user_pic SET is_current = false, replaced_at = $2 WHERE user_id = $1 AND is_current`
+		if _, err := r.db.ExecContext(ctx, query, user.ID, time.Now()); err != nil {
+			return err
+		}
+
+		variantSizes := make([]int64, 0, len(profilePic.VariantSizes))
+		for _, size := range profilePic.VariantSizes {
+			variantSizes = append(variantSizes, int64(size))
+		}
+
+		query = `
+			INSERT INTO user_pic(id, user_id, version, filename, uploaded_at, url, s3_key, etag, encryption, encryption_key, variant_sizes, is_current)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, true)
+		`
+		_, err := r.db.ExecContext(ctx, query, profilePic.ID, user.ID, version, profilePic.Filename, profilePic.UploadedAt, profilePic.Url, profilePic.S3Key, profilePic.ETag, profilePic.Encryption, profilePic.EncryptionKey, pq.Array(variantSizes))
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
+	profilePic.Version = version
+	profilePic.Current = true
+	profilePic.ReplacedAt = nil
+
 	return nil
 }
 
 func (r *postgresUserRepository) GetProfilePic(ctx context.Context, user *domain.User) (*domain.ProfilePic, error) {
-	query := `SELECT filename, uploaded_at, url, s3_key, etag, encryption, encryption_key FROM user_pic WHERE user_id = $1`
+	query := profilePicSelect + ` WHERE user_id = $1 AND is_current`
+	return scanProfilePic(r.db.QueryRowContext(ctx, query, user.ID))
+}
 
-	profilePic := domain.ProfilePic{}
-	err := r.db.QueryRowContext(ctx, query, user.ID).Scan(&profilePic.Filename, &profilePic.UploadedAt, &profilePic.Url, &profilePic.S3Key, &profilePic.ETag, &profilePic.Encryption, &profilePic.EncryptionKey)
+func (r *postgresUserRepository) GetProfilePicVersion(ctx context.Context, user *domain.User, version int) (*domain.ProfilePic, error) {
+	query := profilePicSelect + ` WHERE user_id = $1 AND version = $2`
+	return scanProfilePic(r.db.QueryRowContext(ctx, query, user.ID, version))
+}
+
+func (r *postgresUserRepository) ListProfilePicVersions(ctx context.Context, user *domain.User) ([]*domain.ProfilePic, error) {
+	query := profilePicSelect + ` WHERE user_id = $1 ORDER BY version DESC`
+	return r.listProfilePics(ctx, query, user.ID)
+}
+
+// RestoreProfilePicVersion returns sql.ErrNoRows when the user has no such version
+func (r *postgresUserRepository) RestoreProfilePicVersion(ctx context.Context, user *domain.User, version int) (*domain.ProfilePic, error) {
+	var profilePic *domain.ProfilePic
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, user.ID); err != nil {
+			return err
+		}
+
+		var err error
+		profilePic, err = scanProfilePic(r.db.QueryRowContext(ctx, profilePicSelect+` WHERE user_id = $1 AND version = $2`, user.ID, version))
+		if err != nil {
+			return err
+		}
+
+		if profilePic.Current {
+			return nil
+		}
+
+		query := `UPDATE user_pic SET is_current = false, replaced_at = $2 WHERE user_id = $1 AND is_current`
+		if _, err := r.db.ExecContext(ctx, query, user.ID, time.Now()); err != nil {
+			return err
+		}
+
+		query = `UPDATE user_pic SET is_current = true, replaced_at = NULL WHERE id = $1`
+		_, err = r.db.ExecContext(ctx, query, profilePic.ID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &profilePic, nil
+	profilePic.Current = true
+	profilePic.ReplacedAt = nil
+
+	return profilePic, nil
 }
 
 func (r *postgresUserRepository) DeleteProfilePic(ctx context.Context, user *domain.User) error {
@@ -47,3 +163,78 @@ func (r *postgresUserRepository) DeleteProfilePic(ctx context.Context, user *dom
 
 	return nil
 }
+
+func (r *postgresUserRepository) ListExpiredProfilePicVersions(ctx context.Context, replacedBefore time.Time, limit int) ([]*domain.ProfilePic, error) {
+	query := profilePicSelect + ` WHERE NOT is_current AND replaced_at <= $1 ORDER BY replaced_at LIMIT $2`
+	return r.listProfilePics(ctx, query, replacedBefore, limit)
+}
+
+// DeleteProfilePicVersion returns sql.ErrNoRows when the version was restored or deleted in the meantime
+func (r *postgresUserRepository) DeleteProfilePicVersion(ctx context.Context, profilePic *domain.ProfilePic) error {
+	query := `DELETE FROM user_pic WHERE id = $1 AND NOT is_current RETURNING id`
+	var id string
+	return r.db.QueryRowContext(ctx, query, profilePic.ID).Scan(&id)
+}
+
+func (r *postgresUserRepository) listProfilePics(ctx context.Context, query string, args ...interface{}) ([]*domain.ProfilePic, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profilePics := []*domain.ProfilePic{}
+	for rows.Next() {
+		profilePic, err := scanProfilePic(rows)
+		if err != nil {
+			return nil, err
+		}
+		profilePics = append(profilePics, profilePic)
+	}
+
+	return profilePics, rows.Err()
+}
+
+func (r *postgresUserRepository) GetProfile(ctx context.Context, user *domain.User) (*domain.Profile, error) {
+	query := `SELECT id, email, first_name, last_name, verify, status, created_at, updated_at FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	profile := domain.Profile{}
+	err := r.db.QueryRowContext(ctx, query, user.ID).Scan(&profile.ID, &profile.Email, &profile.FirstName, &profile.LastName, &profile.Verified, &profile.Status, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+func (r *postgresUserRepository) ListPurgeableUsers(ctx context.Context, now time.Time, limit int) ([]*domain.User, error) {
+	query := `SELECT id FROM users WHERE deleted_at IS NOT NULL AND purge_after <= $1 ORDER BY purge_after LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, domain.NewUser(id))
+	}
+
+	return users, rows.Err()
+}
+
+func (r *postgresUserRepository) PurgeUser(ctx context.Context, user *domain.User) error {
+	// only a soft deleted user can be purged
+	query := `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL`
+	_, err := r.db.ExecContext(ctx, query, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"encoding/base64"
+	"go-template/internal/user/domain"
+	"time"
+)
+
+type ExportProfile struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Verified  bool      `json:"verified"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExportProfilePic struct {
+	FileName   string    `json:"file_name"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	URL        string    `json:"url"`
+	ETag       string    `json:"etag"`
+	Encryption string    `json:"encryption"`
+	// Content is the base64 encoded image, the zip archive carries it as a separate file instead
+	Content string `json:"content,omitempty"`
+}
+
+type ExportResponse struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Profile    ExportProfile     `json:"profile"`
+	ProfilePic *ExportProfilePic `json:"profile_pic,omitempty"`
+}
+
+// NewExportResponse embeds the profile pic content only when withContent is set
+func NewExportResponse(export *domain.DataExport, withContent bool) *ExportResponse {
+	response := &ExportResponse{
+		ExportedAt: export.ExportedAt,
+		Profile: ExportProfile{
+			ID:        export.Profile.ID,
+			Email:     export.Profile.Email,
+			FirstName: export.Profile.FirstName,
+			LastName:  export.Profile.LastName,
+			Verified:  export.Profile.Verified,
+			Status:    export.Profile.Status,
+			CreatedAt: export.Profile.CreatedAt,
+			UpdatedAt: export.Profile.UpdatedAt,
+		},
+	}
+
+	if export.ProfilePic != nil {
+		response.ProfilePic = &ExportProfilePic{
+			FileName:   export.ProfilePic.Filename,
+			UploadedAt: export.ProfilePic.UploadedAt,
+			URL:        export.ProfilePic.Url,
+			ETag:       export.ProfilePic.ETag,
+			Encryption: export.ProfilePic.Encryption,
+		}
+		if withContent {
+			response.ProfilePic.Content = base64.StdEncoding.EncodeToString(export.ProfilePicContent)
+		}
+	}
+
+	return response
+}
@@ -2,20 +2,85 @@ package dto
 
 import (
 	"go-template/internal/user/domain"
+	"net/url"
+	"strconv"
 )
 
+// picContentPath serves the files of the profile pic, see UserHandler.GetProfilePicContent
+const picContentPath = "/v1/user/self/pic/content"
+
 type PicResponse struct {
-	UserID     string `json:"user_id"`
-	FileName   string `json:"file_name"`
-	URL        string `json:"url"`
-	UploadDate string `json:"upload_date"`
+	UserID   string `json:"user_id"`
+	Version  int    `json:"version"`
+	Current  bool   `json:"current"`
+	FileName string `json:"file_name"`
+	// URL serves the file through the API, with the bearer token of the user
+	URL        string  `json:"url" example:"/v1/user/self/pic/content"`
+	ETag       string  `json:"etag"`
+	UploadDate string  `json:"upload_date"`
+	ReplacedAt *string `json:"replaced_at"`
+	// Variants are the square copies of the profile pic, the smallest first
+	Variants []*PicVariantResponse `json:"variants"`
+}
+
+type PicVariantResponse struct {
+	Size int    `json:"size" example:"256"`
+	URL  string `json:"url"`
+}
+
+type PicVersionsResponse struct {
+	Versions []*PicResponse `json:"versions"`
 }
 
-func NewPicResponse(user *domain.User, profilePic *domain.ProfilePic, bucketName string) *PicResponse {
+func NewPicResponse(user *domain.User, profilePic *domain.ProfilePic) *PicResponse {
+	var replacedAt *string
+	if profilePic.ReplacedAt != nil {
+		formatted := profilePic.ReplacedAt.Format("2006-01-02T15:04:05.000Z")
+		replacedAt = &formatted
+	}
+
+	variants := make([]*PicVariantResponse, 0, len(profilePic.VariantSizes))
+	for _, size := range profilePic.VariantSizes {
+		variants = append(variants, &PicVariantResponse{
+			Size: size,
+			URL:  picContentURL(profilePic, size),
+		})
+	}
+
 	return &PicResponse{
 		UserID:     user.ID,
+		Version:    profilePic.Version,
+		Current:    profilePic.Current,
 		FileName:   profilePic.Filename,
-		URL:        bucketName + "/" + user.ID + "/" + profilePic.Filename,
+		URL:        picContentURL(profilePic, 0),
+		ETag:       profilePic.ETag,
 		UploadDate: profilePic.UploadedAt.Format("2006-01-02"),
+		ReplacedAt: replacedAt,
+		Variants:   variants,
+	}
+}
+
+func NewPicVersionsResponse(user *domain.User, profilePics []*domain.ProfilePic) *PicVersionsResponse {
+	versions := make([]*PicResponse, 0, len(profilePics))
+	for _, profilePic := range profilePics {
+		versions = append(versions, NewPicResponse(user, profilePic))
+	}
+
+	return &PicVersionsResponse{Versions: versions}
+}
+
+// picContentURL selects the version unless it is the current one and the variant unless size is 0
+func picContentURL(profilePic *domain.ProfilePic, size int) string {
+	query := url.Values{}
+	if !profilePic.Current {
+		query.Set("version", strconv.Itoa(profilePic.Version))
+	}
+	if size > 0 {
+		query.Set("size", strconv.Itoa(size))
+	}
+
+	if len(query) == 0 {
+		return picContentPath
 	}
+	return picContentPath + "?" + query.Encode()
 }
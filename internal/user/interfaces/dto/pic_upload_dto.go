@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"go-template/internal/user/domain"
+)
+
+type PicUploadInput struct {
+	Filename    string `json:"filename" example:"photo.jpg" binding:"required,max=255"`
+	ContentType string `json:"content_type" example:"image/jpeg" binding:"required"`
+	// Size is the exact size of the file, the presigned request only accepts this content length (in bytes)
+	Size int64 `json:"size" example:"102400" binding:"required,min=1"`
+}
+
+type PicUploadResponse struct {
+	UploadID string `json:"upload_id"`
+	Method   string `json:"method" example:"PUT"`
+	URL      string `json:"url"`
+	// Headers must be sent with the upload request as they are signed
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt string            `json:"expires_at"`
+}
+
+type PicURLResponse struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func NewPicUploadResponse(upload *domain.PicUpload, presignedURL *domain.PresignedURL) *PicUploadResponse {
+	headers := make(map[string]string, len(presignedURL.Header))
+	for name := range presignedURL.Header {
+		headers[name] = presignedURL.Header.Get(name)
+	}
+
+	return &PicUploadResponse{
+		UploadID:  upload.ID,
+		Method:    presignedURL.Method,
+		URL:       presignedURL.URL,
+		Headers:   headers,
+		ExpiresAt: presignedURL.ExpiresAt.Format("2006-01-02T15:04:05.000Z"),
+	}
+}
+
+func NewPicURLResponse(presignedURL *domain.PresignedURL) *PicURLResponse {
+	return &PicURLResponse{
+		URL:       presignedURL.URL,
+		ExpiresAt: presignedURL.ExpiresAt.Format("2006-01-02T15:04:05.000Z"),
+	}
+}
@@ -1,35 +1,81 @@
 package http
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
 	authDomain "go-template/internal/auth/domain"
-	"go-template/internal/aws/s3"
 	"go-template/internal/user/application"
 	"go-template/internal/user/domain"
 	"go-template/internal/user/interfaces/dto"
+	"go-template/pkg/apperrors"
 	"net/http"
+	"path"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// multipartOverhead is allowed on top of the maximum profile pic size for the boundaries and the part headers (in bytes)
+const multipartOverhead = 64 * 1024
+
 type UserHandler struct {
 	userApplicationService application.UserApplicationService
-	s3Module               s3.S3Module
+	maxPicSize             int64
 }
 
-func NewUserHandler(userApplicationService application.UserApplicationService, s3Module s3.S3Module) *UserHandler {
+func NewUserHandler(userApplicationService application.UserApplicationService, maxPicSize int64) *UserHandler {
 	return &UserHandler{
 		userApplicationService: userApplicationService,
-		s3Module:               s3Module,
+		maxPicSize:             maxPicSize,
 	}
 }
 
+// @Summary Upload the profile pic
+// @Description Upload the first profile pic, PUT replaces an existing one
+// @Tags user
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param profilePic formData file true "jpg or png image"
+// @Success 200 {object} dto.PicResponse
+// @Router /v1/user/self/pic [post]
 func (h *UserHandler) UploadProfilePic(c *gin.Context) {
+	h.saveProfilePic(c, false)
+}
+
+// @Summary Replace the profile pic
+// @Description Upload a new version of the profile pic, the previous one is kept in the versions until the retention ends
+// @Tags user
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param profilePic formData file true "jpg or png image"
+// @Success 200 {object} dto.PicResponse
+// @Router /v1/user/self/pic [put]
+func (h *UserHandler) ReplaceProfilePic(c *gin.Context) {
+	h.saveProfilePic(c, true)
+}
+
+// saveProfilePic uploads a new version, it is refused when a profile pic exists unless replace is set
+func (h *UserHandler) saveProfilePic(c *gin.Context, replace bool) {
 	authUser, _ := c.Get("user")
 	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
 
-	// multipart/form-data
+	// multipart/form-data, the body is cut before a too large file is buffered
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxPicSize+multipartOverhead)
 	profilePicFile, err := c.FormFile("profilePic")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apperr := apperrors.NewPayloadTooLarge(h.maxPicSize, c.Request.ContentLength)
+			c.JSON(apperr.Status(), gin.H{
+				"error": apperr.Message,
+			})
+			return
+		}
+
 		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"error": "profilePic is required",
 		})
@@ -45,16 +91,17 @@ func (h *UserHandler) UploadProfilePic(c *gin.Context) {
 	}
 
 	// check if profile pic already exists
-	profilePic, apperr := h.userApplicationService.GetProfilePic(c, user)
-	if apperr == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Profile pic already exists",
-		})
-		return
+	if !replace {
+		if _, apperr := h.userApplicationService.GetProfilePic(c, user); apperr == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Profile pic already exists",
+			})
+			return
+		}
 	}
 
 	// Save the file
-	profilePic, apperr = h.userApplicationService.UploadProfilePic(c, user, profilePicFile)
+	profilePic, apperr := h.userApplicationService.UploadProfilePic(c, user, profilePicFile)
 	if apperr != nil {
 		c.JSON(apperr.Status(), gin.H{
 			"error": apperr.Message,
@@ -62,7 +109,7 @@ func (h *UserHandler) UploadProfilePic(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.NewPicResponse(user, profilePic, h.s3Module.GetBucketName()))
+	c.JSON(http.StatusOK, dto.NewPicResponse(user, profilePic))
 }
 
 func (h *UserHandler) GetProfilePic(c *gin.Context) {
@@ -77,7 +124,7 @@ func (h *UserHandler) GetProfilePic(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.NewPicResponse(user, profilePic, h.s3Module.GetBucketName()))
+	c.JSON(http.StatusOK, dto.NewPicResponse(user, profilePic))
 }
 
 func (h *UserHandler) DeleteProfilePic(c *gin.Context) {
@@ -94,3 +141,223 @@ func (h *UserHandler) DeleteProfilePic(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// @Summary List the profile pic versions
+// @Description List the current profile pic and the previous versions that can be restored, the newest first
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.PicVersionsResponse
+// @Router /v1/user/self/pic/versions [get]
+func (h *UserHandler) ListProfilePicVersions(c *gin.Context) {
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	profilePics, apperr := h.userApplicationService.ListProfilePicVersions(c, user)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewPicVersionsResponse(user, profilePics))
+}
+
+// @Summary Restore a profile pic version
+// @Description Make a previous version the current profile pic again
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param version path int true "Version"
+// @Success 200 {object} dto.PicResponse
+// @Router /v1/user/self/pic/versions/{version}/restore [post]
+func (h *UserHandler) RestoreProfilePicVersion(c *gin.Context) {
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "version must be a positive number",
+		})
+		return
+	}
+
+	profilePic, apperr := h.userApplicationService.RestoreProfilePicVersion(c, user, version)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewPicResponse(user, profilePic))
+}
+
+// @Summary Request a profile pic upload
+// @Description Get a presigned URL to upload the profile pic directly to the storage, then confirm the upload
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body dto.PicUploadInput true "File details"
+// @Success 201 {object} dto.PicUploadResponse
+// @Router /v1/user/self/pic/uploads [post]
+func (h *UserHandler) CreateProfilePicUpload(c *gin.Context) {
+	var input dto.PicUploadInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	upload, presignedURL, apperr := h.userApplicationService.CreateProfilePicUpload(c, user, input.Filename, input.ContentType, input.Size)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewPicUploadResponse(upload, presignedURL))
+}
+
+// @Summary Confirm a profile pic upload
+// @Description Validate the uploaded file and make it the current profile pic, the previous one is kept in the versions
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Upload ID"
+// @Success 200 {object} dto.PicResponse
+// @Router /v1/user/self/pic/uploads/{id}/confirm [post]
+func (h *UserHandler) ConfirmProfilePicUpload(c *gin.Context) {
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	profilePic, apperr := h.userApplicationService.ConfirmProfilePicUpload(c, user, c.Param("id"))
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewPicResponse(user, profilePic))
+}
+
+// @Summary Get a download URL of the profile pic
+// @Description Get a presigned URL of the current profile pic, or of one of its variants
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param size query int false "Variant size, the original when empty"
+// @Success 200 {object} dto.PicURLResponse
+// @Router /v1/user/self/pic/url [get]
+func (h *UserHandler) GetProfilePicURL(c *gin.Context) {
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	size, ok := queryPositiveInt(c, "size")
+	if !ok {
+		return
+	}
+
+	presignedURL, apperr := h.userApplicationService.GetProfilePicURL(c, user, size)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewPicURLResponse(presignedURL))
+}
+
+// @Summary Export the user data
+// @Description Download the profile and the profile pic, as JSON with the image base64 encoded or as a zip archive
+// @Tags user
+// @Produce json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param format query string false "json (default) or zip"
+// @Success 200 {object} dto.ExportResponse
+// @Router /v1/user/self/export [get]
+func (h *UserHandler) ExportData(c *gin.Context) {
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "format must be json or zip",
+		})
+		return
+	}
+
+	export, apperr := h.userApplicationService.ExportData(c, user)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	disposition := `attachment; filename="export-` + user.ID + "." + format + `"`
+
+	if format == "json" {
+		c.Header("Content-Disposition", disposition)
+		c.JSON(http.StatusOK, dto.NewExportResponse(export, true))
+		return
+	}
+
+	archive, err := writeExportArchive(export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": apperrors.NewInternal().Message,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", disposition)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// writeExportArchive stores the profile as profile.json and the image under profile_pic/
+func writeExportArchive(export *domain.DataExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	profile, err := json.MarshalIndent(dto.NewExportResponse(export, false), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := archive.Create("profile.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(profile); err != nil {
+		return nil, err
+	}
+
+	if export.ProfilePic != nil {
+		// the filename comes from the upload, only its base name is kept inside the archive
+		file, err := archive.Create("profile_pic/" + path.Base(export.ProfilePic.Filename))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(export.ProfilePicContent); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
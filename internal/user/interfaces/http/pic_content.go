@@ -0,0 +1,167 @@
+package http
+
+import (
+	authDomain "go-template/internal/auth/domain"
+	"go-template/internal/user/domain"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// selfPicCacheControl lets the browser keep the file but revalidate it, the profile pic is replaced under the same URL
+	selfPicCacheControl = "private, no-cache"
+	// avatarCacheControl lets shared caches keep the file as well, it is revalidated as the self one
+	avatarCacheControl = "public, no-cache"
+)
+
+// @Summary Download the profile pic
+// @Description Stream the current profile pic, a previous version or one of the variants, If-None-Match and single byte ranges are supported
+// @Tags user
+// @Produce image/jpeg
+// @Produce image/png
+// @Security BearerAuth
+// @Param version query int false "Version, the current one when empty"
+// @Param size query int false "Variant size, the original when empty"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Success 304 {string} string "Not modified"
+// @Router /v1/user/self/pic/content [get]
+func (h *UserHandler) GetProfilePicContent(c *gin.Context) {
+	authUser, _ := c.Get("user")
+	user := domain.NewUser(authUser.(*authDomain.AuthUser).ID)
+
+	version, ok := queryPositiveInt(c, "version")
+	if !ok {
+		return
+	}
+	size, ok := queryPositiveInt(c, "size")
+	if !ok {
+		return
+	}
+
+	profilePic, apperr := h.userApplicationService.GetProfilePicVersion(c, user, version)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	h.serveProfilePic(c, profilePic, size, selfPicCacheControl)
+}
+
+// @Summary Download the avatar of a user
+// @Description Stream the current profile pic of an active user, If-None-Match and single byte ranges are supported
+// @Tags user
+// @Produce image/jpeg
+// @Produce image/png
+// @Param id path string true "User ID"
+// @Param size query int false "Variant size, the original when empty"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Success 304 {string} string "Not modified"
+// @Router /v1/users/{id}/avatar [get]
+func (h *UserHandler) GetAvatar(c *gin.Context) {
+	size, ok := queryPositiveInt(c, "size")
+	if !ok {
+		return
+	}
+
+	profilePic, apperr := h.userApplicationService.GetAvatar(c, c.Param("id"))
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+
+	h.serveProfilePic(c, profilePic, size, avatarCacheControl)
+}
+
+/*
+serveProfilePic streams a file of the profile pic with its validators
+- A matching If-None-Match is answered with a 304 before the file is read
+- A single byte range is served with a 206, other ranges are ignored and the whole file is sent
+*/
+func (h *UserHandler) serveProfilePic(c *gin.Context, profilePic *domain.ProfilePic, size int, cacheControl string) {
+	etag := profilePic.ContentETag(size)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	c.Header("Accept-Ranges", "bytes")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	byteRange := c.GetHeader("Range")
+	if !isSingleByteRange(byteRange) {
+		byteRange = ""
+	}
+	// the range of an older representation is useless, the whole file is sent instead
+	if ifRange := c.GetHeader("If-Range"); ifRange != "" && ifRange != etag {
+		byteRange = ""
+	}
+
+	content, apperr := h.userApplicationService.OpenProfilePicContent(c, profilePic, size, byteRange)
+	if apperr != nil {
+		c.JSON(apperr.Status(), gin.H{
+			"error": apperr.Message,
+		})
+		return
+	}
+	defer content.Body.Close()
+
+	status := http.StatusOK
+	extraHeaders := map[string]string{
+		// the content type is not guessed from user uploaded bytes
+		"X-Content-Type-Options": "nosniff",
+	}
+	if content.ContentRange != "" {
+		status = http.StatusPartialContent
+		extraHeaders["Content-Range"] = content.ContentRange
+	}
+
+	c.DataFromReader(status, content.ContentLength, content.ContentType, content.Body, extraHeaders)
+}
+
+// queryPositiveInt reads an optional positive query parameter, 0 when it is missing, the request is refused when it is invalid
+func queryPositiveInt(c *gin.Context, name string) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": name + " must be a positive number",
+		})
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+// etagMatches compares the entity tags of an If-None-Match header with the weak comparison
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSingleByteRange reports whether the Range header asks for one byte range, S3 serves no more than one
+func isSingleByteRange(header string) bool {
+	return strings.HasPrefix(header, "bytes=") && !strings.Contains(header, ",")
+}
@@ -0,0 +1,44 @@
+package http
+
+import "testing"
+
+func TestEtagMatches(t *testing.T) {
+	etag := `"abc-256"`
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{``, false},
+		{`"abc-256"`, true},
+		{`W/"abc-256"`, true},
+		{`"other", "abc-256"`, true},
+		{`*`, true},
+		{`"abc"`, false},
+	}
+
+	for _, test := range tests {
+		if got := etagMatches(test.header, etag); got != test.want {
+			t.Errorf("etagMatches(%q) should be %v, got %v", test.header, test.want, got)
+		}
+	}
+}
+
+func TestIsSingleByteRange(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"bytes=0-1023", true},
+		{"bytes=-500", true},
+		{"bytes=0-1,5-9", false},
+		{"items=0-1", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isSingleByteRange(test.header); got != test.want {
+			t.Errorf("isSingleByteRange(%q) should be %v, got %v", test.header, test.want, got)
+		}
+	}
+}
@@ -1,47 +1,163 @@
 package user
 
 import (
-	"go-template/internal/auth/domain/basic"
+	"context"
+	authApplication "go-template/internal/auth/application"
+	authDomain "go-template/internal/auth/domain"
 	"go-template/internal/auth/interfaces/http/middleware"
-	"go-template/internal/aws/s3"
+	sharedConfig "go-template/internal/shared/config"
 	"go-template/internal/shared/infrastructure/database"
 	"go-template/internal/shared/infrastructure/logger"
+	"go-template/internal/shared/infrastructure/storage"
 	"go-template/internal/user/application"
+	"go-template/internal/user/config"
 	"go-template/internal/user/domain"
 	"go-template/internal/user/infrastructure"
 	"go-template/internal/user/interfaces/http"
+	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultPurgeInterval is used when user.purge_interval is not configured (in seconds)
+const defaultPurgeInterval = 60 * 60
+
+// defaultPicVersionRetention is used when user.pic_version_retention is not configured (in seconds)
+const defaultPicVersionRetention = 30 * 24 * 60 * 60
+
+// defaultPicPresignExpiration is used when user.pic_presign_expiration is not configured (in seconds)
+const defaultPicPresignExpiration = 15 * 60
+
 type Module struct {
-	handler      *http.UserHandler
-	basicService *basic.BasicService
-	s3Module     s3.S3Module
+	handler                *http.UserHandler
+	authenticator          authApplication.Authenticator
+	storage                storage.ObjectStorage
+	userApplicationService application.UserApplicationService
+	purgeInterval          time.Duration
+	shutdownChan           chan struct{}
 }
 
-func NewModule(db database.BaseDatabase, logger logger.Logger, basicService *basic.BasicService, s3Module s3.S3Module) *Module {
+func NewModule(
+	db database.BaseDatabase,
+	logger logger.Logger,
+	authenticator authApplication.Authenticator,
+	objectStorage storage.ObjectStorage,
+) *Module {
+	userConfig := loadConfig()
+
 	userRepository := infrastructure.NewPostgresUserRepository(db)
-	userService := domain.NewUserService(s3Module)
+	limits := loadProfilePicLimits(userConfig)
+
+	picPresignExpiration := userConfig.User.PicPresignExpiration
+	if picPresignExpiration <= 0 {
+		picPresignExpiration = defaultPicPresignExpiration
+	}
+
+	userService := domain.NewUserService(objectStorage, limits, time.Duration(picPresignExpiration)*time.Second)
+
+	picVersionRetention := userConfig.User.PicVersionRetention
+	if picVersionRetention <= 0 {
+		picVersionRetention = defaultPicVersionRetention
+	}
 
-	userApplicationService := application.NewUserApplicationService(logger, userService, userRepository)
-	userHandler := http.NewUserHandler(userApplicationService, s3Module)
+	userApplicationService := application.NewUserApplicationService(logger, userService, userRepository, time.Duration(picVersionRetention)*time.Second)
+	userHandler := http.NewUserHandler(userApplicationService, limits.MaxSize)
 
-	return &Module{
-		handler:      userHandler,
-		basicService: basicService,
-		s3Module:     s3Module,
+	purgeInterval := userConfig.User.PurgeInterval
+	if purgeInterval <= 0 {
+		purgeInterval = defaultPurgeInterval
 	}
+
+	mod := &Module{
+		handler:                userHandler,
+		authenticator:          authenticator,
+		storage:                objectStorage,
+		userApplicationService: userApplicationService,
+		purgeInterval:          time.Duration(purgeInterval) * time.Second,
+		shutdownChan:           make(chan struct{}),
+	}
+
+	go mod.startPurge()
+
+	return mod
+}
+
+func loadConfig() *config.UserConfig {
+	// load user config with viper
+	var userConfig *config.UserConfig
+	if err := sharedConfig.Load(&userConfig); err != nil {
+		log.Fatalf("Failed to load user config: %v", err)
+	}
+
+	return userConfig
+}
+
+// loadProfilePicLimits keeps the default limits unless configured otherwise
+func loadProfilePicLimits(userConfig *config.UserConfig) domain.ProfilePicLimits {
+	limits := domain.ProfilePicLimits{
+		MaxSize:      domain.DefaultProfilePicMaxSize,
+		MaxDimension: domain.DefaultProfilePicMaxDimension,
+	}
+	if userConfig.User.PicMaxSize > 0 {
+		limits.MaxSize = userConfig.User.PicMaxSize
+	}
+	if userConfig.User.PicMaxDimension > 0 {
+		limits.MaxDimension = userConfig.User.PicMaxDimension
+	}
+
+	return limits
+}
+
+// startPurge purges the deleted accounts past their grace period, the expired profile pic versions and uploads until the module is shut down
+func (m *Module) startPurge() {
+	ticker := time.NewTicker(m.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.userApplicationService.PurgeDeletedUsers(context.Background())
+			m.userApplicationService.PurgeProfilePicVersions(context.Background())
+			m.userApplicationService.PurgeExpiredPicUploads(context.Background())
+		case <-m.shutdownChan:
+			return
+		}
+	}
+}
+
+func (m *Module) Shutdown() {
+	close(m.shutdownChan)
 }
 
 func (m *Module) RegisterRoutes(router *gin.Engine) {
 
 	userRouter := router.Group("/v1/user")
-	userRouter.Use(middleware.BasicAuthMiddleware(m.basicService))
+	userRouter.Use(middleware.AuthMiddleware(m.authenticator))
 	userRouter.Use(middleware.AccountVerificationMiddleware())
 	{
-		userRouter.POST("/self/pic", m.handler.UploadProfilePic)
-		userRouter.GET("/self/pic", m.handler.GetProfilePic)
-		userRouter.DELETE("/self/pic", m.handler.DeleteProfilePic)
+		userRouter.POST("/self/pic", middleware.RequireScopeMiddleware(authDomain.ScopePicWrite), m.handler.UploadProfilePic)
+		userRouter.PUT("/self/pic", middleware.RequireScopeMiddleware(authDomain.ScopePicWrite), m.handler.ReplaceProfilePic)
+		userRouter.GET("/self/pic", middleware.RequireScopeMiddleware(authDomain.ScopePicRead), m.handler.GetProfilePic)
+		userRouter.GET("/self/pic/content", middleware.RequireScopeMiddleware(authDomain.ScopePicRead), m.handler.GetProfilePicContent)
+		userRouter.DELETE("/self/pic", middleware.RequireScopeMiddleware(authDomain.ScopePicWrite), m.handler.DeleteProfilePic)
+		userRouter.GET("/self/pic/versions", middleware.RequireScopeMiddleware(authDomain.ScopePicRead), m.handler.ListProfilePicVersions)
+		userRouter.POST("/self/pic/versions/:version/restore", middleware.RequireScopeMiddleware(authDomain.ScopePicWrite), m.handler.RestoreProfilePicVersion)
+		userRouter.POST("/self/pic/uploads", middleware.RequireScopeMiddleware(authDomain.ScopePicWrite), m.handler.CreateProfilePicUpload)
+		userRouter.POST("/self/pic/uploads/:id/confirm", middleware.RequireScopeMiddleware(authDomain.ScopePicWrite), m.handler.ConfirmProfilePicUpload)
+		userRouter.GET("/self/pic/url", middleware.RequireScopeMiddleware(authDomain.ScopePicRead), m.handler.GetProfilePicURL)
+	}
+
+	// an unverified user can export the data as well, API keys cannot
+	exportRouter := router.Group("/v1/user")
+	exportRouter.Use(middleware.AuthMiddleware(m.authenticator), middleware.RejectApiKeyMiddleware())
+	{
+		exportRouter.GET("/self/export", m.handler.ExportData)
+	}
+
+	// the avatars are public, e.g. for img tags that cannot send a token
+	avatarRouter := router.Group("/v1/users")
+	{
+		avatarRouter.GET("/:id/avatar", m.handler.GetAvatar)
 	}
 }
@@ -0,0 +1,4 @@
+DROP INDEX refresh_tokens_user_id_idx;
+DROP INDEX refresh_tokens_family_id_idx;
+
+DROP TABLE refresh_tokens;
@@ -0,0 +1,17 @@
+CREATE TABLE
+  refresh_tokens (
+    id VARCHAR(36) NOT NULL,
+    -- every rotation of a login keeps the same family id
+    family_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (id)
+  );
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens(user_id);
@@ -0,0 +1,18 @@
+CREATE TABLE
+  api_keys (
+    id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    -- public part of the key, used to look the key up
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (id)
+  );
+
+CREATE INDEX api_keys_user_id_idx ON api_keys(user_id);
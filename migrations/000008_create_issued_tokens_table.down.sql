@@ -0,0 +1,3 @@
+DROP INDEX issued_tokens_user_id_purpose_idx;
+
+DROP TABLE issued_tokens;
@@ -0,0 +1,14 @@
+CREATE TABLE
+  issued_tokens (
+    -- jti claim of the token
+    id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP,
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (id)
+  );
+
+CREATE INDEX issued_tokens_user_id_purpose_idx ON issued_tokens(user_id, purpose);
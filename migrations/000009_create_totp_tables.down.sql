@@ -0,0 +1,6 @@
+ALTER TABLE refresh_tokens DROP COLUMN second_factor;
+
+DROP INDEX user_recovery_codes_user_id_idx;
+
+DROP TABLE user_recovery_codes;
+DROP TABLE user_totp;
@@ -0,0 +1,28 @@
+CREATE TABLE
+  user_totp (
+    user_id VARCHAR(36) NOT NULL,
+    -- AES-GCM encrypted shared secret
+    secret_encrypted BYTEA NOT NULL,
+    confirmed_at TIMESTAMP,
+    -- the last accepted time step, a code cannot be replayed within its window
+    last_used_step BIGINT,
+    created_at TIMESTAMP NOT NULL,
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (user_id)
+  );
+
+CREATE TABLE
+  user_recovery_codes (
+    id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (id)
+  );
+
+CREATE INDEX user_recovery_codes_user_id_idx ON user_recovery_codes(user_id);
+
+-- sessions started with a completed second factor keep it across refreshes
+ALTER TABLE refresh_tokens ADD COLUMN second_factor BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,9 @@
+CREATE TABLE
+  auth_attempts (
+    -- "account:<email>" or "ip:<address>"
+    key VARCHAR(320) NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    primary key (key)
+  );
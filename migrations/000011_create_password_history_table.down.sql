@@ -0,0 +1,3 @@
+DROP INDEX password_history_user_id_created_at_idx;
+
+DROP TABLE password_history;
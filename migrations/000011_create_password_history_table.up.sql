@@ -0,0 +1,13 @@
+CREATE TABLE
+  password_history (
+    id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    foreign key (user_id) references users (id) on delete cascade,
+    -- a hash is recorded once, saving the user without a new password does not add an entry
+    unique (user_id, password_hash),
+    primary key (id)
+  );
+
+CREATE INDEX password_history_user_id_created_at_idx ON password_history(user_id, created_at);
@@ -0,0 +1,3 @@
+-- argon2id hashes carry their parameters and are longer than the 60 characters of bcrypt
+ALTER TABLE users
+  ALTER COLUMN password TYPE VARCHAR(255);
@@ -0,0 +1,2 @@
+-- the requested new address, it replaces email once its verification token is confirmed
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(255);
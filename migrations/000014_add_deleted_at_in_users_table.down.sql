@@ -0,0 +1,5 @@
+DROP INDEX users_purge_after_idx;
+
+ALTER TABLE users
+  DROP COLUMN deleted_at,
+  DROP COLUMN purge_after;
@@ -0,0 +1,6 @@
+-- a deleted account is kept until purge_after, then the purge removes its files and rows
+ALTER TABLE users
+  ADD COLUMN deleted_at TIMESTAMP,
+  ADD COLUMN purge_after TIMESTAMP;
+
+CREATE INDEX users_purge_after_idx ON users(purge_after);
@@ -0,0 +1,48 @@
+CREATE TABLE
+  permissions (
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    primary key (name)
+  );
+
+CREATE TABLE
+  roles (
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    primary key (name)
+  );
+
+CREATE TABLE
+  role_permissions (
+    role_name VARCHAR(64) NOT NULL,
+    permission_name VARCHAR(64) NOT NULL,
+    foreign key (role_name) references roles (name) on delete cascade,
+    foreign key (permission_name) references permissions (name) on delete cascade,
+    primary key (role_name, permission_name)
+  );
+
+CREATE TABLE
+  user_roles (
+    user_id VARCHAR(36) NOT NULL,
+    role_name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    foreign key (user_id) references users (id) on delete cascade,
+    foreign key (role_name) references roles (name) on delete cascade,
+    primary key (user_id, role_name)
+  );
+
+CREATE INDEX user_roles_role_name_idx ON user_roles(role_name);
+
+-- the admin role can manage every user, grant it with
+-- INSERT INTO user_roles (user_id, role_name) VALUES ('<user id>', 'admin');
+INSERT INTO permissions (name, description) VALUES
+  ('users:read', 'List and view users'),
+  ('users:write', 'Disable and verify users'),
+  ('users:delete', 'Delete users');
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Manages the users');
+
+INSERT INTO role_permissions (role_name, permission_name) VALUES
+  ('admin', 'users:read'),
+  ('admin', 'users:write'),
+  ('admin', 'users:delete');
@@ -0,0 +1,2 @@
+-- a disabled user cannot authenticate until it is enabled again
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
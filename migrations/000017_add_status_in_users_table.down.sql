@@ -0,0 +1,11 @@
+DROP INDEX users_status_idx;
+
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+UPDATE users SET disabled_at = status_changed_at WHERE status IN ('suspended', 'deactivated');
+
+ALTER TABLE users
+  DROP COLUMN status,
+  DROP COLUMN status_reason,
+  DROP COLUMN status_changed_at,
+  DROP COLUMN activated_at;
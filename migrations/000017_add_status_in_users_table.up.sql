@@ -0,0 +1,20 @@
+-- the account status lifecycle replaces the disabled flag:
+-- pending until the email is verified, active, suspended by an admin or deactivated
+ALTER TABLE users
+  ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  ADD COLUMN status_reason VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN status_changed_at TIMESTAMP,
+  ADD COLUMN activated_at TIMESTAMP;
+
+UPDATE users SET
+  status = CASE
+    WHEN disabled_at IS NOT NULL THEN 'suspended'
+    WHEN verify THEN 'active'
+    ELSE 'pending'
+  END,
+  status_changed_at = COALESCE(disabled_at, created_at),
+  activated_at = CASE WHEN verify THEN created_at END;
+
+ALTER TABLE users DROP COLUMN disabled_at;
+
+CREATE INDEX users_status_idx ON users(status);
@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN last_login_at;
+
+DROP TRIGGER auth_events_no_update ON auth_events;
+DROP FUNCTION auth_events_append_only;
+DROP INDEX auth_events_user_id_created_at_idx;
+DROP TABLE auth_events;
@@ -0,0 +1,30 @@
+CREATE TABLE
+  auth_events (
+    id VARCHAR(36) NOT NULL,
+    -- NULL for the failed logins of unknown emails
+    user_id VARCHAR(36),
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    type VARCHAR(32) NOT NULL,
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    -- the events are removed with the purged user
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (id)
+  );
+
+CREATE INDEX auth_events_user_id_created_at_idx ON auth_events(user_id, created_at DESC);
+
+-- the events are append-only, only the cascade of a purged user removes them
+CREATE FUNCTION auth_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER auth_events_no_update BEFORE UPDATE ON auth_events
+  FOR EACH ROW EXECUTE FUNCTION auth_events_append_only();
+
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMP;
@@ -0,0 +1,14 @@
+DROP INDEX user_pic_replaced_at_idx;
+DROP INDEX user_pic_current_idx;
+DROP INDEX user_pic_user_id_version_idx;
+
+-- only the current versions fit the single profile pic per user
+DELETE FROM user_pic WHERE NOT is_current;
+
+ALTER TABLE user_pic DROP COLUMN replaced_at;
+ALTER TABLE user_pic DROP COLUMN is_current;
+ALTER TABLE user_pic DROP COLUMN version;
+
+ALTER TABLE user_pic DROP CONSTRAINT user_pic_pkey;
+ALTER TABLE user_pic DROP COLUMN id;
+ALTER TABLE user_pic ADD PRIMARY KEY (user_id);
@@ -0,0 +1,16 @@
+-- a user keeps the previous profile pics as versions, one of them is the current one
+ALTER TABLE user_pic DROP CONSTRAINT user_pic_pkey;
+
+ALTER TABLE user_pic ADD COLUMN id VARCHAR(36);
+UPDATE user_pic SET id = gen_random_uuid()::text;
+ALTER TABLE user_pic ALTER COLUMN id SET NOT NULL;
+ALTER TABLE user_pic ADD PRIMARY KEY (id);
+
+ALTER TABLE user_pic ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE user_pic ADD COLUMN is_current BOOLEAN NOT NULL DEFAULT true;
+-- set when another version becomes the current one, the retention counts from it
+ALTER TABLE user_pic ADD COLUMN replaced_at TIMESTAMP;
+
+CREATE UNIQUE INDEX user_pic_user_id_version_idx ON user_pic(user_id, version);
+CREATE UNIQUE INDEX user_pic_current_idx ON user_pic(user_id) WHERE is_current;
+CREATE INDEX user_pic_replaced_at_idx ON user_pic(replaced_at) WHERE NOT is_current;
@@ -0,0 +1 @@
+ALTER TABLE user_pic DROP COLUMN variant_sizes;
@@ -0,0 +1,2 @@
+-- the sizes of the scaled copies stored next to the profile pic, empty for the pics uploaded before the variants
+ALTER TABLE user_pic ADD COLUMN variant_sizes INT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP INDEX user_pic_uploads_expires_at_idx;
+DROP TABLE user_pic_uploads;
@@ -0,0 +1,16 @@
+CREATE TABLE
+  user_pic_uploads (
+    id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    -- the staging key the client uploads to with the presigned URL
+    s3_key VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    foreign key (user_id) references users (id) on delete cascade,
+    primary key (id)
+  );
+
+CREATE INDEX user_pic_uploads_expires_at_idx ON user_pic_uploads(expires_at);
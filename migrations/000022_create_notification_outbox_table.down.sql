@@ -0,0 +1,2 @@
+DROP INDEX notification_outbox_pending_idx;
+DROP TABLE notification_outbox;
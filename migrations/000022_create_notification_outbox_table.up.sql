@@ -0,0 +1,17 @@
+CREATE TABLE
+  notification_outbox (
+    id VARCHAR(36) NOT NULL,
+    -- the topic the payload is published to by the dispatcher
+    topic VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    -- a claimed message is leased until next_attempt_at, it is picked up again when the dispatcher dies before publishing
+    next_attempt_at TIMESTAMP NOT NULL,
+    -- set when the message ran out of attempts, it is kept for inspection
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    primary key (id)
+  );
+
+CREATE INDEX notification_outbox_pending_idx ON notification_outbox(next_attempt_at) WHERE failed_at IS NULL;
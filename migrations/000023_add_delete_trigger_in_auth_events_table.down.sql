@@ -0,0 +1,2 @@
+DROP TRIGGER auth_events_no_delete ON auth_events;
+DROP FUNCTION auth_events_cascade_only;
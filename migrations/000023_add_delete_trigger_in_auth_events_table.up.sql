@@ -0,0 +1,13 @@
+-- a direct DELETE is refused, only the cascade of a purged user removes the events
+CREATE FUNCTION auth_events_cascade_only() RETURNS trigger AS $$
+BEGIN
+  -- the cascade deletes from the foreign key trigger, one level deeper than a statement
+  IF pg_trigger_depth() > 1 THEN
+    RETURN OLD;
+  END IF;
+  RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER auth_events_no_delete BEFORE DELETE ON auth_events
+  FOR EACH ROW EXECUTE FUNCTION auth_events_cascade_only();
@@ -3,7 +3,9 @@ package apperrors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // Type holds a type string and integer code for the error
@@ -20,6 +22,8 @@ const (
 	UnprocessableEntity Type = "UNPROCESSABLE_ENTITY" // 422
 	ErrInvalidClaims    Type = "INVALID_CLAIMS"       // Invalid JWT claims
 	Forbidden           Type = "FORBIDDEN"
+	TooManyRequests     Type = "TOO_MANY_REQUESTS"     // 429
+	RangeNotSatisfiable Type = "RANGE_NOT_SATISFIABLE" // 416
 )
 
 // Error holds a custom error for the application
@@ -27,6 +31,16 @@ const (
 type Error struct {
 	Type    Type   `json:"type"`
 	Message string `json:"message"`
+	// RetryAfter is the number of seconds sent in the Retry-After header of a 429
+	RetryAfter int `json:"-"`
+	// Details lists the individual problems of a validation error
+	Details []Detail `json:"details,omitempty"`
+}
+
+// Detail is one problem of a validation error, Code is stable for clients to match on
+type Detail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // Error satisfies the error interface
@@ -56,6 +70,10 @@ func (e Error) Status() int {
 		return http.StatusUnauthorized
 	case Forbidden:
 		return http.StatusForbidden
+	case TooManyRequests:
+		return http.StatusTooManyRequests
+	case RangeNotSatisfiable:
+		return http.StatusRequestedRangeNotSatisfiable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -129,6 +147,13 @@ func NewUnprocessableEntity(reason string) *Error {
 	}
 }
 
+// NewUnprocessableEntityWithDetails to create a 422 listing every problem of the input
+func NewUnprocessableEntityWithDetails(reason string, details []Detail) *Error {
+	err := NewUnprocessableEntity(reason)
+	err.Details = details
+	return err
+}
+
 // NewInvalidClaims to create an error for 401
 func NewInvalidClaims(reason string) *Error {
 	return &Error{
@@ -144,3 +169,20 @@ func NewForbidden(reason string) *Error {
 		Message: fmt.Sprintf("Forbidden. Reason: %v", reason),
 	}
 }
+
+// NewTooManyRequests to create an error for 429, the retry after is rounded up to whole seconds
+func NewTooManyRequests(reason string, retryAfter time.Duration) *Error {
+	return &Error{
+		Type:       TooManyRequests,
+		Message:    fmt.Sprintf("Too many requests. Reason: %v", reason),
+		RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+	}
+}
+
+// NewRangeNotSatisfiable to create an error for 416
+func NewRangeNotSatisfiable(reason string) *Error {
+	return &Error{
+		Type:    RangeNotSatisfiable,
+		Message: fmt.Sprintf("Range not satisfiable. Reason: %v", reason),
+	}
+}